@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/form3tech-oss/github-team-approver/internal/api/approval"
+	"github.com/form3tech-oss/github-team-approver/internal/api/configuration"
+	"github.com/form3tech-oss/github-team-approver/internal/api/offline"
+)
+
+// evaluate computes the approval status of a PR from files rather than from GitHub, and prints the result.
+func evaluate(args []string, stdout, stderr io.Writer) int {
+	var (
+		files     offline.Files
+		adminTeam string
+	)
+	flags := flag.NewFlagSet(commandEvaluate, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&files.Config, "config", "", "The path to the configuration file (required).")
+	flags.StringVar(&files.Event, "event", "", "The path to the payload of a pull request event (required).")
+	flags.StringVar(&files.Reviews, "reviews", "", "The path to the reviews of the PR.")
+	flags.StringVar(&files.Teams, "teams", "", "The path to the teams of the organisation, each listing its members under \"members\".")
+	flags.StringVar(&files.Commits, "commits", "", "The path to the commits of the PR.")
+	flags.StringVar(&files.CommitFiles, "files", "", "The path to the files changed by the PR.")
+	flags.StringVar(&files.Codeowners, "codeowners", "", "The path to the CODEOWNERS file of the repository.")
+	flags.StringVar(&adminTeam, "admin-team", "", "The team whose approval changes to the configuration file require.")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if files.Config == "" || files.Event == "" {
+		fmt.Fprintln(stderr, "Both -config and -event are required.")
+		flags.Usage()
+		return 2
+	}
+
+	source, err := offline.Load(files)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to load the PR: %v\n", err)
+		return 1
+	}
+
+	log := logrus.New()
+	log.SetOutput(stderr)
+	log.SetLevel(logrus.WarnLevel)
+	app := approval.NewApproval(logrus.NewEntry(log), source, approval.Settings{
+		ConfigurationAdminTeamHandle: adminTeam,
+		ConfigurationSource:          configuration.FileSource(files.Config),
+	})
+	result, err := app.ComputeApprovalStatus(context.Background(), source.PR())
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to compute status: %v\n", err)
+		return 1
+	}
+
+	printResult(stdout, result)
+	return 0
+}
+
+func printResult(w io.Writer, result *approval.Result) {
+	fmt.Fprintf(w, "Status: %s\n", result.Status())
+	fmt.Fprintf(w, "Description: %s\n", strings.ReplaceAll(result.Description(), "\n", "\n  "))
+	fmt.Fprintf(w, "Labels: %s\n", strings.Join(result.FinalLabels(), ", "))
+	fmt.Fprintf(w, "Team reviews to request: %s\n", strings.Join(result.ReviewsToRequest(), ", "))
+	fmt.Fprintf(w, "User reviews to request: %s\n", strings.Join(result.UserReviewsToRequest(), ", "))
+}
@@ -9,7 +9,21 @@ import (
 	"github.com/form3tech-oss/github-team-approver/internal/api"
 )
 
+const (
+	commandValidateConfig = "validate-config"
+	commandEvaluate       = "evaluate"
+)
+
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case commandValidateConfig:
+			os.Exit(validateConfig(os.Args[2:], os.Stdout, os.Stderr))
+		case commandEvaluate:
+			os.Exit(evaluate(os.Args[2:], os.Stdout, os.Stderr))
+		}
+	}
+
 	// buffering shutdown channel as recommended
 	// https://golang.org/pkg/os/signal/#Notify
 	// golangci-lint SA1017
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/form3tech-oss/github-team-approver/internal/api/configuration"
+	"github.com/form3tech-oss/github-team-approver/internal/api/offline"
+)
+
+// validateConfig lints the given configuration files, printing the problems found in each. It returns a non-zero exit
+// code if any of them is invalid.
+func validateConfig(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet(commandValidateConfig, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: %s path/to/GITHUB_TEAM_APPROVER.yaml...\n", commandValidateConfig)
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	code := 0
+	for _, path := range flags.Args() {
+		_, err := offline.ReadConfiguration(path)
+		var invalid *configuration.ValidationError
+		switch {
+		case err == nil:
+			fmt.Fprintf(stdout, "%s: OK\n", path)
+		case errors.As(err, &invalid):
+			fmt.Fprintf(stdout, "%s: invalid configuration\n", path)
+			for _, problem := range invalid.Problems {
+				fmt.Fprintf(stdout, "  - %s\n", problem)
+			}
+			code = 1
+		default:
+			fmt.Fprintf(stdout, "%s: %v\n", path, err)
+			code = 1
+		}
+	}
+	return code
+}
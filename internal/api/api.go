@@ -1,11 +1,17 @@
 package api
 
 import (
+	"bytes"
 	"context"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/form3tech-oss/github-team-approver/internal/api/approval"
+	"github.com/form3tech-oss/github-team-approver/internal/api/configuration"
+	"github.com/form3tech-oss/github-team-approver/internal/api/delivery"
 	"github.com/form3tech-oss/github-team-approver/internal/api/github"
 	"github.com/form3tech-oss/github-team-approver/internal/api/secret"
 	log "github.com/sirupsen/logrus"
@@ -17,24 +23,74 @@ const (
 	logTimeFormat = "2006-01-02T15:04:05.000Z07:00"
 
 	envAppName                         = "APP_NAME"
+	envCentralConfigurationRepository  = "CENTRAL_CONFIGURATION_REPOSITORY"
+	envConfigurationAdminTeam          = "CONFIGURATION_ADMIN_TEAM"
+	envConfigurationSource             = "CONFIGURATION_SOURCE"
+	envDeliveryStore                   = "DELIVERY_STORE"
+	envDeliveryStorePath               = "DELIVERY_STORE_PATH"
+	envDeliveryTTL                     = "DELIVERY_TTL"
+	envDraftPolicy                     = "DRAFT_POLICY"
+	envEventDebounceWindow             = "EVENT_DEBOUNCE_WINDOW"
+	envEventQueueMaxAttempts           = "EVENT_QUEUE_MAX_ATTEMPTS"
+	envEventQueueRetryBackoff          = "EVENT_QUEUE_RETRY_BACKOFF"
+	envEventQueueSize                  = "EVENT_QUEUE_SIZE"
+	envEventQueueWorkers               = "EVENT_QUEUE_WORKERS"
+	envExplainAPITokenPath             = "EXPLAIN_API_TOKEN_PATH"
 	envGitHubAppWebhookSecretTokenPath = "GITHUB_APP_WEBHOOK_SECRET_TOKEN_PATH"
 	envIgnoredRepositories             = "IGNORED_REPOSITORIES"
 	envLogLevel                        = "LOG_LEVEL"
 	envLogFormat                       = "LOG_FORMAT"
 	envSecretStoreType                 = "SECRET_STORE_TYPE" // Set to AWS_SSM for the ability to run in ECS using SSM. Empty, not set or anything else for default K8s secret
 	envSlackWebhookSecret              = "SLACK_WEBHOOK_SECRET"
+	envStatusReporting                 = "STATUS_REPORTING"
+	envTeamReevaluationInterval        = "TEAM_REEVALUATION_INTERVAL"
+
+	// statusReportingCommitStatus, statusReportingCheckRun and statusReportingBoth are the values of
+	// STATUS_REPORTING, deciding whether the approval status is published as a commit status, a check run, or both.
+	statusReportingCommitStatus = "commit_status"
+	statusReportingCheckRun     = "check_run"
+	statusReportingBoth         = "both"
+
+	// draftPolicyEvaluate, draftPolicyPending and draftPolicySkip are the values of DRAFT_POLICY, deciding whether
+	// draft PRs are evaluated like any other PR, reported as pending, or left alone.
+	draftPolicyEvaluate = "evaluate"
+	draftPolicyPending  = "pending"
+	draftPolicySkip     = "skip"
+
+	// deliveryStoreMemory, deliveryStoreFile and deliveryStoreNone are the values of DELIVERY_STORE, deciding where
+	// the deliveries handled are remembered, if anywhere.
+	deliveryStoreMemory = "memory"
+	deliveryStoreFile   = "file"
+	deliveryStoreNone   = "none"
+	// defaultDeliveryTTL covers the three days during which GitHub lets deliveries be redelivered.
+	defaultDeliveryTTL = 72 * time.Hour
 )
 
 type API struct {
 	AppName                  string
 	SecretStore              secret.Store
+	clients                  *github.Clients
+	configurationSource      configuration.Source
+	configurationAdminTeam   string
+	deliveries               delivery.Store
+	draftPolicy              string
+	explainAPIToken          []byte
+	eventQueue               *eventQueue
 	githubWebhookSecretToken []byte
 	ignoredRepositories      []string
+	metrics                  *metrics
+	prEvents                 *prEvents
+	reportCheckRun           bool
+	reportCommitStatus       bool
 	slackWebhookSecret       string
+	teamReevaluator          *teamReevaluator
 }
 
 func newApi() *API {
-	return &API{}
+	return &API{
+		metrics:  &metrics{},
+		prEvents: newPREvents(),
+	}
 }
 
 func Start(address string, shutdown <-chan os.Signal, ready chan<- struct{}) {
@@ -51,6 +107,14 @@ func (api *API) init() {
 	api.setGitHubAppSecret()
 	api.setSlackWebhookSecret()
 	api.setIgnoredRepositories()
+	api.setConfigurationSource()
+	api.setConfigurationAdminTeam()
+	api.setExplainAPIToken()
+	api.setStatusReporting()
+	api.setDraftPolicy()
+	api.setDeliveryStore()
+	api.setEventQueue()
+	api.setTeamReevaluator()
 }
 
 func (api *API) setAppName() {
@@ -59,6 +123,7 @@ func (api *API) setAppName() {
 
 func (api *API) initSecretStore(env string) {
 	api.SecretStore = getSecretStore(env)
+	api.clients = github.NewClients(api.SecretStore)
 	log.WithField("env", env).Info("Configured Secret Store")
 }
 
@@ -124,12 +189,149 @@ func (api *API) setIgnoredRepositories() {
 	log.Info("Configured Ignored repositories")
 }
 
+func (api *API) setConfigurationSource() {
+	v := os.Getenv(envConfigurationSource)
+	source, err := configuration.ParseSource(v)
+	if err != nil {
+		log.WithError(err).Warnf("failed to parse configuration source, falling back to %q", configuration.SourceDefaultBranch)
+	}
+	api.configurationSource = source.WithCentralRepository(os.Getenv(envCentralConfigurationRepository))
+	log.WithFields(log.Fields{
+		"configuration_source":             source.String(),
+		"central_configuration_repository": api.configurationSource.CentralRepository(),
+	}).Info("Configured configuration source")
+}
+
+func (api *API) setConfigurationAdminTeam() {
+	api.configurationAdminTeam = os.Getenv(envConfigurationAdminTeam)
+	if api.configurationAdminTeam == "" {
+		return
+	}
+	log.WithField("configuration_admin_team", api.configurationAdminTeam).Info("Configured configuration admin team")
+}
+
+// approvalSettings returns the options affecting how the approval status of a PR is computed.
+func (api *API) approvalSettings() approval.Settings {
+	return approval.Settings{
+		ConfigurationSource:          api.configurationSource,
+		ConfigurationAdminTeamHandle: api.configurationAdminTeam,
+		DraftsPending:                api.draftPolicy == draftPolicyPending,
+	}
+}
+
+func (api *API) setStatusReporting() {
+	v := os.Getenv(envStatusReporting)
+	switch v {
+	case "", statusReportingCommitStatus:
+		api.reportCommitStatus = true
+	case statusReportingCheckRun:
+		api.reportCheckRun = true
+	case statusReportingBoth:
+		api.reportCommitStatus = true
+		api.reportCheckRun = true
+	default:
+		log.WithField("status_reporting", v).Warnf("invalid status reporting, falling back to %q", statusReportingCommitStatus)
+		api.reportCommitStatus = true
+	}
+	log.WithFields(log.Fields{
+		"report_commit_status": api.reportCommitStatus,
+		"report_check_run":     api.reportCheckRun,
+	}).Info("Configured status reporting")
+}
+
+func (api *API) setExplainAPIToken() {
+	token, err := api.SecretStore.Get(envExplainAPITokenPath)
+	if err != nil || len(bytes.TrimSpace(token)) == 0 {
+		log.Info("Explain endpoint disabled: no API token configured")
+		return
+	}
+	api.explainAPIToken = bytes.TrimSpace(token)
+	log.Info("Configured explain API token")
+}
+
+func (api *API) setDraftPolicy() {
+	v := os.Getenv(envDraftPolicy)
+	switch v {
+	case "":
+		api.draftPolicy = draftPolicyEvaluate
+	case draftPolicyEvaluate, draftPolicyPending, draftPolicySkip:
+		api.draftPolicy = v
+	default:
+		log.WithField("draft_policy", v).Warnf("invalid draft policy, falling back to %q", draftPolicyEvaluate)
+		api.draftPolicy = draftPolicyEvaluate
+	}
+	log.WithField("draft_policy", api.draftPolicy).Info("Configured draft policy")
+}
+
+// setDeliveryStore sets up where the deliveries handled are remembered, so that redeliveries are acknowledged without
+// being handled again.
+func (api *API) setDeliveryStore() {
+	v := os.Getenv(envDeliveryStore)
+	ttl := getEnvDuration(envDeliveryTTL, defaultDeliveryTTL)
+	switch v {
+	case deliveryStoreNone:
+		log.Info("Delivery deduplication disabled")
+		return
+	case deliveryStoreFile:
+		path := os.Getenv(envDeliveryStorePath)
+		store, err := delivery.NewFileStore(path, ttl)
+		if err == nil {
+			api.deliveries = store
+			log.WithFields(log.Fields{"delivery_store": v, "path": path, "ttl": ttl.String()}).Info("Configured delivery store")
+			return
+		}
+		log.WithError(err).Warnf("failed to open delivery store, falling back to %q", deliveryStoreMemory)
+	case "", deliveryStoreMemory:
+	default:
+		log.WithField("delivery_store", v).Warnf("invalid delivery store, falling back to %q", deliveryStoreMemory)
+	}
+	api.deliveries = delivery.NewMemoryStore(ttl)
+	log.WithFields(log.Fields{"delivery_store": deliveryStoreMemory, "ttl": ttl.String()}).Info("Configured delivery store")
+}
+
+// setEventQueue sets up the queue processing events in the background, unless EVENT_QUEUE_WORKERS is zero in which
+// case events are processed within the webhook request.
+func (api *API) setEventQueue() {
+	workers := getEnvInt(envEventQueueWorkers, defaultEventQueueWorkers)
+	if workers == 0 {
+		log.Info("Events processed synchronously")
+		return
+	}
+	size := getEnvInt(envEventQueueSize, defaultEventQueueSize)
+	maxAttempts := getEnvInt(envEventQueueMaxAttempts, defaultEventQueueMaxAttempts)
+	backoff := getEnvDuration(envEventQueueRetryBackoff, defaultEventQueueRetryBackoff)
+	debounceWindow := getEnvDuration(envEventDebounceWindow, defaultEventDebounceWindow)
+	api.eventQueue = newEventQueue(size, maxAttempts, backoff, api.metrics, api.processJob)
+	api.eventQueue.debounceWindow = debounceWindow
+	api.eventQueue.failed = func(j *job) { api.forgetDelivery(j.log, j.deliveryID) }
+	api.eventQueue.start(workers)
+	log.WithFields(log.Fields{
+		"workers":         workers,
+		"size":            size,
+		"max_attempts":    maxAttempts,
+		"retry_backoff":   backoff.String(),
+		"debounce_window": debounceWindow.String(),
+	}).Info("Configured event queue")
+}
+
+// setTeamReevaluator sets up the re-evaluation of the open PRs affected by "membership" and "team" events, at most one
+// PR per TEAM_REEVALUATION_INTERVAL.
+func (api *API) setTeamReevaluator() {
+	interval := getEnvDuration(envTeamReevaluationInterval, defaultTeamReevaluationInterval)
+	api.teamReevaluator = newTeamReevaluator(interval, api.reevaluateTeam)
+	api.teamReevaluator.failed = func(c *teamChange) { api.forgetDelivery(c.log, c.deliveryID) }
+	api.teamReevaluator.start()
+	log.WithField("interval", interval.String()).Info("Configured team re-evaluation")
+}
+
 func (api *API) startServer(address string, shutdown <-chan os.Signal, ready chan<- struct{}) {
 
 	m := http.NewServeMux()
 	m.HandleFunc("/health", api.HandleHealth)
 	m.HandleFunc("/events", api.Handle)
 	m.HandleFunc("/function/github-team-approver", api.Handle) // Keep backwards-compatibility.
+	m.HandleFunc(explainPathPrefix, api.HandleExplain)
+	m.HandleFunc(metricsPath, api.HandleMetrics)
 	srv := &http.Server{Addr: address, Handler: m}
 
 	go func() {
@@ -148,6 +350,10 @@ func (api *API) startServer(address string, shutdown <-chan os.Signal, ready cha
 	if err := srv.Shutdown(ctx); err != nil {
 		log.WithError(err).Fatal("context forced to shutdown")
 	}
+	if api.eventQueue != nil {
+		api.eventQueue.stop(ctx)
+	}
+	api.teamReevaluator.stop(ctx)
 	log.Info("server shutdown")
 }
 
@@ -158,6 +364,35 @@ func getSecretStore(env string) secret.Store {
 	return secret.NewEnvSecretStore()
 }
 
+// getEnvInt parses the given environment variable as a non-negative integer, falling back to def if it is unset or invalid.
+func getEnvInt(name string, def int) int {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		log.WithField(strings.ToLower(name), v).Warnf("invalid value, falling back to %d", def)
+		return def
+	}
+	return n
+}
+
+// getEnvDuration parses the given environment variable as a positive duration (e.g. "500ms"), falling back to def if
+// it is unset or invalid.
+func getEnvDuration(name string, def time.Duration) time.Duration {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.WithField(strings.ToLower(name), v).Warnf("invalid value, falling back to %s", def)
+		return def
+	}
+	return d
+}
+
 func getAppNameOrDefault() string {
 	if v := os.Getenv(envAppName); v != "" {
 		return v
@@ -336,7 +336,7 @@ func TestGitHubTeamApproverCleansUpOldIgnoredReviewsComments(t *testing.T) {
 	then.
 		ExpectPendingAnswerReturned().
 		ExpectStatusPendingReported().
-		ExpectPreviousIgnoredReviewCommentsDeleted().
+		ExpectLegacyReviewCommentsDeleted().
 		ExpectCommentAliceIgnoredAsReviewer().
 		ExpectLabelsUpdated().
 		ExpectedReviewRequestsMadeForFoo()
@@ -360,13 +360,13 @@ func TestGitHubTeamApproverCleansUpOldInvalidReviewsComments(t *testing.T) {
 	then.
 		ExpectPendingAnswerReturned().
 		ExpectStatusPendingReported().
-		ExpectPreviousIgnoredReviewCommentsDeleted().
+		ExpectLegacyReviewCommentsDeleted().
 		ExpectInvalidCommentCharlieIgnoredAsReviewer().
 		ExpectLabelsUpdated().
 		ExpectedReviewRequestsMadeForFoo()
 }
 
-func TestGitHubTeamApproverCleansUpOldInvalidAndRetainsIgnoredReviewsComments(t *testing.T) {
+func TestGitHubTeamApproverCleansUpOldInvalidAndIgnoredReviewsComments(t *testing.T) {
 	given, when, then := stages.ApiTest(t)
 
 	given.
@@ -384,12 +384,97 @@ func TestGitHubTeamApproverCleansUpOldInvalidAndRetainsIgnoredReviewsComments(t
 	then.
 		ExpectPendingAnswerReturned().
 		ExpectStatusPendingReported().
-		ExpectPreviousAliceIgnoredReviewCommentsRetained().
+		ExpectLegacyReviewCommentsDeleted().
 		ExpectInvalidCommentCharlieIgnoredAsReviewer().
 		ExpectLabelsUpdated().
 		ExpectedReviewRequestsMadeForFoo()
 }
 
+func TestGitHubTeamApproverEditsSummaryCommentInPlace(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithNoContributorReviewEnabledAndFooAsApprovingTeam().
+		PullRequestExists().
+		SummaryCommentExists().
+		CommitsWithBobAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectSuccessAnswerReturned().
+		ExpectStatusSuccessReported().
+		ExpectSummaryCommentEditedWithApprovalFromAlice().
+		ExpectLabelsUpdated()
+}
+
+func TestGitHubTeamApproverDoesNotEditUnchangedSummaryComment(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithNoContributorReviewEnabledAndFooAsApprovingTeam().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithAliceAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent().
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectPendingAnswerReturned().
+		ExpectCommentAliceIgnoredAsReviewer().
+		ExpectSummaryCommentNotEdited()
+}
+
+func TestGitHubTeamApproverDeletesSummaryCommentWhenConfigurationBecomesInvalid(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithConfigurationContainingInvalidRegex().
+		PullRequestExists().
+		SummaryCommentExists().
+		NoReviewsExist().
+		GitHubTeamApproverRunning()
+	when.
+		SendingPREvent()
+	then.
+		ExpectErrorAnswerReturned().
+		ExpectStatusErrorReported().
+		ExpectSummaryCommentDeleted()
+}
+
+func TestGitHubTeamApproverLeavesCommentsQuotingTheSummaryAlone(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithNoContributorReviewEnabledAndFooAsApprovingTeam().
+		PullRequestExists().
+		SummaryCommentQuotedByUserExists().
+		CommitsWithBobAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectSuccessAnswerReturned().
+		ExpectStatusSuccessReported().
+		ExpectCommentQuotedByUserLeftAlone()
+}
+
 func TestGitHubTeamApproverReportsInvalidTeamHandlesInConfiguration(t *testing.T) {
 	given, when, then := stages.ApiTest(t)
 
@@ -425,3 +510,1127 @@ func TestNoTeamRequestedForReviewIfConfigurationIsInvalid(t *testing.T) {
 	then.
 		ExpectNoReviewRequestsMade()
 }
+
+func TestGitHubTeamApproverReportsUnknownFieldInConfiguration(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithConfigurationContainingUnknownField().
+		PullRequestExists().
+		NoReviewsExist().
+		GitHubTeamApproverRunning()
+	when.
+		SendingPREvent()
+	then.
+		ExpectErrorAnswerReturned().
+		ExpectStatusErrorReported().
+		ExpectUnknownFieldInStatusDescription()
+}
+
+func TestGitHubTeamApproverReportsInvalidRegexInConfiguration(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithConfigurationContainingInvalidRegex().
+		PullRequestExists().
+		NoReviewsExist().
+		GitHubTeamApproverRunning()
+	when.
+		SendingPREvent()
+	then.
+		ExpectErrorAnswerReturned().
+		ExpectStatusErrorReported().
+		ExpectInvalidRegexInStatusDescription().
+		ExpectNoReviewRequestsMade()
+}
+
+func TestWhenStaleApprovalsAreDismissedAndApprovalIsForLatestCommit(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithStaleApprovalsDismissedAndFooAsApprovingTeam().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithBobAsContributor().
+		AliceApprovesLatestCommit().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectSuccessAnswerReturned().
+		ExpectStatusSuccessReported().
+		ExpectNoCommentsMade().
+		ExpectLabelsUpdated()
+}
+
+func TestWhenStaleApprovalsAreDismissedAndApprovalIsForPreviousCommit(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithStaleApprovalsDismissedAndFooAsApprovingTeam().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithBobAsContributor().
+		AliceApprovesPreviousCommit().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectPendingAnswerReturned().
+		ExpectStatusPendingReported().
+		ExpectStaleReviewerAliceInStatusDescription().
+		ExpectCommentAliceStaleReviewer().
+		ExpectLabelsUpdated().
+		ExpectedReviewRequestsMadeForFoo()
+}
+
+func TestWhenChangesRequestedByTeamMemberAndBlockingIsDisabled(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithFooAsApprovingTeam().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithCharlieAsContributor().
+		AliceApprovesAndBobRequestsChanges().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectSuccessAnswerReturned().
+		ExpectStatusSuccessReported().
+		ExpectLabelsUpdated()
+}
+
+func TestWhenChangesRequestedByTeamMemberAndBlockingIsEnabled(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithBlockOnChangesRequestedAndFooAsApprovingTeam().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithCharlieAsContributor().
+		AliceApprovesAndBobRequestsChanges().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectPendingAnswerReturned().
+		ExpectStatusPendingReported().
+		ExpectBlockingReviewerBobInStatusDescription().
+		ExpectLabelsUpdated()
+}
+
+func TestWhenCodeownersRuleAndOwnersOfAllChangedPathsApproved(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithCodeownersRuleAndFooOwningCode().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithCharlieAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectSuccessAnswerReturned().
+		ExpectStatusSuccessReported().
+		ExpectLabelsUpdated()
+}
+
+func TestWhenCodeownersRuleAndOwnerOfChangedPathDidNotApprove(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithCodeownersRuleAndFooOwningCodeAndCharlieOwningConfig().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithBobAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectPendingAnswerReturned().
+		ExpectStatusPendingReported().
+		ExpectPendingApprovalFromCharlieInStatusDescription().
+		ExpectedReviewRequestsMadeForCharlie()
+}
+
+func TestWhenCodeownersRuleAndNoCodeownersFile(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithCodeownersRuleAndNoCodeownersFile().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithCharlieAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectErrorAnswerReturned().
+		ExpectStatusErrorReported().
+		ExpectMissingCodeownersFileInStatusDescription()
+}
+
+func TestWhenCodeownersRuleAndInvalidCodeownersFile(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithCodeownersRuleAndInvalidCodeownersFile().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithCharlieAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectErrorAnswerReturned().
+		ExpectStatusErrorReported().
+		ExpectInvalidCodeownersFileInStatusDescription()
+}
+
+func TestWhenCodeownersRuleWithMinimumApprovalsAndNoOwnersOfChangedPaths(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithFooAsApprovingTeamAndCodeownersRuleWithoutOwnersOfChangedPaths().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithCharlieAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectSuccessAnswerReturned().
+		ExpectStatusSuccessReported().
+		ExpectLabelsUpdated()
+}
+
+func TestWhenChangedFilesAreNotAllExcludedFromDirectoriesRule(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithFooAsApprovingTeamForAllDirectoriesExceptConfig().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithCharlieAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectSuccessAnswerReturned().
+		ExpectStatusSuccessReported().
+		ExpectLabelsUpdated()
+}
+
+func TestWhenAllChangedFilesAreExcludedFromDirectoriesRule(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithFooAsApprovingTeamForAllDirectoriesExceptCodeAndConfig().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithCharlieAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectPendingAnswerReturned().
+		ExpectStatusPendingReported().
+		ExpectNoRulesMatchedInStatusDescription()
+}
+
+func TestWhenApprovingUserHasNotApproved(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithFooAndCharlieAsApprovers().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithBobAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectPendingAnswerReturned().
+		ExpectStatusPendingReported().
+		ExpectPendingApprovalFromCharlieInStatusDescription().
+		ExpectedReviewRequestsMadeForCharlie()
+}
+
+func TestWhenApprovingUserAndTeamHaveApproved(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithFooAndCharlieAsApprovers().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithBobAsContributor().
+		AliceAndCharlieApprovePullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectSuccessAnswerReturned().
+		ExpectStatusSuccessReported().
+		ExpectLabelsUpdated()
+}
+
+func TestWhenApprovingUserContributedToPullRequest(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithFooAndCharlieAsApprovers().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithCharlieAsContributor().
+		AliceAndCharlieApprovePullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectPendingAnswerReturned().
+		ExpectStatusPendingReported().
+		ExpectPendingApprovalFromCharlieInStatusDescription()
+}
+
+func TestWhenChildTeamMemberApprovesAndChildTeamsAreIncluded(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFooAndChildTeamBar().
+		RepoWithChildTeamsIncludedAndFooAsApprovingTeam().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithBobAsContributor().
+		CharlieApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectSuccessAnswerReturned().
+		ExpectStatusSuccessReported().
+		ExpectLabelsUpdated()
+}
+
+func TestWhenChildTeamMemberApprovesAndChildTeamsAreNotIncluded(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFooAndChildTeamBar().
+		RepoWithFooAsApprovingTeam().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithBobAsContributor().
+		CharlieApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectPendingAnswerReturned().
+		ExpectStatusPendingReported().
+		ExpectInvalidCommentCharlieIgnoredAsReviewer()
+}
+
+func TestWhenConfigurationIsReadFromDefaultBranch(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithFooAsApprovingTeamAndCharlieAsApproverOnMaster().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithBobAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectSuccessAnswerReturned().
+		ExpectStatusSuccessReported().
+		ExpectLabelsUpdated()
+}
+
+func TestWhenConfigurationIsReadFromBaseBranch(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithFooAsApprovingTeamAndCharlieAsApproverOnMaster().
+		ConfigurationReadFromBaseBranch().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithBobAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectPendingAnswerReturned().
+		ExpectStatusPendingReported().
+		ExpectPendingApprovalFromCharlieAndBaseBranchInStatusDescription().
+		ExpectedReviewRequestsMadeForCharlie()
+}
+
+func TestWhenRepoLacksConfigurationFileAndCentralConfigurationExists(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithoutConfigurationFile().
+		CentralRepoWithFooAsApprovingTeam().
+		CentralConfigurationRepositoryConfigured().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithBobAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectSuccessAnswerReturned().
+		ExpectStatusSuccessReported().
+		ExpectLabelsUpdated()
+}
+
+func TestWhenCentralConfigurationHasMandatoryRules(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithFooAsApprovingTeam().
+		CentralRepoWithCharlieAsMandatoryApprover().
+		CentralConfigurationRepositoryConfigured().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithBobAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectPendingAnswerReturned().
+		ExpectStatusPendingReported().
+		ExpectPendingApprovalFromCharlieInStatusDescription().
+		ExpectedReviewRequestsMadeForCharlie()
+}
+
+func TestWhenRepoForciblyApprovesAndCentralMandatoryRulesAreNotFulfilled(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithFooForciblyApprovingEveryPR().
+		CentralRepoWithCharlieAsMandatoryApprover().
+		CentralConfigurationRepositoryConfigured().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithBobAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectPendingAnswerReturned().
+		ExpectStatusPendingReported().
+		ExpectPendingApprovalFromCharlieInStatusDescription().
+		ExpectedReviewRequestsMadeForCharlie()
+}
+
+func TestWhenRepoForciblyApprovesAndCentralMandatoryRulesAreFulfilled(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithFooForciblyApprovingEveryPR().
+		CentralRepoWithCharlieAsMandatoryApprover().
+		CentralConfigurationRepositoryConfigured().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithBobAsContributor().
+		AliceAndCharlieApprovePullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectSuccessAnswerReturned().
+		ExpectStatusSuccessReported()
+}
+
+func TestWhenConfigurationFileIsChangedAndAdminTeamHasNotApproved(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFooAndChildTeamBar().
+		RepoWithFooAsApprovingTeam().
+		ConfigurationAdminTeamBarConfigured().
+		PullRequestChangingConfigurationFileExists().
+		NoCommentsExist().
+		CommitsWithBobAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectPendingAnswerReturned().
+		ExpectStatusPendingReported().
+		ExpectPendingApprovalFromBarForConfigurationChangeInStatusDescription().
+		ExpectedReviewRequestsMadeForBar()
+}
+
+func TestWhenConfigurationFileIsRenamedAndAdminTeamHasNotApproved(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFooAndChildTeamBar().
+		RepoWithFooAsApprovingTeam().
+		ConfigurationAdminTeamBarConfigured().
+		PullRequestRenamingConfigurationFileExists().
+		NoCommentsExist().
+		CommitsWithBobAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectPendingAnswerReturned().
+		ExpectStatusPendingReported().
+		ExpectPendingApprovalFromBarForConfigurationChangeInStatusDescription().
+		ExpectedReviewRequestsMadeForBar()
+}
+
+func TestWhenConfigurationFileIsChangedAndAdminTeamHasApproved(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFooAndChildTeamBar().
+		RepoWithFooAsApprovingTeam().
+		ConfigurationAdminTeamBarConfigured().
+		PullRequestChangingConfigurationFileExists().
+		NoCommentsExist().
+		CommitsWithBobAsContributor().
+		AliceAndCharlieApprovePullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectSuccessAnswerReturned().
+		ExpectStatusSuccessReported().
+		ExpectLabelsUpdated()
+}
+
+func TestWhenConfigurationFileIsNotChangedAndAdminTeamIsConfigured(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFooAndChildTeamBar().
+		RepoWithFooAsApprovingTeam().
+		ConfigurationAdminTeamBarConfigured().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithBobAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectSuccessAnswerReturned().
+		ExpectStatusSuccessReported().
+		ExpectLabelsUpdated()
+}
+
+func TestExplainEndpointExplainsWhyRuleDoesNotApply(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		ExplainAPITokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithFooAsApprovingTeam().
+		PullRequestExists().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		RequestingExplanation()
+	then.
+		ExpectExplanationOfRuleNotMatchingBody().
+		ExpectNoStatusReported().
+		ExpectNoCommentsMade()
+}
+
+func TestExplainEndpointExplainsApprovals(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		ExplainAPITokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithFooAsApprovingTeam().
+		PullRequestImpactingCustomersExists().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		RequestingExplanation()
+	then.
+		ExpectExplanationOfApprovalFromAlice().
+		ExpectNoStatusReported()
+}
+
+func TestExplainEndpointRequiresToken(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		ExplainAPITokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithFooAsApprovingTeam().
+		PullRequestExists().
+		GitHubTeamApproverRunning()
+	when.
+		RequestingExplanationWithoutToken()
+	then.
+		ExpectUnauthorizedReturned()
+}
+
+func TestWhenStatusIsReportedAsCheckRun(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		StatusReportedAsCheckRun().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithFooAsApprovingTeam().
+		PullRequestExists().
+		CommitsWithBobAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectSuccessAnswerReturned().
+		ExpectNoStatusReported().
+		ExpectCompletedCheckRunSummarisingApprovalFromAlice()
+}
+
+func TestWhenStatusIsReportedAsCheckRunAgainTheCheckRunIsUpdated(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		StatusReportedAsCheckRun().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithFooAsApprovingTeam().
+		PullRequestExists().
+		CommitsWithBobAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent().
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectSuccessAnswerReturned().
+		ExpectCompletedCheckRunSummarisingApprovalFromAlice().
+		ExpectCheckRunUpdated()
+}
+
+func TestWhenStatusIsReportedAsCommitStatusAndCheckRun(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		StatusReportedAsCommitStatusAndCheckRun().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithNoContributorReviewEnabledAndFooAsApprovingTeam().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithAliceAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectPendingAnswerReturned().
+		ExpectStatusPendingReported().
+		ExpectInProgressCheckRunSummarisingIgnoredReviewers()
+}
+
+func TestWhenStatusIsReportedAsCommitStatusByDefault(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithFooAsApprovingTeam().
+		PullRequestExists().
+		CommitsWithBobAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectStatusSuccessReported().
+		ExpectNoCheckRunReported()
+}
+
+func TestGitHubTeamApproverReEvaluatesPRWhenLabelsChange(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithNoContributorReviewEnabledAndFooAsApprovingTeam().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithAliceAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingPRLabeledEvent()
+	then.
+		ExpectPendingAnswerReturned().
+		ExpectStatusPendingReported()
+}
+
+func TestGitHubTeamApproverIgnoresChangesOfTheLabelsItManages(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithNoContributorReviewEnabledAndFooAsApprovingTeam().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithAliceAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingPRLabeledEventForItsOwnLabel()
+	then.
+		StatusNoContentReturned().
+		ExpectNoStatusReported()
+}
+
+func TestWhenEventDescribesAnOlderCommitTheHeadOfThePullRequestIsEvaluated(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithNoContributorReviewEnabledAndFooAsApprovingTeam().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithBobAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingPREventForAnOlderCommit()
+	then.
+		ExpectSuccessAnswerReturned().
+		ExpectStatusSuccessReported()
+}
+
+func TestWhenDraftsAreReportedAsPendingRulesAreNotEvaluated(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		DraftsReportedAsPending().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithNoContributorReviewEnabledAndFooAsApprovingTeam().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithAliceAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingDraftPREvent()
+	then.
+		ExpectPendingAnswerReturned().
+		ExpectStatusPendingReportedForDraft().
+		ExpectNoReviewRequestsMade()
+}
+
+func TestWhenDraftsAreSkippedNoStatusIsReported(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		DraftsSkipped().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithNoContributorReviewEnabledAndFooAsApprovingTeam().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithAliceAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingDraftPREvent()
+	then.
+		ExpectNoStatusReported().
+		ExpectNoReviewRequestsMade()
+}
+
+func TestWhenDraftsAreSkippedPRIsEvaluatedOnceReadyForReview(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		DraftsSkipped().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithNoContributorReviewEnabledAndFooAsApprovingTeam().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithAliceAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingPRReadyForReviewEvent()
+	then.
+		ExpectPendingAnswerReturned().
+		ExpectStatusPendingReported()
+}
+
+func TestGitHubTeamApproverAcknowledgesRedeliveriesWithoutHandlingThem(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithNoContributorReviewEnabledAndFooAsApprovingTeam().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithAliceAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent().
+		RedeliveringTheLastEvent()
+	then.
+		ExpectRedeliveryAcknowledged().
+		ExpectMetricsReportingDuplicateDelivery()
+}
+
+func TestGitHubTeamApproverFailsEventsItCannotAuthenticateFor(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		FakeGHRunning().
+		AppPrivateKeyMissing().
+		OrganisationWithTeamFoo().
+		RepoWithNoContributorReviewEnabledAndFooAsApprovingTeam().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithAliceAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectEventFailedToBeHandled().
+		ExpectNoStatusReported()
+	when.
+		RedeliveringTheLastEvent()
+	then.
+		ExpectEventFailedToBeHandled()
+}
+
+func TestGitHubTeamApproverRemembersDeliveriesInAFile(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		DeliveriesRememberedInAFile().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithNoContributorReviewEnabledAndFooAsApprovingTeam().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithAliceAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent().
+		RedeliveringTheLastEvent()
+	then.
+		ExpectRedeliveryAcknowledged().
+		ExpectMetricsReportingDuplicateDelivery()
+}
+
+func TestWhenMembersOfAnApprovingTeamChangeOpenPRsAreReevaluated(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		TeamChangesReevaluatedWithoutDelay().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithFooAsApprovingTeam().
+		PullRequestExists().
+		PullRequestImpactingCustomersIsOpen().
+		NoCommentsExist().
+		CommitsWithAliceAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingMembershipAddedEventForTeamFoo()
+	then.
+		ExpectAcceptedAnswerReturned().
+		ExpectMetricsReportingPullRequestReevaluated().
+		ExpectStatusSuccessReported()
+}
+
+func TestWhenMembersOfAnApprovingTeamReferencedByNameChangeOpenPRsAreReevaluated(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		TeamChangesReevaluatedWithoutDelay().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithFooAsApprovingTeamByName().
+		PullRequestExists().
+		PullRequestImpactingCustomersIsOpen().
+		NoCommentsExist().
+		CommitsWithAliceAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingMembershipAddedEventForTeamFoo()
+	then.
+		ExpectAcceptedAnswerReturned().
+		ExpectMetricsReportingPullRequestReevaluated().
+		ExpectStatusSuccessReported()
+}
+
+func TestWhenMembersOfAnApprovingTeamReferencedByIDChangeOpenPRsAreReevaluated(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		TeamChangesReevaluatedWithoutDelay().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithFooAsApprovingTeamByID().
+		PullRequestExists().
+		PullRequestImpactingCustomersIsOpen().
+		NoCommentsExist().
+		CommitsWithAliceAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingMembershipAddedEventForTeamFoo()
+	then.
+		ExpectAcceptedAnswerReturned().
+		ExpectMetricsReportingPullRequestReevaluated().
+		ExpectStatusSuccessReported()
+}
+
+func TestWhenMembersOfAnotherTeamChangeOpenPRsAreNotReevaluated(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		TeamChangesReevaluatedWithoutDelay().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithFooAsApprovingTeam().
+		PullRequestExists().
+		PullRequestImpactingCustomersIsOpen().
+		NoCommentsExist().
+		CommitsWithAliceAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingMembershipAddedEventForAnotherTeam()
+	then.
+		ExpectAcceptedAnswerReturned().
+		ExpectMetricsReportingNoPullRequestReevaluated().
+		ExpectNoStatusReported()
+}
+
+func TestWhenEventsAreProcessedAsynchronously(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		EventsProcessedAsynchronously().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithNoContributorReviewEnabledAndFooAsApprovingTeam().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithAliceAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectAcceptedAnswerReturned().
+		ExpectStatusPendingEventuallyReported().
+		ExpectMetricsReportingEventProcessed()
+}
+
+func TestEventsAreProcessedAsynchronouslyByDefault(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		EventQueueWorkersNotConfigured().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithNoContributorReviewEnabledAndFooAsApprovingTeam().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithAliceAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectAcceptedAnswerReturned().
+		ExpectStatusPendingEventuallyReported().
+		ExpectMetricsReportingEventProcessed()
+}
+
+func TestWhenEventsForAPullRequestArriveInABurstTheyAreEvaluatedOnce(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		EventsProcessedAsynchronously().
+		EventsDebouncedForLongerThanABurst().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithNoContributorReviewEnabledAndFooAsApprovingTeam().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithAliceAsContributor().
+		AliceApprovesPullRequest().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent().
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectAcceptedAnswerReturned().
+		ExpectStatusPendingEventuallyReported().
+		ExpectMetricsReportingEventCoalesced()
+}
+
+func TestWhenProcessingAnEventAsynchronouslyFailsItIsRetried(t *testing.T) {
+	given, when, then := stages.ApiTest(t)
+
+	given.
+		GitHubWebHookTokenExists().
+		EventsProcessedAsynchronously().
+		FakeGHRunning().
+		OrganisationWithTeamFoo().
+		RepoWithNoContributorReviewEnabledAndFooAsApprovingTeam().
+		PullRequestExists().
+		NoCommentsExist().
+		CommitsWithAliceAsContributor().
+		AliceApprovesPullRequest().
+		StatusReportFailsOnce().
+		GitHubTeamApproverRunning()
+	when.
+		SendingApprovedPRReviewSubmittedEvent()
+	then.
+		ExpectAcceptedAnswerReturned().
+		ExpectStatusPendingEventuallyReported().
+		ExpectMetricsReportingEventRetriedOnce()
+}
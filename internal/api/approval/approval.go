@@ -2,6 +2,8 @@ package approval
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"net/url"
@@ -12,7 +14,8 @@ import (
 
 	"github.com/sirupsen/logrus"
 
-	"github.com/form3tech-oss/github-team-approver-commons/v2/pkg/configuration"
+	"github.com/form3tech-oss/github-team-approver/internal/api/configuration"
+	"github.com/form3tech-oss/github-team-approver/internal/api/glob"
 
 	ghclient "github.com/form3tech-oss/github-team-approver/internal/api/github"
 
@@ -21,14 +24,23 @@ import (
 
 const (
 	pullRequestReviewStateApproved               = "APPROVED"
+	pullRequestReviewStateChangesRequested       = "CHANGES_REQUESTED"
 	pullRequestReviewStateCommented              = "COMMENTED"
 	pullRequestLabelPrefix                       = "github-team-approver/"
 	statusEventDescriptionNoRulesForTargetBranch = "No rules are defined for the target branch."
+	statusEventDescriptionInvalidConfiguration   = "Invalid config:\n%s"
+	statusEventDescriptionInvalidCentralConfig   = "Invalid config in %s:\n%s"
+	statusEventDescriptionDraft                  = "Draft PR"
+	statusEventDescriptionNoCodeownersFile       = "Invalid config: no " + configuration.CodeownersFilePath + " file exists in the target branch."
 	StatusEventStatusPending                     = "pending"
 	StatusEventStatusSuccess                     = "success"
 	StatusEventStatusError                       = "error"
 )
 
+// legacyTeamHandleOrg is the organisation team handles were prefixed with before the prefix named the organisation of
+// the PR, which is still stripped so that existing configuration files keep working.
+const legacyTeamHandleOrg = "form3tech"
+
 var (
 	CoauthorPattern      = regexp.MustCompile("Co-authored-by: .+? <([\\w\\+-]+)@users.noreply.github.com>")
 	ErrInvalidTeamHandle = errors.New("No team could be found with given name or slug")
@@ -36,13 +48,37 @@ var (
 
 type Approval struct {
 	log    *logrus.Entry
-	client *ghclient.Client
+	client DataSource
+	// diffFingerprints caches the fingerprint of the changes introduced by the PR at a given commit.
+	diffFingerprints map[string]string
+	// teamMembers caches the direct members of each team, keyed by team name.
+	teamMembers map[string][]*github.User
+	// commits caches the commits of the PR.
+	commits []*github.RepositoryCommit
+	// explain records which conditions of each rule the PR meets.
+	explain bool
+
+	settings Settings
+}
+
+// Settings holds the options of the service which affect how the approval status of a PR is computed.
+type Settings struct {
+	// ConfigurationSource is where the configuration file is read from.
+	ConfigurationSource configuration.Source
+	// ConfigurationAdminTeamHandle is the handle of the team which must approve changes to the configuration file, if
+	// any.
+	ConfigurationAdminTeamHandle string
+	// DraftsPending reports draft PRs as pending without evaluating the rules for them.
+	DraftsPending bool
 }
 
-func NewApproval(log *logrus.Entry, client *ghclient.Client) *Approval {
+func NewApproval(log *logrus.Entry, client DataSource, settings Settings) *Approval {
 	return &Approval{
-		log:    log,
-		client: client,
+		log:              log,
+		client:           client,
+		settings:         settings,
+		diffFingerprints: make(map[string]string),
+		teamMembers:      make(map[string][]*github.User),
 	}
 }
 
@@ -50,42 +86,82 @@ type PR struct {
 	OwnerLogin    string
 	RepoName      string
 	TargetBranch  string
+	HeadBranch    string
+	HeadSHA       string
+	Title         string
 	Body          string
 	Number        int
 	InitialLabels []string
 	Author        *github.User
+	// Draft is whether the PR is still a draft.
+	Draft bool
+	// Milestone is the title of the milestone of the PR, if any.
+	Milestone string
 }
 
-func NewPR(ownerLogin, repoName, targetBranch, body string, number int, labels []string, author *github.User) *PR {
+func NewPR(ownerLogin, repoName, targetBranch, headBranch, headSHA, title, body string, number int, labels []string, author *github.User) *PR {
 	return &PR{
 		OwnerLogin:    ownerLogin,
 		RepoName:      repoName,
 		Number:        number,
 		TargetBranch:  targetBranch,
+		HeadBranch:    headBranch,
+		HeadSHA:       headSHA,
+		Title:         title,
 		Body:          body,
 		InitialLabels: labels,
 		Author:        author,
 	}
 }
 
+// IsManagedLabel returns whether the label is one of the labels github-team-approver adds to and removes from PRs.
+func IsManagedLabel(name string) bool {
+	return strings.HasPrefix(name, pullRequestLabelPrefix)
+}
+
 func (a *Approval) ComputeApprovalStatus(ctx context.Context, pr *PR) (*Result, error) {
 	// Get the configuration for approvals in the current repository.
-	cfg, err := a.client.GetConfiguration(ctx, pr.OwnerLogin, pr.RepoName)
+	a.log.Tracef("Reading configuration from %s", a.settings.ConfigurationSource)
+	cfg, err := a.client.GetResolvedConfiguration(ctx, pr.OwnerLogin, pr.RepoName, pr.TargetBranch, a.settings.ConfigurationSource)
+	var invalid *configuration.ValidationError
+	if errors.As(err, &invalid) {
+		a.log.WithError(err).Warn("Invalid configuration")
+		return invalidConfigurationResult(pr, invalid), nil
+	}
 	if err != nil {
 		return nil, err
 	}
+	for _, step := range cfg.Resolution {
+		a.log.Tracef("Configuration resolved with %s", step)
+	}
+	if pr.Draft && a.settings.DraftsPending {
+		a.log.Trace("Reporting the draft PR as pending")
+		return &Result{
+			status:      StatusEventStatusPending,
+			description: statusEventDescriptionDraft,
+			trace:       Trace{TargetBranch: pr.TargetBranch, Configuration: a.configurationTrace(cfg, pr)},
+		}, nil
+	}
 
 	rules, err := a.computeRulesForTargetBranch(cfg, pr)
 	if err != nil {
 		return nil, err
 	}
+	guarded, err := a.changesConfigurationFile(ctx, pr)
+	if err != nil {
+		return nil, err
+	}
 	if len(rules) > 0 {
 		a.log.Tracef("A total of %d rules apply to target branch %q", len(rules), pr.TargetBranch)
+	} else if guarded {
+		a.log.Tracef("No rules apply to target branch %q, but the configuration file is changed", pr.TargetBranch)
 	} else {
 		a.log.Tracef("No rules apply to target branch %q", pr.TargetBranch)
 		status := &Result{
 			status:      StatusEventStatusSuccess,
 			description: statusEventDescriptionNoRulesForTargetBranch,
+			source:      a.sourceNote(pr),
+			trace:       Trace{TargetBranch: pr.TargetBranch, Configuration: a.configurationTrace(cfg, pr)},
 		}
 
 		return status, nil
@@ -104,6 +180,7 @@ func (a *Approval) ComputeApprovalStatus(ctx context.Context, pr *PR) (*Result,
 	}
 
 	state := newState()
+	state.noRulesForTargetBranch = len(rules) == 0
 	state.setApprovingReviewers(reviews)
 
 	// Copy all labels not owned by ourselves from the "initialLabels" slice into "finalLabels" so we can update the latter with the final set of labels as we go.
@@ -115,13 +192,20 @@ func (a *Approval) ComputeApprovalStatus(ctx context.Context, pr *PR) (*Result,
 
 	allAllowedMembers := map[string]bool{}
 	// Check if each required team has approved the pull request.
-	for _, rule := range rules {
-		matched, err := a.isRuleMatched(ctx, rule, pr)
+	for i, rule := range rules {
+		matched, reason, err := a.isRuleMatched(ctx, rule, pr)
 		if err != nil {
 			return nil, err
 		}
+		rm := RuleMatch{Rule: i, Matched: matched, Reason: reason, Fulfilled: matched}
+		if a.explain {
+			if rm.Conditions, err = a.conditionMatches(ctx, rule, pr); err != nil {
+				return nil, err
+			}
+		}
 
 		if !matched {
+			state.addRuleMatch(rm)
 			continue
 		}
 
@@ -132,110 +216,305 @@ func (a *Approval) ComputeApprovalStatus(ctx context.Context, pr *PR) (*Result,
 			}
 		}
 
-		mr := NewMatchedRule(rule)
-		// Check the approval status for each rule.
-		for _, handle := range rule.ApprovingTeamHandles {
-			teamName, err := getTeamNameFromTeamHandle(teams, handle)
-			if err != nil {
-				if errors.Is(err, ErrInvalidTeamHandle) {
-					state.addInvalidTeamHandle(handle)
-					continue
-				}
-
-				return nil, err
-			}
-			// Grab the list of members on the current approving team.
-			members, err := a.client.GetTeamMembers(ctx, teams, pr.OwnerLogin, teamName)
+		expanded, err := a.expandCodeownersRule(ctx, pr, rule)
+		if errors.Is(err, ghclient.ErrNoCodeownersFile) {
+			// Not requiring any approval would let every PR through, so the rule can't be fulfilled instead.
+			a.log.WithError(err).Warn("Rule derives its approvers from a missing CODEOWNERS file")
+			return &Result{
+				status:      StatusEventStatusError,
+				description: statusEventDescriptionNoCodeownersFile,
+				source:      a.sourceNote(pr),
+				trace:       Trace{TargetBranch: pr.TargetBranch, Configuration: a.configurationTrace(cfg, pr)},
+			}, nil
+		}
+		if errors.As(err, &invalid) {
+			a.log.WithError(err).Warn("Rule derives its approvers from an invalid CODEOWNERS file")
+			result := invalidConfigurationResult(pr, invalid)
+			result.source = a.sourceNote(pr)
+			result.trace.Configuration = a.configurationTrace(cfg, pr)
+			return result, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, rule := range expanded {
+			mr, err := a.evaluateRule(ctx, state, pr, teams, reviews, rule, allAllowedMembers)
 			if err != nil {
 				return nil, err
 			}
+			mr.Rule = i
+			state.addMatchedRule(mr)
+			rm.record(mr)
+		}
+		state.addRuleMatch(rm)
+	}
 
-			addMembers(allAllowedMembers, members)
-
-			allowed, ignored, err := a.allowedAndIgnoreReviewers(ctx, pr, members, rule.IgnoreContributorApproval)
-			if err != nil {
-				return nil, err
-			}
-			// Check whether the current team has approved the PR.
-			approvalCount := countApprovalsForTeam(reviews, allowed)
-			// Need to use full team handle here, as we'll be comparing recorded handles
-			// to all approving team handles before computing the final status.
-			mr.RecordApproval(handle, approvalCount)
-			state.addIgnoredReviewers(ignored)
+	if guarded {
+		mr, err := a.evaluateRule(ctx, state, pr, teams, reviews, a.configurationGuardRule(), allAllowedMembers)
+		if err != nil {
+			return nil, err
 		}
-		state.addMatchedRule(mr)
+		state.configurationGuard = &mr
 	}
 
 	state.updateInvalidReviewers(allAllowedMembers)
 
 	result := state.result(a.log, teams) // state should not be consumed past this point
+	result.source = a.sourceNote(pr)
+	result.trace.TargetBranch = pr.TargetBranch
+	result.trace.Configuration = a.configurationTrace(cfg, pr)
+	// GitHub refuses to request a review from the author of the PR.
+	result.userReviewsToRequest = deleteIfExisting(result.userReviewsToRequest, pr.Author.GetLogin())
 
-	if result.pendingReviewsWaiting() {
-		if err := a.client.ReportIgnoredReviews(
-			ctx, pr.OwnerLogin, pr.RepoName, pr.Number, result.IgnoredReviewers()); err != nil {
-			return nil, err
+	return result, nil
+}
+
+// invalidConfigurationResult reports the problems found in the configuration as an error, naming the repository the
+// configuration was read from unless it is the one of the PR.
+func invalidConfigurationResult(pr *PR, invalid *configuration.ValidationError) *Result {
+	problems := strings.Join(invalid.Problems, "\n")
+	description := fmt.Sprintf(statusEventDescriptionInvalidConfiguration, problems)
+	if invalid.Repository != "" && invalid.Repository != pr.RepoName {
+		description = fmt.Sprintf(statusEventDescriptionInvalidCentralConfig, invalid.Repository, problems)
+	}
+	return &Result{
+		status:      StatusEventStatusError,
+		description: description,
+		trace:       Trace{TargetBranch: pr.TargetBranch, ConfigurationErrors: invalid.Problems},
+	}
+}
+
+// sourceNote returns the note appended to the status description on where the configuration was read from, which is
+// shortened for the default branch. The trace records the full note.
+func (a *Approval) sourceNote(pr *PR) string {
+	return a.settings.ConfigurationSource.Note(pr.TargetBranch)
+}
+
+// configurationTrace notes where the configuration was read from, followed by the steps it was resolved with.
+func (a *Approval) configurationTrace(cfg *configuration.Configuration, pr *PR) []string {
+	steps := []string{a.settings.ConfigurationSource.Describe(pr.TargetBranch)}
+	for _, step := range cfg.Resolution {
+		steps = append(steps, "Resolved with "+step+".")
+	}
+	return steps
+}
+
+// evaluateRule records the approvals given by the members of each approving team of the given rule.
+func (a *Approval) evaluateRule(ctx context.Context, state *state, pr *PR, teams []*github.Team, reviews []*github.PullRequestReview, rule configuration.Rule, allAllowedMembers map[string]bool) (MatchedRule, error) {
+	mr := NewMatchedRule(rule)
+	// Check the approval status for each rule.
+	for _, handle := range rule.ApprovingHandles() {
+		// Grab the list of members on the current approving team.
+		members, err := a.approvingMembers(ctx, teams, pr, handle, rule.IncludeChildTeams)
+		if err != nil {
+			if errors.Is(err, ErrInvalidTeamHandle) {
+				state.addInvalidTeamHandle(handle)
+				continue
+			}
+
+			return mr, err
 		}
 
-		if err := a.client.ReportInvalidReviews(
-			ctx, pr.OwnerLogin, pr.RepoName, pr.Number, result.InvalidReviewers()); err != nil {
+		addMembers(allAllowedMembers, members)
+
+		allowed, ignored, err := a.allowedAndIgnoreReviewers(ctx, pr, members, rule.IgnoreContributorApproval)
+		if err != nil {
+			return mr, err
+		}
+		// Check whether the current team has approved the PR.
+		approvers, stale, err := a.discardStaleApprovals(ctx, pr, rule.StaleApprovals, approvalsForTeam(reviews, allowed))
+		if err != nil {
+			return mr, err
+		}
+		// Need to use full team handle here, as we'll be comparing recorded handles
+		// to all approving team handles before computing the final status.
+		mr.RecordApproval(handle, approvers)
+		state.addIgnoredReviewers(ignored)
+		state.addStaleReviewers(stale)
+
+		if rule.BlockOnChangesRequested {
+			mr.RecordChangesRequested(changesRequestedByTeam(reviews, memberLogins(members)))
+		}
+	}
+	return mr, nil
+}
+
+// approvingMembers returns the users allowed to approve on behalf of the given handle, which is either a team handle
+// or the prefixed login of an individual user. When includeChildTeams is set, members of the child teams of the team
+// are returned as well.
+func (a *Approval) approvingMembers(ctx context.Context, teams []*github.Team, pr *PR, handle string, includeChildTeams bool) ([]*github.User, error) {
+	if login := strings.TrimPrefix(handle, configuration.UserHandlePrefix); login != handle {
+		return []*github.User{{Login: github.String(login)}}, nil
+	}
+
+	teamName, err := GetTeamNameFromTeamHandle(teams, pr.OwnerLogin, handle)
+	if err != nil {
+		return nil, err
+	}
+	if !includeChildTeams {
+		return a.getTeamMembers(ctx, teams, pr, teamName)
+	}
+
+	var members []*github.User
+	seen := map[string]bool{}
+	for _, team := range teamHierarchy(teams, teamName) {
+		teamMembers, err := a.getTeamMembers(ctx, teams, pr, team.GetName())
+		if err != nil {
 			return nil, err
 		}
+		for _, m := range teamMembers {
+			if !seen[m.GetLogin()] {
+				seen[m.GetLogin()] = true
+				members = append(members, m)
+			}
+		}
 	}
+	return members, nil
+}
 
-	return result, nil
+// getTeamMembers returns the direct members of the team with the given name.
+func (a *Approval) getTeamMembers(ctx context.Context, teams []*github.Team, pr *PR, teamName string) ([]*github.User, error) {
+	if members, ok := a.teamMembers[teamName]; ok {
+		return members, nil
+	}
+
+	members, err := a.client.GetTeamMembers(ctx, teams, pr.OwnerLogin, teamName)
+	if err != nil {
+		return nil, err
+	}
+	a.teamMembers[teamName] = members
+	return members, nil
 }
 
-func addMembers(allowed map[string]bool, members []*github.User) {
-	for _, m := range members {
-		allowed[m.GetLogin()] = true
+// teamHierarchy returns the team with the given name followed by all of its descendants, as given by the parent of
+// each team. Each team is returned once, even if the hierarchy contains cycles.
+func teamHierarchy(teams []*github.Team, name string) []*github.Team {
+	var hierarchy []*github.Team
+	visited := map[int64]bool{}
+	for _, team := range teams {
+		if team.GetName() == name {
+			hierarchy = append(hierarchy, team)
+			visited[team.GetID()] = true
+			break
+		}
 	}
+
+	for i := 0; i < len(hierarchy); i++ {
+		for _, team := range teams {
+			if team.GetParent() != nil && team.GetParent().GetID() == hierarchy[i].GetID() && !visited[team.GetID()] {
+				hierarchy = append(hierarchy, team)
+				visited[team.GetID()] = true
+			}
+		}
+	}
+	return hierarchy
 }
 
-func (a *Approval) isRuleMatched(ctx context.Context, rule configuration.Rule, pr *PR) (bool, error) {
-	// Check whether the pull request's body matches the aforementioned regex (ignoring case).
-	prBodyMatch, err := a.isRegexMatched(ctx, pr.OwnerLogin, pr.RepoName, pr.Number, rule.Regex, pr.Body)
+// expandCodeownersRule replaces a rule with "codeowners" set with one rule per distinct set of owners of the files
+// changed by the PR, each of them requiring an approval from any of those owners.
+// Other rules are returned as is. ghclient.ErrNoCodeownersFile is returned if the target branch has no CODEOWNERS file,
+// and a configuration.ValidationError if the file can't be parsed.
+func (a *Approval) expandCodeownersRule(ctx context.Context, pr *PR, rule configuration.Rule) ([]configuration.Rule, error) {
+	if !rule.Codeowners {
+		return []configuration.Rule{rule}, nil
+	}
+
+	content, err := a.client.GetCodeowners(ctx, pr.OwnerLogin, pr.RepoName, pr.TargetBranch)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	// check whether there is a rule on a directory and it has changed
-	directoriesMatch, err := a.areDirectoriesMatched(ctx, pr.OwnerLogin, pr.RepoName, pr.Number, rule.Directories)
+	owners, err := parseCodeowners(content)
 	if err != nil {
-		return false, err
+		return nil, &configuration.ValidationError{Problems: []string{err.Error()}}
 	}
-	// check whether there is a rule on a label and it matches
-	prLabelMatch, err := a.isRegexLabelMatched(ctx, pr.OwnerLogin, pr.RepoName, pr.Number, rule.RegexLabel)
+
+	commitFiles, err := a.client.GetPullRequestCommitFiles(ctx, pr.OwnerLogin, pr.RepoName, pr.Number)
 	if err != nil {
-		return false, err
+		return nil, fmt.Errorf("codeowners: get pull request commit files: %w", err)
+	}
+	paths := make([]string, 0, len(commitFiles))
+	for _, f := range commitFiles {
+		paths = append(paths, f.GetFilename())
 	}
 
-	if !prBodyMatch && !directoriesMatch && !prLabelMatch {
-		a.log.Tracef("PR doesn't match regular expression %v, directory %v or label regular expression %v", rule.Regex, rule.Directories, rule.RegexLabel)
-		return false, nil
+	var expanded []configuration.Rule
+	for _, group := range owners.ownerGroups(paths) {
+		teamHandles, userHandles := codeownersHandles(pr.OwnerLogin, group)
+		if len(teamHandles) == 0 && len(userHandles) == 0 {
+			continue
+		}
+		r := rule
+		r.ApprovalMode = configuration.ApprovalModeRequireAny
+		r.ApprovingTeamHandles = teamHandles
+		r.ApprovingUserHandles = userHandles
+		expanded = append(expanded, r)
+	}
+	if len(expanded) == 0 {
+		// None of the changed files have owners, so no approvals are required (not even a minimum number of them, as
+		// nobody could give them).
+		a.log.Tracef("No owners found in %s for the files changed by the PR", configuration.CodeownersFilePath)
+		r := rule
+		r.ApprovalMode = configuration.ApprovalModeRequireAll
+		r.ApprovingTeamHandles = []string{}
+		r.ApprovingUserHandles = []string{}
+		r.MinimumApprovals = 0
+		r.TeamMinimumApprovals = nil
+		expanded = append(expanded, r)
+	}
+	return expanded, nil
+}
+
+// codeownersHandles converts CODEOWNERS owners into approving team and user handles.
+// Teams of the PR's organisation ("@org/slug") become their slug, users ("@login") their login, and e-mail addresses
+// are left out as they cannot be mapped to GitHub users.
+func codeownersHandles(ownerLogin string, owners []string) ([]string, []string) {
+	teamHandles, userHandles := []string{}, []string{}
+	for _, owner := range owners {
+		if !strings.HasPrefix(owner, "@") {
+			continue
+		}
+		org, slug, ok := strings.Cut(strings.TrimPrefix(owner, "@"), "/")
+		switch {
+		case !ok:
+			userHandles = append(userHandles, org)
+		case strings.EqualFold(org, ownerLogin):
+			teamHandles = append(teamHandles, slug)
+		default:
+			teamHandles = append(teamHandles, org+"/"+slug)
+		}
 	}
+	return teamHandles, userHandles
+}
 
-	shouldMatchDirectories := len(rule.Directories) > 0
-	if shouldMatchDirectories && !directoriesMatch {
-		a.log.WithField("directories", rule.Directories).Tracef("Rule has 'directories' set but PR does not match")
-		return false, nil
+func addMembers(allowed map[string]bool, members []*github.User) {
+	for _, m := range members {
+		allowed[m.GetLogin()] = true
 	}
+}
 
-	shouldMatchBody := rule.Regex != ""
-	if shouldMatchBody && !prBodyMatch {
-		a.log.WithField("regex", rule.Regex).Tracef("Rule has 'regex' set but PR does not match")
-		return false, nil
+// isRuleMatched returns whether the rule applies to the PR, along with a human-readable reason.
+func (a *Approval) isRuleMatched(ctx context.Context, rule configuration.Rule, pr *PR) (bool, string, error) {
+	condition := rule.Condition()
+	if condition.IsEmpty() {
+		// Rules deriving their approving teams from CODEOWNERS apply to every PR unless restricted further.
+		// Any other rule must set at least one condition.
+		if rule.Codeowners {
+			return true, "rule derives its approvers from CODEOWNERS", nil
+		}
+		return false, "rule doesn't set any condition", nil
 	}
 
-	shouldMatchLabels := rule.RegexLabel != ""
-	if shouldMatchLabels && !prLabelMatch {
-		a.log.WithField("regex_label", rule.RegexLabel).Tracef("Rule has 'regex_label' set but PR does not match")
-		return false, nil
+	matched, reason, err := a.NewMatcher(condition).Match(ctx, pr)
+	if err != nil {
+		return false, "", err
 	}
 
 	a.log.WithFields(logrus.Fields{
-		"pr":   pr.Number,
-		"rule": rule,
-	}).Tracef("PR matches rule")
-	return true, nil
+		"pr":      pr.Number,
+		"rule":    rule,
+		"matched": matched,
+	}).Tracef("Rule matching: %s", reason)
+	return matched, reason, nil
 }
 
 func (a *Approval) isRegexMatched(ctx context.Context, ownerLogin, repoName string, prNumber int, regex string, body string) (bool, error) {
@@ -252,24 +531,65 @@ func (a *Approval) isRegexMatched(ctx context.Context, ownerLogin, repoName stri
 	return prBodyMatch, nil
 }
 
-func (a *Approval) areDirectoriesMatched(ctx context.Context, ownerLogin, repoName string, prNumber int, directories []string) (bool, error) {
-	var matchedDirectories []string
+// areDirectoriesMatched returns true if any file changed by the PR is in one of the given directories (or any
+// directory, if none are given) and in none of the excluded ones.
+func (a *Approval) areDirectoriesMatched(ctx context.Context, ownerLogin, repoName string, prNumber int, directories, excludeDirectories []string) (bool, error) {
+	if len(directories) == 0 && len(excludeDirectories) == 0 {
+		return false, nil
+	}
 
-	for _, directory := range directories {
-		commitFiles, err := a.client.GetPullRequestCommitFiles(ctx, ownerLogin, repoName, prNumber)
-		if err != nil {
-			return false, fmt.Errorf("directory match: get pull request commit files: %w", err)
+	commitFiles, err := a.client.GetPullRequestCommitFiles(ctx, ownerLogin, repoName, prNumber)
+	if err != nil {
+		return false, fmt.Errorf("directory match: get pull request commit files: %w", err)
+	}
+
+	for _, commitFile := range commitFiles {
+		included := len(directories) == 0
+		for _, directory := range directories {
+			if included, err = isFileInDirectory(directory, commitFile); err != nil {
+				return false, fmt.Errorf("directory match: is directory changed: %w", err)
+			}
+			if included {
+				break
+			}
 		}
-		directoryMatched, err := isDirectoryChanged(directory, commitFiles)
-		if err != nil {
-			return false, fmt.Errorf("directory match: is directory changed: %w", err)
+		if !included {
+			continue
+		}
+
+		excluded := false
+		for _, directory := range excludeDirectories {
+			if excluded, err = isFileInDirectory(directory, commitFile); err != nil {
+				return false, fmt.Errorf("directory match: is directory excluded: %w", err)
+			}
+			if excluded {
+				break
+			}
 		}
-		if directoryMatched {
-			matchedDirectories = append(matchedDirectories, directory)
+		if !excluded {
+			return true, nil
 		}
 	}
 
-	return len(matchedDirectories) > 0, nil
+	return false, nil
+}
+
+func (a *Approval) isRegexCommitMessageMatched(ctx context.Context, pr *PR, regex string) (bool, error) {
+	if regex == "" {
+		return false, nil
+	}
+
+	commits, err := a.getPRCommits(ctx, pr)
+	if err != nil {
+		return false, fmt.Errorf("regex commit message match: get PR commits: %w", err)
+	}
+
+	for _, commit := range commits {
+		if matched, err := a.isRegexMatched(ctx, pr.OwnerLogin, pr.RepoName, pr.Number, regex, commit.GetCommit().GetMessage()); err != nil || matched {
+			return matched, err
+		}
+	}
+	return false, nil
 }
 
 func (a *Approval) isRegexLabelMatched(ctx context.Context, ownerLogin, repoName string, prNumber int, regexLabel string) (bool, error) {
@@ -291,9 +611,19 @@ func (a *Approval) isRegexLabelMatched(ctx context.Context, ownerLogin, repoName
 	return false, nil
 }
 
-// return true if there were any changes in the specified directory. If the directory starts with '/' match is done
-// with HasPrefix, otherwise match is done with Contains function
-func isDirectoryChanged(directory string, commitFiles []*github.CommitFile) (bool, error) {
+// return true if the commit file is in the specified directory. Glob patterns (e.g. '**/*.tf' or
+// 'charts/*/values.yaml') are matched against the path of the file and its parent directories from the root of the
+// repository. Otherwise, if the directory starts with '/' match is done with HasPrefix, and with Contains function
+// if it doesn't.
+func isFileInDirectory(directory string, commitFile *github.CommitFile) (bool, error) {
+	// we are not checking changes (or additions/deletions) because this can be a new or deleted file
+	if commitFile == nil || commitFile.ContentsURL == nil {
+		return false, fmt.Errorf("commit file %+v has nil contents url, skipping", commitFile)
+	}
+
+	if glob.IsPattern(directory) {
+		return matchGlob(directory, commitFile.GetFilename())
+	}
 
 	var startsWith bool
 	directory = strings.TrimSuffix(directory, "/")
@@ -302,29 +632,15 @@ func isDirectoryChanged(directory string, commitFiles []*github.CommitFile) (boo
 		directory = strings.TrimPrefix(directory, "/")
 	}
 
-	for _, commitFile := range commitFiles {
-
-		// we are not checking changes (or additions/deletions) because this can be a new or deleted file
-		if commitFile == nil || commitFile.ContentsURL == nil {
-			return false, fmt.Errorf("commit file %+v has nil contents url, skipping", commitFile)
-		}
-
-		relPath, err := contentsUrlToRelDir(*commitFile.ContentsURL)
-		if err != nil {
-			return false, err
-		}
+	relPath, err := contentsUrlToRelDir(*commitFile.ContentsURL)
+	if err != nil {
+		return false, err
+	}
 
-		if startsWith {
-			if strings.HasPrefix(relPath, directory) {
-				return true, nil
-			}
-		} else {
-			if strings.Contains(relPath, directory) {
-				return true, nil
-			}
-		}
+	if startsWith {
+		return strings.HasPrefix(relPath, directory), nil
 	}
-	return false, nil
+	return strings.Contains(relPath, directory), nil
 }
 
 // return relative directory of contents url (strips 'https://api.github.com/repos/<org>/<repo>/' and file part)
@@ -350,15 +666,35 @@ func (a *Approval) computeRulesForTargetBranch(cfg *configuration.Configuration,
 	a.log.Tracef("Computing the set of rules that applies to target branch %q", pr.TargetBranch)
 
 	var rules []configuration.Rule
-	for _, prCfg := range cfg.PullRequestApprovalRules {
-		if len(prCfg.TargetBranches) == 0 || indexOf(prCfg.TargetBranches, pr.TargetBranch) >= 0 {
+	for i, prCfg := range cfg.PullRequestApprovalRules {
+		pattern, matched, err := prCfg.MatchTargetBranch(pr.TargetBranch)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			a.log.WithField("rule_set", i).Tracef("Rule set selected by target branch pattern %q", pattern)
 			rules = append(rules, prCfg.Rules...)
 		}
 	}
 	return rules, nil
 }
 
-func countApprovalsForTeam(reviews []*github.PullRequestReview, teamMembers []string) (approvalCount int) {
+// approvalsForTeam returns the latest review of each team member who approved the pull request.
+func approvalsForTeam(reviews []*github.PullRequestReview, teamMembers []string) []*github.PullRequestReview {
+	return latestReviewsInState(reviews, teamMembers, pullRequestReviewStateApproved)
+}
+
+// changesRequestedByTeam returns the team members whose latest review requested changes to the pull request.
+func changesRequestedByTeam(reviews []*github.PullRequestReview, teamMembers []string) []string {
+	var logins []string
+	for _, r := range latestReviewsInState(reviews, teamMembers, pullRequestReviewStateChangesRequested) {
+		logins = append(logins, r.GetUser().GetLogin())
+	}
+	return logins
+}
+
+// latestReviewsInState returns the latest review of each team member, provided it is in the given state.
+func latestReviewsInState(reviews []*github.PullRequestReview, teamMembers []string, state string) []*github.PullRequestReview {
 	// Build a map containing the usernames of each team member.
 	isTeamMember := map[string]bool{}
 	for _, t := range teamMembers {
@@ -378,19 +714,126 @@ func countApprovalsForTeam(reviews []*github.PullRequestReview, teamMembers []st
 		}
 	}
 
-	// Count and return how many approvals we've got from team members.
-	approvalCount = 0
+	// Collect and return the latest reviews in the requested state.
+	var latest []*github.PullRequestReview
 	for _, r := range lastReviewByTeamMember {
-		if r.GetState() == pullRequestReviewStateApproved {
-			approvalCount += 1
+		if r.GetState() == state {
+			latest = append(latest, r)
+		}
+	}
+	sort.Slice(latest, func(i, j int) bool {
+		return latest[i].GetUser().GetLogin() < latest[j].GetUser().GetLogin()
+	})
+	return latest
+}
+
+func memberLogins(members []*github.User) []string {
+	logins := make([]string, 0, len(members))
+	for _, m := range members {
+		logins = append(logins, m.GetLogin())
+	}
+	return logins
+}
+
+// discardStaleApprovals splits the given approvals into the logins of the reviewers whose approval counts and the
+// logins of the reviewers whose approval was given for a previous version of the pull request.
+func (a *Approval) discardStaleApprovals(ctx context.Context, pr *PR, mode string, approvals []*github.PullRequestReview) ([]string, []string, error) {
+	var approvers, stale []string
+	for _, r := range approvals {
+		fresh, err := a.isApprovalFresh(ctx, pr, mode, r)
+		if err != nil {
+			return nil, nil, err
+		}
+		if fresh {
+			approvers = append(approvers, r.GetUser().GetLogin())
+		} else {
+			stale = append(stale, r.GetUser().GetLogin())
 		}
 	}
-	return approvalCount
+	return approvers, stale, nil
 }
 
-func getTeamNameFromTeamHandle(teams []*github.Team, v string) (string, error) {
-	// Remove the "form3tech/" prefix from the team handle if it is present.
-	v = strings.TrimPrefix(v, "form3tech/")
+func (a *Approval) isApprovalFresh(ctx context.Context, pr *PR, mode string, review *github.PullRequestReview) (bool, error) {
+	switch {
+	case mode != configuration.StaleApprovalsDismiss && mode != configuration.StaleApprovalsDismissIfDiffChanged:
+		return true, nil
+	case review.GetCommitID() == pr.HeadSHA:
+		return true, nil
+	case mode == configuration.StaleApprovalsDismiss:
+		return false, nil
+	}
+
+	reviewed, err := a.diffFingerprint(ctx, pr, review.GetCommitID())
+	if err != nil {
+		return false, err
+	}
+	head, err := a.diffFingerprint(ctx, pr, pr.HeadSHA)
+	if err != nil {
+		return false, err
+	}
+	a.log.WithFields(logrus.Fields{
+		"reviewer":  review.GetUser().GetLogin(),
+		"commit_id": review.GetCommitID(),
+	}).Tracef("Approval given for a previous commit, diff unchanged: %t", reviewed == head)
+	return reviewed == head, nil
+}
+
+// diffFingerprint returns a fingerprint of the changes introduced by the PR as of the given commit.
+func (a *Approval) diffFingerprint(ctx context.Context, pr *PR, commitID string) (string, error) {
+	if fingerprint, ok := a.diffFingerprints[commitID]; ok {
+		return fingerprint, nil
+	}
+
+	var fingerprint string
+	files, err := a.client.CompareCommits(ctx, pr.OwnerLogin, pr.RepoName, pr.TargetBranch, commitID)
+	switch {
+	case errors.Is(err, ghclient.ErrComparisonTruncated):
+		// The files left out of the comparison may differ, so the changes are only considered the same at the same
+		// commit.
+		fingerprint = "truncated:" + commitID
+	case err != nil:
+		return "", fmt.Errorf("diff fingerprint: %w", err)
+	default:
+		fingerprint = fingerprintCommitFiles(files)
+	}
+	a.diffFingerprints[commitID] = fingerprint
+	return fingerprint, nil
+}
+
+// fingerprintCommitFiles hashes the changes made to each file. Hunk headers are left out as the line numbers they
+// contain shift whenever the base branch changes the same files. GitHub leaves the patch out for binary and large
+// files, whose contents are hashed through their blob SHA instead.
+func fingerprintCommitFiles(files []*github.CommitFile) string {
+	sorted := make([]*github.CommitFile, len(files))
+	copy(sorted, files)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].GetFilename() < sorted[j].GetFilename()
+	})
+
+	h := sha256.New()
+	for _, f := range sorted {
+		fmt.Fprintf(h, "%s\x00%s\x00", f.GetFilename(), f.GetStatus())
+		if f.GetPatch() == "" {
+			fmt.Fprintf(h, "%s\x00", f.GetSHA())
+			continue
+		}
+		for _, line := range strings.Split(f.GetPatch(), "\n") {
+			if !strings.HasPrefix(line, "@@") {
+				fmt.Fprintf(h, "%s\n", line)
+			}
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// GetTeamNameFromTeamHandle returns the name of the team a handle refers to, by its ID, slug or name, optionally
+// prefixed by "<org>/" for the organisation of the PR.
+func GetTeamNameFromTeamHandle(teams []*github.Team, ownerLogin, v string) (string, error) {
+	// Remove the "<org>/" prefix from the team handle if it names the organisation of the PR, or is the legacy
+	// "form3tech/" prefix.
+	if org, slug, ok := strings.Cut(v, "/"); ok && (strings.EqualFold(org, ownerLogin) || org == legacyTeamHandleOrg) {
+		v = slug
+	}
 	// Lookup the resulting handle in the list of teams.
 	for _, team := range teams {
 		if strconv.FormatInt(team.GetID(), 10) == v || team.GetSlug() == v || team.GetName() == v {
@@ -404,7 +847,7 @@ func (a *Approval) allowedAndIgnoreReviewers(ctx context.Context, pr *PR, member
 	commits := []*github.RepositoryCommit{}
 	if ignoreContributors {
 		var err error
-		commits, err = a.client.GetPRCommits(ctx, pr.OwnerLogin, pr.RepoName, pr.Number)
+		commits, err = a.getPRCommits(ctx, pr)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -419,6 +862,20 @@ func (a *Approval) allowedAndIgnoreReviewers(ctx context.Context, pr *PR, member
 	return allowed, ignored, nil
 }
 
+// getPRCommits returns the commits of the PR.
+func (a *Approval) getPRCommits(ctx context.Context, pr *PR) ([]*github.RepositoryCommit, error) {
+	if a.commits != nil {
+		return a.commits, nil
+	}
+
+	commits, err := a.client.GetPRCommits(ctx, pr.OwnerLogin, pr.RepoName, pr.Number)
+	if err != nil {
+		return nil, err
+	}
+	a.commits = commits
+	return commits, nil
+}
+
 func filterAllowedAndIgnoreReviewers(members []*github.User, commits []*github.RepositoryCommit, events []*github.IssueEvent) ([]string, []string) {
 	authors := map[string]bool{}
 	for _, c := range commits {
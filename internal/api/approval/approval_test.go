@@ -2,58 +2,48 @@ package approval
 
 import (
 	"testing"
+	"time"
 
 	"github.com/google/go-github/v42/github"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-func TestIsDirectoryChanged(t *testing.T) {
+func TestIsFileInDirectory(t *testing.T) {
 
-	t.Run("absolute directory and matching commit files returns true", func(t *testing.T) {
+	t.Run("absolute directory and matching commit file returns true", func(t *testing.T) {
 
-		commitFiles := getCommitFiles(
-			"https://api.github.com/repos/octocat/Hello-World/production/file1.txt?ref=6dcb09b5b57875f334f61aebed695e2e4193db5e",
-			"https://api.github.com/repos/octocat/Hello-World/staging/file1.txt?ref=6dcb09b5b57875f334f61aebed695e2e4193db5e",
-		)
-		changed, err := isDirectoryChanged("/production", commitFiles)
+		commitFile := getCommitFile("https://api.github.com/repos/octocat/Hello-World/production/file1.txt?ref=6dcb09b5b57875f334f61aebed695e2e4193db5e")
+		changed, err := isFileInDirectory("/production", commitFile)
 
 		require.NoError(t, err)
 		assert.True(t, changed)
 	})
 
-	t.Run("absolute directory ending with '/' and matching commit files returns true", func(t *testing.T) {
+	t.Run("absolute directory ending with '/' and matching commit file returns true", func(t *testing.T) {
 
-		commitFiles := getCommitFiles(
-			"https://api.github.com/repos/octocat/Hello-World/production/file1.txt?ref=6dcb09b5b57875f334f61aebed695e2e4193db5e",
-			"https://api.github.com/repos/octocat/Hello-World/staging/file1.txt?ref=6dcb09b5b57875f334f61aebed695e2e4193db5e",
-		)
-		changed, err := isDirectoryChanged("/production/", commitFiles)
+		commitFile := getCommitFile("https://api.github.com/repos/octocat/Hello-World/production/file1.txt?ref=6dcb09b5b57875f334f61aebed695e2e4193db5e")
+		changed, err := isFileInDirectory("/production/", commitFile)
 
 		require.NoError(t, err)
 		assert.True(t, changed)
 	})
 
-	t.Run("absolute directory and not matching commits files returns false", func(t *testing.T) {
+	t.Run("absolute directory and not matching commit file returns false", func(t *testing.T) {
 
 		// matching absolute '/production' but commit is to '/docs/production'
-		commitFiles := getCommitFiles(
-			"https://api.github.com/repos/octocat/Hello-World/docs/production/file1.txt?ref=6dcb09b5b57875f334f61aebed695e2e4193db5e",
-			"https://api.github.com/repos/octocat/Hello-World/staging/file1.txt?ref=6dcb09b5b57875f334f61aebed695e2e4193db5e",
-		)
-		changed, err := isDirectoryChanged("/production", commitFiles)
+		commitFile := getCommitFile("https://api.github.com/repos/octocat/Hello-World/docs/production/file1.txt?ref=6dcb09b5b57875f334f61aebed695e2e4193db5e")
+		changed, err := isFileInDirectory("/production", commitFile)
 
 		require.NoError(t, err)
 		assert.False(t, changed)
 	})
 
-	t.Run("relative directory and matching commit files returns true", func(t *testing.T) {
+	t.Run("relative directory and matching commit file returns true", func(t *testing.T) {
 
 		// matching relative 'production' and commit is to '/docs/production'
-		commitFiles := getCommitFiles(
-			"https://api.github.com/repos/octocat/Hello-World/docs/production/file1.txt?ref=6dcb09b5b57875f334f61aebed695e2e4193db5e",
-		)
-		changed, err := isDirectoryChanged("production", commitFiles)
+		commitFile := getCommitFile("https://api.github.com/repos/octocat/Hello-World/docs/production/file1.txt?ref=6dcb09b5b57875f334f61aebed695e2e4193db5e")
+		changed, err := isFileInDirectory("production", commitFile)
 
 		require.NoError(t, err)
 		assert.True(t, changed)
@@ -62,40 +52,81 @@ func TestIsDirectoryChanged(t *testing.T) {
 	t.Run("relative directory matching commit file name returns false", func(t *testing.T) {
 
 		// matching relative 'production' and commit is to '/docs/production' file not directory
-		commitFiles := getCommitFiles(
-			"https://api.github.com/repos/octocat/Hello-World/docs/production?ref=6dcb09b5b57875f334f61aebed695e2e4193db5e",
-		)
-		changed, err := isDirectoryChanged("production", commitFiles)
+		commitFile := getCommitFile("https://api.github.com/repos/octocat/Hello-World/docs/production?ref=6dcb09b5b57875f334f61aebed695e2e4193db5e")
+		changed, err := isFileInDirectory("production", commitFile)
 
 		require.NoError(t, err)
 		assert.False(t, changed)
 	})
 
-	t.Run("invalid commit files returns error", func(t *testing.T) {
+	t.Run("invalid commit file returns error", func(t *testing.T) {
 
 		// matching relative 'production' and commit is to '/docs/production'
-		commitFiles := getCommitFiles(
-			"https://api.github.com/production/file1.txt?ref=6dcb09b5b57875f334f61aebed695e2e4193db5e",
-		)
-		_, err := isDirectoryChanged("production", commitFiles)
+		commitFile := getCommitFile("https://api.github.com/production/file1.txt?ref=6dcb09b5b57875f334f61aebed695e2e4193db5e")
+		_, err := isFileInDirectory("production", commitFile)
 
 		require.Error(t, err)
 	})
 
 	t.Run("nil commit content url returns error", func(t *testing.T) {
 
-		commitFiles := []*github.CommitFile{{}}
-		_, err := isDirectoryChanged("production", commitFiles)
+		_, err := isFileInDirectory("production", &github.CommitFile{})
 
 		require.Error(t, err)
 	})
 
-	t.Run("nil commit files returns false", func(t *testing.T) {
+	t.Run("nil commit file returns error", func(t *testing.T) {
 
-		changed, err := isDirectoryChanged("production", nil)
+		_, err := isFileInDirectory("production", nil)
 
-		require.NoError(t, err)
-		assert.False(t, changed)
+		require.Error(t, err)
+	})
+}
+
+func TestIsFileInDirectoryWithGlobPattern(t *testing.T) {
+	tests := map[string]struct {
+		directory string
+		filename  string
+		want      bool
+	}{
+		"recursive extension match":           {"**/*.tf", "infra/modules/vpc/main.tf", true},
+		"recursive extension match at root":   {"**/*.tf", "main.tf", true},
+		"recursive extension mismatch":        {"**/*.tf", "infra/main.tfvars", false},
+		"single segment wildcard":             {"charts/*/values.yaml", "charts/api/values.yaml", true},
+		"single segment wildcard too deep":    {"charts/*/values.yaml", "charts/api/sub/values.yaml", false},
+		"directory contents":                  {"deploy/**", "deploy/docs/README.md", true},
+		"parent directory match":              {"deploy/*", "deploy/prod/values.yaml", true},
+		"patterns are relative to the root":   {"api/*", "internal/rapid/api/main.go", false},
+		"leading slash":                       {"/api/*", "api/main.go", true},
+		"leading slash in filename":           {"api/*", "/api/main.go", true},
+		"alternation":                         {"{api,web}/**/*.go", "web/handler.go", true},
+		"character class":                     {"env-[0-9]/*", "env-1/values.yaml", true},
+		"negated character class":             {"env-[!0-9]/*", "env-1/values.yaml", false},
+		"question mark doesn't match slash":   {"api?main.go", "api/main.go", false},
+		"question mark matches one character": {"api?.go", "api2.go", true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			commitFile := &github.CommitFile{
+				Filename:    github.String(tt.filename),
+				ContentsURL: github.String("https://api.github.com/repos/octocat/Hello-World/contents/" + tt.filename),
+			}
+			changed, err := isFileInDirectory(tt.directory, commitFile)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, changed)
+		})
+	}
+
+	t.Run("invalid glob pattern returns error", func(t *testing.T) {
+		commitFile := &github.CommitFile{
+			Filename:    github.String("api/main.go"),
+			ContentsURL: github.String("https://api.github.com/repos/octocat/Hello-World/contents/api/main.go"),
+		}
+		_, err := isFileInDirectory("{api,web/**", commitFile)
+
+		require.Error(t, err)
 	})
 }
 
@@ -605,13 +636,210 @@ func TestFilterAllowedAndIgnoreReviewers(t *testing.T) {
 	}
 }
 
-// --- helper functions ---
+func TestApprovalsForTeam(t *testing.T) {
+	reviews := []*github.PullRequestReview{
+		{
+			User:        &github.User{Login: github.String("alice")},
+			State:       github.String("APPROVED"),
+			SubmittedAt: submittedAt(0),
+		},
+		{
+			User:        &github.User{Login: github.String("bob")},
+			State:       github.String("APPROVED"),
+			SubmittedAt: submittedAt(1),
+		},
+		{
+			User:        &github.User{Login: github.String("bob")},
+			State:       github.String("CHANGES_REQUESTED"),
+			SubmittedAt: submittedAt(2),
+		},
+		{
+			User:        &github.User{Login: github.String("eve")},
+			State:       github.String("APPROVED"),
+			SubmittedAt: submittedAt(3),
+		},
+		{
+			User:        &github.User{Login: github.String("eve")},
+			State:       github.String("COMMENTED"),
+			SubmittedAt: submittedAt(4),
+		},
+		{
+			User:        &github.User{Login: github.String("charlie")},
+			State:       github.String("APPROVED"),
+			SubmittedAt: submittedAt(5),
+		},
+	}
 
-func getCommitFiles(contentUrls ...string) []*github.CommitFile {
+	var approvers []string
+	for _, r := range approvalsForTeam(reviews, []string{"alice", "bob", "eve"}) {
+		approvers = append(approvers, r.GetUser().GetLogin())
+	}
+	require.Equal(t, []string{"alice", "eve"}, approvers)
+}
 
-	var out []*github.CommitFile
-	for i := range contentUrls {
-		out = append(out, &github.CommitFile{ContentsURL: &contentUrls[i]})
+func TestChangesRequestedByTeam(t *testing.T) {
+	reviews := []*github.PullRequestReview{
+		{
+			User:        &github.User{Login: github.String("alice")},
+			State:       github.String("CHANGES_REQUESTED"),
+			SubmittedAt: submittedAt(0),
+		},
+		{
+			User:        &github.User{Login: github.String("alice")},
+			State:       github.String("APPROVED"),
+			SubmittedAt: submittedAt(1),
+		},
+		{
+			User:        &github.User{Login: github.String("bob")},
+			State:       github.String("CHANGES_REQUESTED"),
+			SubmittedAt: submittedAt(2),
+		},
+		{
+			User:        &github.User{Login: github.String("bob")},
+			State:       github.String("COMMENTED"),
+			SubmittedAt: submittedAt(3),
+		},
+		{
+			User:        &github.User{Login: github.String("charlie")},
+			State:       github.String("CHANGES_REQUESTED"),
+			SubmittedAt: submittedAt(4),
+		},
 	}
-	return out
+
+	require.Equal(t, []string{"bob"}, changesRequestedByTeam(reviews, []string{"alice", "bob", "eve"}))
+}
+
+func TestCodeownersHandles(t *testing.T) {
+	owners := []string{"@form3tech/cab-foo", "@Form3Tech/cab-bar", "@other-org/cab-baz", "@alice", "bob@example.com"}
+	teamHandles, userHandles := codeownersHandles("form3tech", owners)
+	require.Equal(t, []string{"cab-foo", "cab-bar", "other-org/cab-baz"}, teamHandles)
+	require.Equal(t, []string{"alice"}, userHandles)
+}
+
+func TestGetTeamNameFromTeamHandle(t *testing.T) {
+	teams := []*github.Team{{ID: github.Int64(1), Name: github.String("CAB Foo"), Slug: github.String("cab-foo")}}
+
+	tests := []struct {
+		handle   string
+		expected string
+	}{
+		{handle: "cab-foo", expected: "CAB Foo"},
+		{handle: "CAB Foo", expected: "CAB Foo"},
+		{handle: "1", expected: "CAB Foo"},
+		{handle: "some-org/cab-foo", expected: "CAB Foo"},
+		{handle: "Some-Org/cab-foo", expected: "CAB Foo"},
+		{handle: "form3tech/cab-foo", expected: "CAB Foo"},
+		{handle: "other-org/cab-foo"},
+		{handle: "cab-bar"},
+	}
+	for _, tt := range tests {
+		name, err := GetTeamNameFromTeamHandle(teams, "some-org", tt.handle)
+		if tt.expected == "" {
+			require.ErrorIs(t, err, ErrInvalidTeamHandle, tt.handle)
+			continue
+		}
+		require.NoError(t, err, tt.handle)
+		require.Equal(t, tt.expected, name, tt.handle)
+	}
+}
+
+func TestTeamHierarchy(t *testing.T) {
+	platform := &github.Team{ID: github.Int64(1), Name: github.String("Platform")}
+	security := &github.Team{ID: github.Int64(2), Name: github.String("Security"), Parent: platform}
+	network := &github.Team{ID: github.Int64(3), Name: github.String("Network"), Parent: platform}
+	appsec := &github.Team{ID: github.Int64(4), Name: github.String("AppSec"), Parent: security}
+	docs := &github.Team{ID: github.Int64(5), Name: github.String("Docs")}
+	teams := []*github.Team{appsec, docs, network, platform, security}
+
+	t.Run("team with descendants", func(t *testing.T) {
+		require.Equal(t, []*github.Team{platform, network, security, appsec}, teamHierarchy(teams, "Platform"))
+	})
+
+	t.Run("team without children", func(t *testing.T) {
+		require.Equal(t, []*github.Team{docs}, teamHierarchy(teams, "Docs"))
+	})
+
+	t.Run("unknown team", func(t *testing.T) {
+		require.Empty(t, teamHierarchy(teams, "Unknown"))
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		a := &github.Team{ID: github.Int64(1), Name: github.String("A")}
+		b := &github.Team{ID: github.Int64(2), Name: github.String("B"), Parent: a}
+		a.Parent = &github.Team{ID: github.Int64(2)}
+
+		require.Equal(t, []*github.Team{a, b}, teamHierarchy([]*github.Team{a, b}, "A"))
+		require.Equal(t, []*github.Team{b, a}, teamHierarchy([]*github.Team{a, b}, "B"))
+	})
+}
+
+func TestFingerprintCommitFiles(t *testing.T) {
+	files := []*github.CommitFile{
+		{
+			Filename: github.String("main.go"),
+			Status:   github.String("modified"),
+			Patch:    github.String("@@ -1,3 +1,4 @@\n package main\n+\n+// foo"),
+		},
+		{
+			Filename: github.String("README.md"),
+			Status:   github.String("added"),
+			Patch:    github.String("@@ -0,0 +1 @@\n+# foo"),
+		},
+	}
+
+	t.Run("same changes with shifted hunks and different order have the same fingerprint", func(t *testing.T) {
+		rebased := []*github.CommitFile{
+			{
+				Filename: github.String("README.md"),
+				Status:   github.String("added"),
+				Patch:    github.String("@@ -0,0 +1 @@\n+# foo"),
+			},
+			{
+				Filename: github.String("main.go"),
+				Status:   github.String("modified"),
+				Patch:    github.String("@@ -10,3 +10,4 @@ func main() {\n package main\n+\n+// foo"),
+			},
+		}
+
+		assert.Equal(t, fingerprintCommitFiles(files), fingerprintCommitFiles(rebased))
+	})
+
+	t.Run("different changes have different fingerprints", func(t *testing.T) {
+		changed := []*github.CommitFile{
+			files[0],
+			{
+				Filename: github.String("README.md"),
+				Status:   github.String("added"),
+				Patch:    github.String("@@ -0,0 +1 @@\n+# bar"),
+			},
+		}
+
+		assert.NotEqual(t, fingerprintCommitFiles(files), fingerprintCommitFiles(changed))
+	})
+
+	t.Run("changed files without a patch have different fingerprints", func(t *testing.T) {
+		binary := func(sha string) []*github.CommitFile {
+			return []*github.CommitFile{
+				{
+					Filename: github.String("logo.png"),
+					Status:   github.String("modified"),
+					SHA:      github.String(sha),
+				},
+			}
+		}
+
+		assert.Equal(t, fingerprintCommitFiles(binary("a1")), fingerprintCommitFiles(binary("a1")))
+		assert.NotEqual(t, fingerprintCommitFiles(binary("a1")), fingerprintCommitFiles(binary("b2")))
+	})
+}
+
+// --- helper functions ---
+
+func submittedAt(sec int64) *time.Time {
+	t := time.Unix(sec, 0)
+	return &t
+}
+
+func getCommitFile(contentsUrl string) *github.CommitFile {
+	return &github.CommitFile{ContentsURL: &contentsUrl}
 }
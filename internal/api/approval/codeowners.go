@@ -0,0 +1,117 @@
+package approval
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/form3tech-oss/github-team-approver/internal/api/glob"
+)
+
+// codeownersEntry is a single line of a CODEOWNERS file.
+type codeownersEntry struct {
+	pattern string
+	regex   *regexp.Regexp
+	owners  []string
+}
+
+// codeowners holds the entries of a CODEOWNERS file in the order they were declared.
+type codeowners []codeownersEntry
+
+// parseCodeowners parses the contents of a CODEOWNERS file, ignoring comments and blank lines.
+// https://docs.github.com/en/repositories/managing-your-repositorys-settings-and-features/customizing-your-repository/about-code-owners
+func parseCodeowners(content string) (codeowners, error) {
+	var entries codeowners
+	for i, line := range strings.Split(content, "\n") {
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		regex, err := codeownersPatternToRegexp(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("CODEOWNERS line %d: %w", i+1, err)
+		}
+		entries = append(entries, codeownersEntry{
+			pattern: fields[0],
+			regex:   regex,
+			owners:  fields[1:],
+		})
+	}
+	return entries, nil
+}
+
+// ownersOf returns the owners of the given path. As in GitHub, the last matching entry takes precedence.
+func (c codeowners) ownersOf(path string) []string {
+	path = strings.TrimPrefix(path, "/")
+	for i := len(c) - 1; i >= 0; i-- {
+		if c[i].regex.MatchString(path) {
+			return c[i].owners
+		}
+	}
+	return nil
+}
+
+// ownerGroups groups the given paths by their owners, returning each distinct (sorted) set of owners once.
+// Paths without owners are left out.
+func (c codeowners) ownerGroups(paths []string) [][]string {
+	var groups [][]string
+	seen := map[string]bool{}
+	for _, path := range paths {
+		owners := append([]string{}, c.ownersOf(path)...)
+		if len(owners) == 0 {
+			continue
+		}
+		sort.Strings(owners)
+		key := strings.Join(owners, " ")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		groups = append(groups, owners)
+	}
+	return groups
+}
+
+// codeownersPatternToRegexp translates a CODEOWNERS pattern, which follows the rules used by gitignore files, into a
+// regular expression matching repository-relative paths.
+func codeownersPatternToRegexp(pattern string) (*regexp.Regexp, error) {
+	p := pattern
+	dirOnly := strings.HasSuffix(p, "/")
+	p = strings.TrimSuffix(p, "/")
+	// Patterns containing a slash other than a trailing one are relative to the root of the repository.
+	anchored := strings.Contains(p, "/")
+	p = strings.TrimPrefix(p, "/")
+	if p == "" {
+		return nil, fmt.Errorf("invalid pattern %q", pattern)
+	}
+
+	expr, err := glob.ToRegexp(p)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(.*/)?")
+	}
+	b.WriteString(expr)
+	switch {
+	case dirOnly:
+		// Trailing slashes only match the contents of directories.
+		b.WriteString("/.*$")
+	case glob.IsPattern(p[strings.LastIndex(p, "/")+1:]):
+		// A wildcard in the last segment only matches the entries of a directory, not what lies further underneath
+		// (e.g. "docs/*" doesn't match "docs/build-app/troubleshooting.md").
+		b.WriteString("$")
+	default:
+		// Patterns naming a directory also match everything underneath it.
+		b.WriteString("(/.*)?$")
+	}
+	return regexp.Compile(b.String())
+}
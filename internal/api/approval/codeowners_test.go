@@ -0,0 +1,60 @@
+package approval
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodeowners_OwnersOf(t *testing.T) {
+	content := `# Default owners
+*                   @form3tech/cab-foo
+
+/docs/              @form3tech/docs # documentation
+docs/*              @carol
+*.go                @form3tech/go
+/build/**/Dockerfile @alice
+config              @bob
+/scripts/*.sh       @eve
+/vendor/
+`
+	owners, err := parseCodeowners(content)
+	require.NoError(t, err)
+
+	tests := map[string][]string{
+		"README.md":                         {"@form3tech/cab-foo"},
+		"docs/index.md":                     {"@carol"},
+		"docs/api/index.md":                 {"@form3tech/docs"},
+		"docs/build-app/troubleshooting.md": {"@form3tech/docs"},
+		"docs.md":                           {"@form3tech/cab-foo"},
+		"main.go":                           {"@form3tech/go"},
+		"docs/example.go":                   {"@form3tech/go"},
+		"build/Dockerfile":                  {"@alice"},
+		"build/images/base/Dockerfile":      {"@alice"},
+		"config/dev.yaml":                   {"@bob"},
+		"deploy/config/dev.yaml":            {"@bob"},
+		"/config/dev.yaml":                  {"@bob"},
+		"scripts/build.sh":                  {"@eve"},
+		"scripts/ci/build.sh":               {"@form3tech/cab-foo"},
+		"vendor/github.com/foo/bar.go":      {},
+	}
+
+	for path, want := range tests {
+		t.Run(path, func(t *testing.T) {
+			require.Equal(t, want, owners.ownersOf(path))
+		})
+	}
+}
+
+func TestCodeowners_OwnerGroups(t *testing.T) {
+	owners, err := parseCodeowners("*.go @form3tech/go @alice\n/docs/ @form3tech/docs\n/vendor/\n")
+	require.NoError(t, err)
+
+	groups := owners.ownerGroups([]string{"main.go", "docs/index.md", "cmd/main.go", "vendor/foo.go", "README.md"})
+	require.Equal(t, [][]string{{"@alice", "@form3tech/go"}, {"@form3tech/docs"}}, groups)
+}
+
+func TestParseCodeownersInvalidPattern(t *testing.T) {
+	_, err := parseCodeowners("* @form3tech/cab-foo\n/ @alice\n")
+	require.EqualError(t, err, `CODEOWNERS line 2: invalid pattern "/"`)
+}
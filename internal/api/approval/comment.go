@@ -0,0 +1,69 @@
+package approval
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Comment renders the approval matrix of the PR as markdown, to be kept in a single comment on the PR. It is empty when
+// no rule was evaluated (e.g. there are no rules for the target branch, or the configuration is invalid).
+func (r *Result) Comment() string {
+	t := r.trace
+	if len(t.Rules) == 0 && t.ConfigurationGuard == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "### Approval status: %s\n\n", r.status)
+
+	var rows strings.Builder
+	for _, rm := range t.Rules {
+		if rm.Matched {
+			writeMatrixRows(&rows, fmt.Sprintf("%d", rm.Rule+1), rm)
+		}
+	}
+	if t.ConfigurationGuard != nil {
+		writeMatrixRows(&rows, "configuration file", *t.ConfigurationGuard)
+	}
+	if rows.Len() == 0 {
+		fmt.Fprintf(&b, "No rule for `%s` applies to this PR.\n", t.TargetBranch)
+	} else {
+		b.WriteString("| Rule | Team | Approvals | Pending |\n")
+		b.WriteString("|------|------|-----------|---------|\n")
+		b.WriteString(rows.String())
+	}
+
+	if len(t.IgnoredReviewers) > 0 || len(t.InvalidReviewers) > 0 || len(t.StaleReviewers) > 0 {
+		b.WriteString("\n#### Reviews not counted\n\n")
+		writeReviewers(&b, "Ignored as they contributed to or reopened the PR", t.IgnoredReviewers)
+		writeReviewers(&b, "Not member of a team with approval capabilities", t.InvalidReviewers)
+		writeReviewers(&b, "Approved a previous version of the PR", t.StaleReviewers)
+	}
+	return b.String()
+}
+
+// NeedsComment reports whether a comment should be created on the PR if there is none yet, which is the case when the
+// PR is pending while some of its reviews are not counted. An existing comment is kept up to date regardless.
+func (r *Result) NeedsComment() bool {
+	return r.pendingReviewsWaiting() &&
+		(len(r.ignoredReviewers) > 0 || len(r.invalidReviewers) > 0 || len(r.staleReviewers) > 0)
+}
+
+// writeMatrixRows writes one row per approving team of a rule applying to the PR, plus one for the minimum number of
+// distinct approvals if the rule sets it. Nothing is pending for a fulfilled rule, even if some of its teams didn't approve.
+func writeMatrixRows(b *strings.Builder, name string, rm RuleMatch) {
+	pending := func(approvals, required int) int {
+		if rm.Fulfilled || approvals >= required {
+			return 0
+		}
+		return required - approvals
+	}
+	for _, team := range rm.Teams {
+		fmt.Fprintf(b, "| %s | `%s` | %d/%d | %d |\n",
+			name, team.Handle, team.Approvals, team.Required, pending(team.Approvals, team.Required))
+	}
+	if rm.MinimumApprovals > 0 {
+		fmt.Fprintf(b, "| %s | distinct approvals | %d/%d | %d |\n",
+			name, len(rm.Approvers), rm.MinimumApprovals, pending(len(rm.Approvers), rm.MinimumApprovals))
+	}
+}
@@ -0,0 +1,93 @@
+package approval
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResult_Comment(t *testing.T) {
+	tests := map[string]struct {
+		result       Result
+		expected     string
+		needsComment bool
+	}{
+		"pending with ignored and invalid reviewers": {
+			result: Result{
+				status:           StatusEventStatusPending,
+				ignoredReviewers: []string{"alice"},
+				invalidReviewers: []string{"charlie"},
+				trace: Trace{
+					TargetBranch: "master",
+					Rules: []RuleMatch{
+						{Rule: 0, Matched: false},
+						{
+							Rule:             1,
+							Matched:          true,
+							Teams:            []TeamApproval{{Handle: "cab-foo", Approvals: 1, Required: 1}, {Handle: "cab-bar", Approvals: 0, Required: 2}},
+							Approvers:        []string{"bob"},
+							MinimumApprovals: 2,
+						},
+					},
+					IgnoredReviewers: []string{"alice"},
+					InvalidReviewers: []string{"charlie"},
+				},
+			},
+			expected: "### Approval status: pending\n\n" +
+				"| Rule | Team | Approvals | Pending |\n" +
+				"|------|------|-----------|---------|\n" +
+				"| 2 | `cab-foo` | 1/1 | 0 |\n" +
+				"| 2 | `cab-bar` | 0/2 | 2 |\n" +
+				"| 2 | distinct approvals | 1/2 | 1 |\n" +
+				"\n#### Reviews not counted\n\n" +
+				"- Ignored as they contributed to or reopened the PR: @alice\n" +
+				"- Not member of a team with approval capabilities: @charlie\n",
+			needsComment: true,
+		},
+		"success through the configuration guard": {
+			result: Result{
+				status: StatusEventStatusSuccess,
+				trace: Trace{
+					TargetBranch: "master",
+					Rules:        []RuleMatch{{Rule: 0, Matched: false}},
+					ConfigurationGuard: &RuleMatch{
+						Rule:      -1,
+						Matched:   true,
+						Teams:     []TeamApproval{{Handle: "admins", Approvals: 1, Required: 1}},
+						Fulfilled: true,
+					},
+				},
+			},
+			expected: "### Approval status: success\n\n" +
+				"| Rule | Team | Approvals | Pending |\n" +
+				"|------|------|-----------|---------|\n" +
+				"| configuration file | `admins` | 1/1 | 0 |\n",
+		},
+		"no rule applies": {
+			result: Result{
+				status:         StatusEventStatusPending,
+				staleReviewers: []string{"alice"},
+				trace:          Trace{TargetBranch: "master", Rules: []RuleMatch{{Rule: 0, Matched: false}}, StaleReviewers: []string{"alice"}},
+			},
+			expected: "### Approval status: pending\n\n" +
+				"No rule for `master` applies to this PR.\n" +
+				"\n#### Reviews not counted\n\n" +
+				"- Approved a previous version of the PR: @alice\n",
+			needsComment: true,
+		},
+		"no rules evaluated": {
+			result: Result{
+				status: StatusEventStatusSuccess,
+				trace:  Trace{TargetBranch: "master"},
+			},
+			expected: "",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tt.expected, tt.result.Comment())
+			require.Equal(t, tt.needsComment, tt.result.NeedsComment())
+		})
+	}
+}
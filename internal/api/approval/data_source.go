@@ -0,0 +1,27 @@
+package approval
+
+import (
+	"context"
+
+	"github.com/google/go-github/v42/github"
+
+	"github.com/form3tech-oss/github-team-approver/internal/api/configuration"
+	ghclient "github.com/form3tech-oss/github-team-approver/internal/api/github"
+)
+
+// DataSource provides the data the approval status of a PR is computed from. It is implemented by the GitHub client,
+// and by sources reading the data from files to evaluate PRs offline.
+type DataSource interface {
+	GetResolvedConfiguration(ctx context.Context, ownerLogin, repoName, baseBranch string, source configuration.Source) (*configuration.Configuration, error)
+	GetCodeowners(ctx context.Context, ownerLogin, repoName, ref string) (string, error)
+	GetTeams(ctx context.Context, organisation string) ([]*github.Team, error)
+	GetTeamMembers(ctx context.Context, teams []*github.Team, organisation, name string) ([]*github.User, error)
+	GetPullRequestReviews(ctx context.Context, ownerLogin, repoName string, prNumber int) ([]*github.PullRequestReview, error)
+	GetPullRequestCommitFiles(ctx context.Context, ownerLogin, repoName string, prNumber int) ([]*github.CommitFile, error)
+	GetPRCommits(ctx context.Context, owner, repo string, prNumber int) ([]*github.RepositoryCommit, error)
+	GetIssuesEvents(ctx context.Context, owner, repo string, number int) ([]*github.IssueEvent, error)
+	GetLabels(ctx context.Context, ownerLogin, repoName string, prNumber int) ([]string, error)
+	CompareCommits(ctx context.Context, ownerLogin, repoName, base, head string) ([]*github.CommitFile, error)
+}
+
+var _ DataSource = (*ghclient.Client)(nil)
@@ -0,0 +1,23 @@
+package approval
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/form3tech-oss/github-team-approver/internal/api/glob"
+)
+
+// matchGlob returns true if the given repository-relative path, or any of its parent directories, matches the glob
+// pattern. Patterns are always relative to the root of the repository.
+func matchGlob(pattern, path string) (bool, error) {
+	expr, err := glob.ToRegexp(strings.Trim(pattern, "/"))
+	if err != nil {
+		return false, err
+	}
+	regex, err := regexp.Compile("^" + expr + "(/.*)?$")
+	if err != nil {
+		return false, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return regex.MatchString(strings.TrimPrefix(path, "/")), nil
+}
@@ -0,0 +1,48 @@
+package approval
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	commons "github.com/form3tech-oss/github-team-approver-commons/v2/pkg/configuration"
+
+	"github.com/form3tech-oss/github-team-approver/internal/api/configuration"
+)
+
+// changesConfigurationFile returns true if a configuration admin team is set and the PR changes the configuration
+// file, including by renaming or moving it away, in which case the PR must be approved by that team whatever the rules
+// say.
+func (a *Approval) changesConfigurationFile(ctx context.Context, pr *PR) (bool, error) {
+	if a.settings.ConfigurationAdminTeamHandle == "" {
+		return false, nil
+	}
+
+	commitFiles, err := a.client.GetPullRequestCommitFiles(ctx, pr.OwnerLogin, pr.RepoName, pr.Number)
+	if err != nil {
+		return false, fmt.Errorf("configuration guard: get pull request commit files: %w", err)
+	}
+	for _, f := range commitFiles {
+		if isConfigurationFile(f.GetFilename()) || isConfigurationFile(f.GetPreviousFilename()) {
+			a.log.Tracef("The PR changes %q and needs approval from %q", configuration.ConfigurationFilePath, a.settings.ConfigurationAdminTeamHandle)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func isConfigurationFile(filename string) bool {
+	return strings.TrimPrefix(filename, "/") == configuration.ConfigurationFilePath
+}
+
+// configurationGuardRule returns the built-in rule requiring an approval from the configuration admin team. Approvals
+// of members who contributed to the PR don't count, so that nobody can approve their own changes to the policy.
+func (a *Approval) configurationGuardRule() configuration.Rule {
+	return configuration.Rule{
+		Rule: commons.Rule{
+			ApprovalMode:              configuration.ApprovalModeRequireAny,
+			ApprovingTeamHandles:      []string{a.settings.ConfigurationAdminTeamHandle},
+			IgnoreContributorApproval: true,
+		},
+	}
+}
@@ -1,29 +1,61 @@
 package approval
 
 import (
+	"fmt"
 	"sort"
 
-	"github.com/form3tech-oss/github-team-approver-commons/v2/pkg/configuration"
+	"github.com/form3tech-oss/github-team-approver/internal/api/configuration"
 )
 
 type MatchedRule struct {
+	// Rule is the index of the rule among the ones for the target branch, as in RuleMatch.
+	Rule       int
 	ConfigRule configuration.Rule
 	Approvals  TeamApprovals
+	// Approvers keeps track of the distinct users whose approvals were counted towards the rule.
+	Approvers map[string]bool
+	// ChangesRequestedBy keeps track of the team members with an outstanding request for changes.
+	ChangesRequestedBy map[string]bool
 }
 
 func NewMatchedRule(rule configuration.Rule) MatchedRule {
 	return MatchedRule{
-		ConfigRule: rule,
-		Approvals:  make(TeamApprovals),
+		ConfigRule:         rule,
+		Approvals:          make(TeamApprovals),
+		Approvers:          make(map[string]bool),
+		ChangesRequestedBy: make(map[string]bool),
 	}
 }
 
-func (mr MatchedRule) RecordApproval(teamHandle string, count int) {
-	if count >= 1 {
-		mr.Approvals[teamHandle] = count
+func (mr MatchedRule) RecordApproval(teamHandle string, approvers []string) {
+	if len(approvers) >= 1 {
+		mr.Approvals[teamHandle] = len(approvers)
+	}
+	for _, approver := range approvers {
+		mr.Approvers[approver] = true
+	}
+}
+
+func (mr MatchedRule) RecordChangesRequested(reviewers []string) {
+	for _, reviewer := range reviewers {
+		mr.ChangesRequestedBy[reviewer] = true
 	}
 }
 
+// BlockingReviewers returns the reviewers whose request for changes prevents the rule from being fulfilled.
+func (mr MatchedRule) BlockingReviewers() []string {
+	blocking := []string{}
+	if !mr.ConfigRule.BlockOnChangesRequested {
+		return blocking
+	}
+	for reviewer := range mr.ChangesRequestedBy {
+		blocking = append(blocking, reviewer)
+	}
+
+	sort.Strings(blocking)
+	return blocking
+}
+
 func (mr MatchedRule) ApprovingTeamNames() []string {
 	approving := []string{}
 	for name, approvals := range mr.Approvals {
@@ -38,9 +70,9 @@ func (mr MatchedRule) ApprovingTeamNames() []string {
 
 func (mr MatchedRule) PendingTeamNames() []string {
 	pending := []string{}
-	for _, name := range mr.ConfigRule.ApprovingTeamHandles {
-		approvals, ok := mr.Approvals[name]
-		if !ok || approvals == 0 {
+	for _, name := range mr.ConfigRule.ApprovingHandles() {
+		// When the rule still lacks approvals in total, every team is in a position to provide them.
+		if mr.MissingApprovalsForTeam(name) > 0 || mr.MissingApprovals() > 0 {
 			pending = append(pending, name)
 		}
 	}
@@ -49,41 +81,78 @@ func (mr MatchedRule) PendingTeamNames() []string {
 	return pending
 }
 
+// PendingDescriptions returns one line per pending team, stating how many approvals are still missing from teams
+// required to approve more than once, followed by the number of approvals still missing in total (if any). The latter
+// names the rule, so that it isn't mistaken for (or merged with) the one of another rule.
+func (mr MatchedRule) PendingDescriptions() []string {
+	descriptions := []string{}
+	for _, name := range mr.PendingTeamNames() {
+		required := mr.ConfigRule.RequiredApprovalsForTeam(name)
+		missing := mr.MissingApprovalsForTeam(name)
+		if required > 1 && missing > 0 {
+			descriptions = append(descriptions, fmt.Sprintf("%s (missing %d of %d)", name, missing, required))
+		} else {
+			descriptions = append(descriptions, name)
+		}
+	}
+
+	if missing := mr.MissingApprovals(); missing > 0 {
+		descriptions = append(descriptions, fmt.Sprintf("Rule %d (missing %d of %d in total)", mr.Rule+1, missing, mr.ConfigRule.MinimumApprovals))
+	}
+	return descriptions
+}
+
+// MissingApprovalsForTeam returns how many more approvals are required from the team identified by the given handle.
+func (mr MatchedRule) MissingApprovalsForTeam(teamHandle string) int {
+	missing := mr.ConfigRule.RequiredApprovalsForTeam(teamHandle) - mr.Approvals[teamHandle]
+	if missing < 0 {
+		return 0
+	}
+	return missing
+}
+
+// MissingApprovals returns how many more distinct approvals are required across all approving teams.
+func (mr MatchedRule) MissingApprovals() int {
+	missing := mr.ConfigRule.MinimumApprovals - len(mr.Approvers)
+	if missing < 0 {
+		return 0
+	}
+	return missing
+}
+
 func (mr MatchedRule) Fulfilled() bool {
 	r := mr.ConfigRule
 
 	switch {
 	case r.ForceApproval:
 		return true
+	case len(mr.BlockingReviewers()) > 0:
+		return false
 	case r.ApprovalMode == configuration.ApprovalModeRequireAny:
-		return mr.Approvals.AnyTeamApproved()
+		return mr.anyTeamFulfilled() && mr.MissingApprovals() == 0
 	case r.ApprovalMode == configuration.ApprovalModeRequireAll:
-		return mr.Approvals.AllTeamsApproved(r.ApprovingTeamHandles)
+		return mr.allTeamsFulfilled() && mr.MissingApprovals() == 0
 	}
 
 	return false
 }
 
-type TeamApprovals map[string]int
-
-func (ta TeamApprovals) AnyTeamApproved() bool {
-	total := 0
-	for _, count := range ta {
-		total += count
+func (mr MatchedRule) anyTeamFulfilled() bool {
+	for _, handle := range mr.ConfigRule.ApprovingHandles() {
+		if mr.MissingApprovalsForTeam(handle) == 0 {
+			return true
+		}
 	}
-	return total >= 1
+	return false
 }
 
-func (ta TeamApprovals) AllTeamsApproved(allTeams []string) bool {
-	for _, handle := range allTeams {
-		approvals, present := ta[handle]
-		if !present {
-			return false
-		}
-
-		if approvals < 1 {
+func (mr MatchedRule) allTeamsFulfilled() bool {
+	for _, handle := range mr.ConfigRule.ApprovingHandles() {
+		if mr.MissingApprovalsForTeam(handle) > 0 {
 			return false
 		}
 	}
 	return true
 }
+
+type TeamApprovals map[string]int
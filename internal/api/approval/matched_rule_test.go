@@ -3,75 +3,11 @@ package approval
 import (
 	"testing"
 
-	"github.com/form3tech-oss/github-team-approver-commons/v2/pkg/configuration"
+	commons "github.com/form3tech-oss/github-team-approver-commons/v2/pkg/configuration"
+	"github.com/form3tech-oss/github-team-approver/internal/api/configuration"
 	"github.com/stretchr/testify/require"
 )
 
-func TestTeamApprovals_AnyTeamApproved(t *testing.T) {
-	tests := map[string]struct {
-		teamApprovals TeamApprovals
-		result        bool
-	}{
-		"when no teams approved": {
-			teamApprovals: TeamApprovals{"A-Team": 0, "B-Team": 0},
-			result:        false,
-		},
-		"when one team approved": {
-			teamApprovals: TeamApprovals{"A-Team": 0, "B-Team": 1},
-			result:        true,
-		},
-		"when both teams approved": {
-			teamApprovals: TeamApprovals{"A-Team": 2, "B-Team": 1},
-			result:        true,
-		},
-		"when empty": {
-			teamApprovals: TeamApprovals{},
-			result:        false,
-		},
-	}
-
-	for name, tt := range tests {
-		t.Run(name, func(t *testing.T) {
-			require.Equal(t, tt.result, tt.teamApprovals.AnyTeamApproved())
-		})
-	}
-}
-
-func TestTeamApprovals_AllTeamsApproved(t *testing.T) {
-	tests := map[string]struct {
-		teamApprovals TeamApprovals
-		result        bool
-	}{
-		"when no teams approved": {
-			teamApprovals: TeamApprovals{"A-Team": 0, "B-Team": 0},
-			result:        false,
-		},
-		"when one team approved, second missing": {
-			teamApprovals: TeamApprovals{"A-Team": 1},
-			result:        false,
-		},
-		"when one team approved": {
-			teamApprovals: TeamApprovals{"A-Team": 0, "B-Team": 1},
-			result:        false,
-		},
-		"when both teams approved": {
-			teamApprovals: TeamApprovals{"A-Team": 2, "B-Team": 1},
-			result:        true,
-		},
-		"when empty": {
-			teamApprovals: TeamApprovals{},
-			result:        false,
-		},
-	}
-
-	for name, tt := range tests {
-		t.Run(name, func(t *testing.T) {
-			handles := []string{"A-Team", "B-Team"}
-			require.Equal(t, tt.result, tt.teamApprovals.AllTeamsApproved(handles))
-		})
-	}
-}
-
 func TestMatchedRule_ApprovingTeamNames(t *testing.T) {
 	tests := map[string]struct {
 		matchedRule MatchedRule
@@ -118,7 +54,9 @@ func TestMatchedRule_PendingTeamNames(t *testing.T) {
 		"pending teams": {
 			matchedRule: MatchedRule{
 				ConfigRule: configuration.Rule{
-					ApprovingTeamHandles: []string{"A-Team", "B-Team", "C-Team"},
+					Rule: commons.Rule{
+						ApprovingTeamHandles: []string{"A-Team", "B-Team", "C-Team"},
+					},
 				},
 				Approvals: TeamApprovals{"B-Team": 1},
 			},
@@ -127,7 +65,9 @@ func TestMatchedRule_PendingTeamNames(t *testing.T) {
 		"no pending teams": {
 			matchedRule: MatchedRule{
 				ConfigRule: configuration.Rule{
-					ApprovingTeamHandles: []string{"A-Team", "B-Team", "C-Team"},
+					Rule: commons.Rule{
+						ApprovingTeamHandles: []string{"A-Team", "B-Team", "C-Team"},
+					},
 				},
 				Approvals: TeamApprovals{"A-Team": 1, "B-Team": 2, "C-Team": 3},
 			},
@@ -136,7 +76,9 @@ func TestMatchedRule_PendingTeamNames(t *testing.T) {
 		"zero approvals counted for team": {
 			matchedRule: MatchedRule{
 				ConfigRule: configuration.Rule{
-					ApprovingTeamHandles: []string{"A-Team", "B-Team", "C-Team"},
+					Rule: commons.Rule{
+						ApprovingTeamHandles: []string{"A-Team", "B-Team", "C-Team"},
+					},
 				},
 				Approvals: TeamApprovals{"A-Team": 0, "B-Team": 1, "C-Team": 2},
 			},
@@ -145,7 +87,9 @@ func TestMatchedRule_PendingTeamNames(t *testing.T) {
 		"empty": {
 			matchedRule: MatchedRule{
 				ConfigRule: configuration.Rule{
-					ApprovingTeamHandles: []string{"A-Team", "B-Team", "C-Team"},
+					Rule: commons.Rule{
+						ApprovingTeamHandles: []string{"A-Team", "B-Team", "C-Team"},
+					},
 				},
 				Approvals: TeamApprovals{},
 			},
@@ -159,3 +103,237 @@ func TestMatchedRule_PendingTeamNames(t *testing.T) {
 		})
 	}
 }
+
+func TestMatchedRule_Fulfilled(t *testing.T) {
+	tests := map[string]struct {
+		rule      configuration.Rule
+		approvals TeamApprovals
+		approvers []string
+		result    bool
+	}{
+		"require any, one approval": {
+			rule: configuration.Rule{
+				Rule: commons.Rule{
+					ApprovalMode:         configuration.ApprovalModeRequireAny,
+					ApprovingTeamHandles: []string{"A-Team", "B-Team"},
+				},
+			},
+			approvals: TeamApprovals{"B-Team": 1},
+			approvers: []string{"alice"},
+			result:    true,
+		},
+		"require any, team minimum not reached": {
+			rule: configuration.Rule{
+				Rule: commons.Rule{
+					ApprovalMode:         configuration.ApprovalModeRequireAny,
+					ApprovingTeamHandles: []string{"A-Team", "B-Team"},
+				},
+				TeamMinimumApprovals: map[string]int{"A-Team": 2, "B-Team": 2},
+			},
+			approvals: TeamApprovals{"A-Team": 1, "B-Team": 1},
+			approvers: []string{"alice", "bob"},
+			result:    false,
+		},
+		"require any, team minimum reached": {
+			rule: configuration.Rule{
+				Rule: commons.Rule{
+					ApprovalMode:         configuration.ApprovalModeRequireAny,
+					ApprovingTeamHandles: []string{"A-Team", "B-Team"},
+				},
+				TeamMinimumApprovals: map[string]int{"A-Team": 2},
+			},
+			approvals: TeamApprovals{"A-Team": 2},
+			approvers: []string{"alice", "bob"},
+			result:    true,
+		},
+		"require any, total minimum not reached": {
+			rule: configuration.Rule{
+				Rule: commons.Rule{
+					ApprovalMode:         configuration.ApprovalModeRequireAny,
+					ApprovingTeamHandles: []string{"A-Team", "B-Team"},
+				},
+				MinimumApprovals: 3,
+			},
+			approvals: TeamApprovals{"A-Team": 1, "B-Team": 1},
+			approvers: []string{"alice", "bob"},
+			result:    false,
+		},
+		"require any, total minimum not reached by the same approver in two teams": {
+			rule: configuration.Rule{
+				Rule: commons.Rule{
+					ApprovalMode:         configuration.ApprovalModeRequireAny,
+					ApprovingTeamHandles: []string{"A-Team", "B-Team"},
+				},
+				MinimumApprovals: 2,
+			},
+			approvals: TeamApprovals{"A-Team": 1, "B-Team": 1},
+			approvers: []string{"alice"},
+			result:    false,
+		},
+		"require any, total minimum reached": {
+			rule: configuration.Rule{
+				Rule: commons.Rule{
+					ApprovalMode:         configuration.ApprovalModeRequireAny,
+					ApprovingTeamHandles: []string{"A-Team", "B-Team"},
+				},
+				MinimumApprovals: 3,
+			},
+			approvals: TeamApprovals{"A-Team": 1, "B-Team": 2},
+			approvers: []string{"alice", "bob", "eve"},
+			result:    true,
+		},
+		"require all, team minimum not reached": {
+			rule: configuration.Rule{
+				Rule: commons.Rule{
+					ApprovalMode:         configuration.ApprovalModeRequireAll,
+					ApprovingTeamHandles: []string{"A-Team", "B-Team"},
+				},
+				TeamMinimumApprovals: map[string]int{"A-Team": 2},
+			},
+			approvals: TeamApprovals{"A-Team": 1, "B-Team": 1},
+			approvers: []string{"alice", "bob"},
+			result:    false,
+		},
+		"require all, team minimum reached": {
+			rule: configuration.Rule{
+				Rule: commons.Rule{
+					ApprovalMode:         configuration.ApprovalModeRequireAll,
+					ApprovingTeamHandles: []string{"A-Team", "B-Team"},
+				},
+				TeamMinimumApprovals: map[string]int{"A-Team": 2},
+			},
+			approvals: TeamApprovals{"A-Team": 2, "B-Team": 1},
+			approvers: []string{"alice", "bob", "eve"},
+			result:    true,
+		},
+		"require all, total minimum not reached": {
+			rule: configuration.Rule{
+				Rule: commons.Rule{
+					ApprovalMode:         configuration.ApprovalModeRequireAll,
+					ApprovingTeamHandles: []string{"A-Team", "B-Team"},
+				},
+				MinimumApprovals: 3,
+			},
+			approvals: TeamApprovals{"A-Team": 1, "B-Team": 1},
+			approvers: []string{"alice", "bob"},
+			result:    false,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			mr := NewMatchedRule(tt.rule)
+			for handle, count := range tt.approvals {
+				mr.Approvals[handle] = count
+			}
+			for _, approver := range tt.approvers {
+				mr.Approvers[approver] = true
+			}
+			require.Equal(t, tt.result, mr.Fulfilled())
+		})
+	}
+}
+
+func TestMatchedRule_PendingDescriptions(t *testing.T) {
+	tests := map[string]struct {
+		rule      configuration.Rule
+		approvals TeamApprovals
+		approvers []string
+		result    []string
+	}{
+		"without minimums": {
+			rule: configuration.Rule{
+				Rule: commons.Rule{
+					ApprovalMode:         configuration.ApprovalModeRequireAll,
+					ApprovingTeamHandles: []string{"A-Team", "B-Team"},
+				},
+			},
+			approvals: TeamApprovals{"B-Team": 1},
+			approvers: []string{"alice"},
+			result:    []string{"A-Team"},
+		},
+		"with team minimum": {
+			rule: configuration.Rule{
+				Rule: commons.Rule{
+					ApprovalMode:         configuration.ApprovalModeRequireAll,
+					ApprovingTeamHandles: []string{"A-Team", "B-Team"},
+				},
+				TeamMinimumApprovals: map[string]int{"A-Team": 3},
+			},
+			approvals: TeamApprovals{"A-Team": 1, "B-Team": 1},
+			approvers: []string{"alice", "bob"},
+			result:    []string{"A-Team (missing 2 of 3)"},
+		},
+		"with total minimum": {
+			rule: configuration.Rule{
+				Rule: commons.Rule{
+					ApprovalMode:         configuration.ApprovalModeRequireAny,
+					ApprovingTeamHandles: []string{"A-Team", "B-Team"},
+				},
+				MinimumApprovals: 3,
+			},
+			approvals: TeamApprovals{"B-Team": 1},
+			approvers: []string{"alice"},
+			result:    []string{"A-Team", "B-Team", "Rule 1 (missing 2 of 3 in total)"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			mr := NewMatchedRule(tt.rule)
+			for handle, count := range tt.approvals {
+				mr.Approvals[handle] = count
+			}
+			for _, approver := range tt.approvers {
+				mr.Approvers[approver] = true
+			}
+			require.Equal(t, tt.result, mr.PendingDescriptions())
+		})
+	}
+}
+
+func TestMatchedRule_BlockingReviewers(t *testing.T) {
+	tests := map[string]struct {
+		block     bool
+		force     bool
+		result    []string
+		fulfilled bool
+	}{
+		"changes requested without blocking": {
+			block:     false,
+			result:    []string{},
+			fulfilled: true,
+		},
+		"changes requested with blocking": {
+			block:     true,
+			result:    []string{"bob", "eve"},
+			fulfilled: false,
+		},
+		"changes requested with blocking and force approval": {
+			block:     true,
+			force:     true,
+			result:    []string{"bob", "eve"},
+			fulfilled: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			rule := configuration.Rule{
+				Rule: commons.Rule{
+					ApprovalMode:         configuration.ApprovalModeRequireAny,
+					ApprovingTeamHandles: []string{"A-Team", "B-Team"},
+					ForceApproval:        tt.force,
+				},
+				BlockOnChangesRequested: tt.block,
+			}
+
+			mr := NewMatchedRule(rule)
+			mr.RecordApproval("A-Team", []string{"alice"})
+			mr.RecordChangesRequested([]string{"eve", "bob"})
+
+			require.Equal(t, tt.result, mr.BlockingReviewers())
+			require.Equal(t, tt.fulfilled, mr.Fulfilled())
+		})
+	}
+}
@@ -0,0 +1,395 @@
+package approval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/form3tech-oss/github-team-approver/internal/api/configuration"
+)
+
+// Matcher decides whether a PR meets a condition.
+type Matcher interface {
+	// Match returns whether the PR meets the condition, along with a human-readable reason.
+	Match(ctx context.Context, pr *PR) (bool, string, error)
+}
+
+// MatcherFactory builds the matcher for the fields or keys of a condition it is responsible for, or returns nil if none
+// of them are set.
+type MatcherFactory func(a *Approval, c configuration.Condition) Matcher
+
+type registeredMatcher struct {
+	name    string
+	factory MatcherFactory
+}
+
+var (
+	matcherRegistryMu sync.RWMutex
+	matcherRegistry   []registeredMatcher
+)
+
+// RegisterMatcher adds a kind of condition to the ones rules can set. Matchers are evaluated in the order they were
+// registered, so cheaper ones should be registered first.
+func RegisterMatcher(name string, factory MatcherFactory) {
+	matcherRegistryMu.Lock()
+	defer matcherRegistryMu.Unlock()
+
+	for _, m := range matcherRegistry {
+		if m.name == name {
+			panic(fmt.Sprintf("matcher %q is already registered", name))
+		}
+	}
+	matcherRegistry = append(matcherRegistry, registeredMatcher{name: name, factory: factory})
+}
+
+// RegisterCondition adds a kind of condition set under its own key, which the factory reads with
+// configuration.Condition.Decode. validate checks the value set for the key when the configuration is read.
+func RegisterCondition(key string, validate func(c configuration.Condition) error, factory MatcherFactory) {
+	configuration.RegisterConditionKind(configuration.ConditionKind{Key: key, Validate: validate})
+	RegisterMatcher(key, factory)
+}
+
+func registeredMatchers() []registeredMatcher {
+	matcherRegistryMu.RLock()
+	defer matcherRegistryMu.RUnlock()
+	return matcherRegistry
+}
+
+func init() {
+	RegisterMatcher("regex", func(a *Approval, c configuration.Condition) Matcher {
+		if c.Regex == "" {
+			return nil
+		}
+		return regexMatcher{a: a, regex: c.Regex}
+	})
+	RegisterMatcher("title_regex", func(a *Approval, c configuration.Condition) Matcher {
+		if c.TitleRegex == "" {
+			return nil
+		}
+		return fieldRegexMatcher{a: a, field: "title", regex: c.TitleRegex, value: func(pr *PR) string { return pr.Title }}
+	})
+	RegisterMatcher("head_branch_regex", func(a *Approval, c configuration.Condition) Matcher {
+		if c.HeadBranchRegex == "" {
+			return nil
+		}
+		return fieldRegexMatcher{a: a, field: "head branch", regex: c.HeadBranchRegex, value: func(pr *PR) string { return pr.HeadBranch }}
+	})
+	RegisterMatcher("regex_label", func(a *Approval, c configuration.Condition) Matcher {
+		if c.RegexLabel == "" {
+			return nil
+		}
+		return regexLabelMatcher{a: a, regexLabel: c.RegexLabel}
+	})
+	RegisterMatcher("commit_message_regex", func(a *Approval, c configuration.Condition) Matcher {
+		if c.CommitMessageRegex == "" {
+			return nil
+		}
+		return commitMessageRegexMatcher{a: a, regex: c.CommitMessageRegex}
+	})
+	RegisterMatcher("directories", func(a *Approval, c configuration.Condition) Matcher {
+		if len(c.Directories) == 0 && len(c.ExcludeDirectories) == 0 {
+			return nil
+		}
+		return directoriesMatcher{a: a, directories: c.Directories, excludeDirectories: c.ExcludeDirectories}
+	})
+	RegisterCondition("author", validateAuthorCondition, func(a *Approval, c configuration.Condition) Matcher {
+		var logins []string
+		if ok, _ := c.Decode("author", &logins); !ok {
+			return nil
+		}
+		for i, login := range logins {
+			logins[i] = strings.TrimPrefix(strings.TrimSpace(login), configuration.UserHandlePrefix)
+		}
+		return authorMatcher{logins: logins}
+	})
+	RegisterCondition("milestone", validateMilestoneCondition, func(a *Approval, c configuration.Condition) Matcher {
+		var titles []string
+		if ok, _ := c.Decode("milestone", &titles); !ok {
+			return nil
+		}
+		return milestoneMatcher{titles: titles}
+	})
+	RegisterCondition("file_count", validateFileCountCondition, func(a *Approval, c configuration.Condition) Matcher {
+		var bounds fileCountBounds
+		if ok, _ := c.Decode("file_count", &bounds); !ok {
+			return nil
+		}
+		return fileCountMatcher{a: a, bounds: bounds}
+	})
+}
+
+// validateAuthorCondition checks that "author" lists the logins of users.
+func validateAuthorCondition(c configuration.Condition) error {
+	var logins []string
+	if _, err := c.Decode("author", &logins); err != nil {
+		return errors.New("must be a list of logins")
+	}
+	if len(logins) == 0 {
+		return errors.New("must list at least one login")
+	}
+	for i, login := range logins {
+		if strings.TrimPrefix(strings.TrimSpace(login), configuration.UserHandlePrefix) == "" {
+			return fmt.Errorf("login %d must not be empty", i)
+		}
+	}
+	return nil
+}
+
+// validateMilestoneCondition checks that "milestone" lists the titles of milestones.
+func validateMilestoneCondition(c configuration.Condition) error {
+	var titles []string
+	if _, err := c.Decode("milestone", &titles); err != nil {
+		return errors.New("must be a list of milestone titles")
+	}
+	if len(titles) == 0 {
+		return errors.New("must list at least one milestone")
+	}
+	for i, title := range titles {
+		if strings.TrimSpace(title) == "" {
+			return fmt.Errorf("milestone %d must not be empty", i)
+		}
+	}
+	return nil
+}
+
+// fileCountBounds bounds the number of files changed by a PR. Either bound may be omitted.
+type fileCountBounds struct {
+	Min *int `yaml:"min"`
+	Max *int `yaml:"max"`
+}
+
+func (b fileCountBounds) String() string {
+	switch {
+	case b.Min != nil && b.Max != nil:
+		return fmt.Sprintf("between %d and %d", *b.Min, *b.Max)
+	case b.Min != nil:
+		return fmt.Sprintf("at least %d", *b.Min)
+	default:
+		return fmt.Sprintf("at most %d", *b.Max)
+	}
+}
+
+// validateFileCountCondition checks that "file_count" sets a minimum and/or a maximum number of files.
+func validateFileCountCondition(c configuration.Condition) error {
+	var bounds fileCountBounds
+	if _, err := c.Decode("file_count", &bounds); err != nil {
+		return errors.New("must set min and/or max")
+	}
+	switch {
+	case bounds.Min == nil && bounds.Max == nil:
+		return errors.New("must set min and/or max")
+	case bounds.Min != nil && *bounds.Min < 0, bounds.Max != nil && *bounds.Max < 0:
+		return errors.New("min and max must not be negative")
+	case bounds.Min != nil && bounds.Max != nil && *bounds.Min > *bounds.Max:
+		return fmt.Errorf("min must not be greater than max, got %d and %d", *bounds.Min, *bounds.Max)
+	}
+	return nil
+}
+
+// allOfMatcher is met when all of its matchers are met. It is met when empty.
+type allOfMatcher []Matcher
+
+func (m allOfMatcher) Match(ctx context.Context, pr *PR) (bool, string, error) {
+	reasons := make([]string, 0, len(m))
+	for _, child := range m {
+		matched, reason, err := child.Match(ctx, pr)
+		if err != nil || !matched {
+			return false, reason, err
+		}
+		reasons = append(reasons, reason)
+	}
+	if len(reasons) == 0 {
+		return true, "no conditions to meet", nil
+	}
+	return true, strings.Join(reasons, " and "), nil
+}
+
+// anyOfMatcher is met when at least one of its matchers is met. It is not met when empty.
+type anyOfMatcher []Matcher
+
+func (m anyOfMatcher) Match(ctx context.Context, pr *PR) (bool, string, error) {
+	reasons := make([]string, 0, len(m))
+	for _, child := range m {
+		matched, reason, err := child.Match(ctx, pr)
+		if err != nil || matched {
+			return matched, reason, err
+		}
+		reasons = append(reasons, reason)
+	}
+	if len(reasons) == 0 {
+		return false, "no conditions to choose from", nil
+	}
+	return false, "none of: " + strings.Join(reasons, "; "), nil
+}
+
+// notMatcher is met when the wrapped matcher is not. The reason given by the wrapped matcher explains both outcomes.
+type notMatcher struct {
+	Matcher
+}
+
+func (m notMatcher) Match(ctx context.Context, pr *PR) (bool, string, error) {
+	matched, reason, err := m.Matcher.Match(ctx, pr)
+	return !matched && err == nil, reason, err
+}
+
+// regexMatcher is met when the body of the PR matches the regular expression.
+type regexMatcher struct {
+	a     *Approval
+	regex string
+}
+
+func (m regexMatcher) Match(ctx context.Context, pr *PR) (bool, string, error) {
+	matched, err := m.a.isRegexMatched(ctx, pr.OwnerLogin, pr.RepoName, pr.Number, m.regex, pr.Body)
+	if matched {
+		return true, fmt.Sprintf("body matches %q", m.regex), err
+	}
+	return false, fmt.Sprintf("body doesn't match %q", m.regex), err
+}
+
+// fieldRegexMatcher is met when a field of the PR matches the regular expression.
+type fieldRegexMatcher struct {
+	a     *Approval
+	field string
+	regex string
+	value func(pr *PR) string
+}
+
+func (m fieldRegexMatcher) Match(ctx context.Context, pr *PR) (bool, string, error) {
+	matched, err := m.a.isRegexMatched(ctx, pr.OwnerLogin, pr.RepoName, pr.Number, m.regex, m.value(pr))
+	if matched {
+		return true, fmt.Sprintf("%s matches %q", m.field, m.regex), err
+	}
+	return false, fmt.Sprintf("%s doesn't match %q", m.field, m.regex), err
+}
+
+// commitMessageRegexMatcher is met when the message of any commit of the PR matches the regular expression.
+type commitMessageRegexMatcher struct {
+	a     *Approval
+	regex string
+}
+
+func (m commitMessageRegexMatcher) Match(ctx context.Context, pr *PR) (bool, string, error) {
+	matched, err := m.a.isRegexCommitMessageMatched(ctx, pr, m.regex)
+	if matched {
+		return true, fmt.Sprintf("a commit message matches %q", m.regex), err
+	}
+	return false, fmt.Sprintf("no commit message matches %q", m.regex), err
+}
+
+// regexLabelMatcher is met when any label of the PR matches the regular expression.
+type regexLabelMatcher struct {
+	a          *Approval
+	regexLabel string
+}
+
+func (m regexLabelMatcher) Match(ctx context.Context, pr *PR) (bool, string, error) {
+	matched, err := m.a.isRegexLabelMatched(ctx, pr.OwnerLogin, pr.RepoName, pr.Number, m.regexLabel)
+	if matched {
+		return true, fmt.Sprintf("a label matches %q", m.regexLabel), err
+	}
+	return false, fmt.Sprintf("no label matches %q", m.regexLabel), err
+}
+
+// directoriesMatcher is met when the PR changes files in any of the directories, other than in the excluded ones.
+type directoriesMatcher struct {
+	a                  *Approval
+	directories        []string
+	excludeDirectories []string
+}
+
+func (m directoriesMatcher) Match(ctx context.Context, pr *PR) (bool, string, error) {
+	matched, err := m.a.areDirectoriesMatched(ctx, pr.OwnerLogin, pr.RepoName, pr.Number, m.directories, m.excludeDirectories)
+
+	where := "anywhere"
+	if len(m.directories) > 0 {
+		where = fmt.Sprintf("in %v", m.directories)
+	}
+	if len(m.excludeDirectories) > 0 {
+		where = fmt.Sprintf("%s outside %v", where, m.excludeDirectories)
+	}
+	if matched {
+		return true, fmt.Sprintf("files changed %s", where), err
+	}
+	return false, fmt.Sprintf("no files changed %s", where), err
+}
+
+// authorMatcher is met when the PR was opened by any of the users.
+type authorMatcher struct {
+	logins []string
+}
+
+func (m authorMatcher) Match(_ context.Context, pr *PR) (bool, string, error) {
+	author := pr.Author.GetLogin()
+	for _, login := range m.logins {
+		if strings.EqualFold(login, author) {
+			return true, fmt.Sprintf("author is %q", author), nil
+		}
+	}
+	return false, fmt.Sprintf("author %q is not one of %v", author, m.logins), nil
+}
+
+// milestoneMatcher is met when the PR is in any of the milestones.
+type milestoneMatcher struct {
+	titles []string
+}
+
+func (m milestoneMatcher) Match(_ context.Context, pr *PR) (bool, string, error) {
+	if pr.Milestone == "" {
+		return false, fmt.Sprintf("no milestone is set, expected one of %v", m.titles), nil
+	}
+	for _, title := range m.titles {
+		if title == pr.Milestone {
+			return true, fmt.Sprintf("milestone is %q", pr.Milestone), nil
+		}
+	}
+	return false, fmt.Sprintf("milestone %q is not one of %v", pr.Milestone, m.titles), nil
+}
+
+// fileCountMatcher is met when the number of files changed by the PR is within the bounds.
+type fileCountMatcher struct {
+	a      *Approval
+	bounds fileCountBounds
+}
+
+func (m fileCountMatcher) Match(ctx context.Context, pr *PR) (bool, string, error) {
+	commitFiles, err := m.a.client.GetPullRequestCommitFiles(ctx, pr.OwnerLogin, pr.RepoName, pr.Number)
+	if err != nil {
+		return false, "", fmt.Errorf("file count match: get pull request commit files: %w", err)
+	}
+	n := len(commitFiles)
+	if (m.bounds.Min != nil && n < *m.bounds.Min) || (m.bounds.Max != nil && n > *m.bounds.Max) {
+		return false, fmt.Sprintf("%d files changed, not %s", n, m.bounds), nil
+	}
+	return true, fmt.Sprintf("%d files changed, %s", n, m.bounds), nil
+}
+
+// NewMatcher builds the tree of matchers for the given condition, using the registered matchers for the fields of
+// each node.
+func (a *Approval) NewMatcher(c configuration.Condition) Matcher {
+	var m allOfMatcher
+	for _, registered := range registeredMatchers() {
+		if leaf := registered.factory(a, c); leaf != nil {
+			m = append(m, leaf)
+		}
+	}
+	for _, child := range c.AllOf {
+		m = append(m, a.NewMatcher(child))
+	}
+	if c.AnyOf != nil {
+		var any anyOfMatcher
+		for _, child := range c.AnyOf {
+			any = append(any, a.NewMatcher(child))
+		}
+		m = append(m, any)
+	}
+	if c.Not != nil {
+		m = append(m, notMatcher{a.NewMatcher(*c.Not)})
+	}
+
+	if len(m) == 1 {
+		return m[0]
+	}
+	return m
+}
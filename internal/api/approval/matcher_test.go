@@ -0,0 +1,414 @@
+package approval
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v42/github"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/form3tech-oss/github-team-approver/internal/api/configuration"
+)
+
+func TestNewMatcher(t *testing.T) {
+	tests := map[string]struct {
+		condition configuration.Condition
+		body      string
+		want      bool
+	}{
+		"single regex": {
+			condition: configuration.Condition{Regex: "security"},
+			body:      "Impacts SECURITY",
+			want:      true,
+		},
+		"all of, all met": {
+			condition: configuration.Condition{AllOf: []configuration.Condition{{Regex: "foo"}, {Regex: "bar"}}},
+			body:      "foo bar",
+			want:      true,
+		},
+		"all of, one not met": {
+			condition: configuration.Condition{AllOf: []configuration.Condition{{Regex: "foo"}, {Regex: "bar"}}},
+			body:      "foo",
+			want:      false,
+		},
+		"any of, one met": {
+			condition: configuration.Condition{AnyOf: []configuration.Condition{{Regex: "foo"}, {Regex: "bar"}}},
+			body:      "bar",
+			want:      true,
+		},
+		"any of, none met": {
+			condition: configuration.Condition{AnyOf: []configuration.Condition{{Regex: "foo"}, {Regex: "bar"}}},
+			body:      "baz",
+			want:      false,
+		},
+		"empty any of": {
+			condition: configuration.Condition{AnyOf: []configuration.Condition{}},
+			body:      "foo",
+			want:      false,
+		},
+		"not, met": {
+			condition: configuration.Condition{Not: &configuration.Condition{Regex: "skip-security"}},
+			body:      "foo",
+			want:      true,
+		},
+		"not, not met": {
+			condition: configuration.Condition{Not: &configuration.Condition{Regex: "skip-security"}},
+			body:      "please skip-security",
+			want:      false,
+		},
+		"fields of the same node are all required": {
+			condition: configuration.Condition{
+				Regex: "foo",
+				AnyOf: []configuration.Condition{{Regex: "bar"}, {Regex: "baz"}},
+				Not:   &configuration.Condition{Regex: "qux"},
+			},
+			body: "foo baz",
+			want: true,
+		},
+		"nested": {
+			condition: configuration.Condition{
+				AnyOf: []configuration.Condition{
+					{AllOf: []configuration.Condition{{Regex: "foo"}, {Not: &configuration.Condition{Regex: "bar"}}}},
+					{Regex: "baz"},
+				},
+			},
+			body: "foo bar",
+			want: false,
+		},
+	}
+
+	a := NewApproval(logrus.NewEntry(logrus.StandardLogger()), nil, Settings{})
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			matched, _, err := a.NewMatcher(tt.condition).Match(context.Background(), &PR{Body: tt.body})
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, matched)
+		})
+	}
+}
+
+func TestNewMatcherWithInvalidRegex(t *testing.T) {
+	a := NewApproval(logrus.NewEntry(logrus.StandardLogger()), nil, Settings{})
+	condition := configuration.Condition{Not: &configuration.Condition{Regex: "("}}
+
+	matched, _, err := a.NewMatcher(condition).Match(context.Background(), &PR{Body: "foo"})
+
+	require.Error(t, err)
+	require.False(t, matched)
+}
+
+func TestNewMatcherReasons(t *testing.T) {
+	tests := map[string]struct {
+		condition configuration.Condition
+		body      string
+		matched   bool
+		reason    string
+	}{
+		"regex met": {
+			condition: configuration.Condition{Regex: "foo"},
+			body:      "foo",
+			matched:   true,
+			reason:    `body matches "foo"`,
+		},
+		"all of met": {
+			condition: configuration.Condition{AllOf: []configuration.Condition{{Regex: "foo"}, {Regex: "bar"}}},
+			body:      "foo bar",
+			matched:   true,
+			reason:    `body matches "foo" and body matches "bar"`,
+		},
+		"all of not met": {
+			condition: configuration.Condition{AllOf: []configuration.Condition{{Regex: "foo"}, {Regex: "bar"}}},
+			body:      "foo",
+			matched:   false,
+			reason:    `body doesn't match "bar"`,
+		},
+		"any of not met": {
+			condition: configuration.Condition{AnyOf: []configuration.Condition{{Regex: "foo"}, {Regex: "bar"}}},
+			body:      "baz",
+			matched:   false,
+			reason:    `none of: body doesn't match "foo"; body doesn't match "bar"`,
+		},
+		"not not met": {
+			condition: configuration.Condition{Not: &configuration.Condition{Regex: "skip"}},
+			body:      "skip",
+			matched:   false,
+			reason:    `body matches "skip"`,
+		},
+	}
+
+	a := NewApproval(logrus.NewEntry(logrus.StandardLogger()), nil, Settings{})
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			matched, reason, err := a.NewMatcher(tt.condition).Match(context.Background(), &PR{Body: tt.body})
+
+			require.NoError(t, err)
+			require.Equal(t, tt.matched, matched)
+			require.Equal(t, tt.reason, reason)
+		})
+	}
+}
+
+func TestNewMatcherWithPullRequestFields(t *testing.T) {
+	pr := &PR{
+		Title:      "[HOTFIX] Fix login",
+		HeadBranch: "release/1.2",
+		Body:       "Fixes the login page",
+	}
+	tests := map[string]struct {
+		condition configuration.Condition
+		matched   bool
+		reason    string
+	}{
+		"title met": {
+			condition: configuration.Condition{TitleRegex: `^\[hotfix\]`},
+			matched:   true,
+			reason:    `title matches "^\\[hotfix\\]"`,
+		},
+		"title not met": {
+			condition: configuration.Condition{TitleRegex: `^\[feature\]`},
+			matched:   false,
+			reason:    `title doesn't match "^\\[feature\\]"`,
+		},
+		"head branch met": {
+			condition: configuration.Condition{HeadBranchRegex: `^release/`},
+			matched:   true,
+			reason:    `head branch matches "^release/"`,
+		},
+		"head branch not met": {
+			condition: configuration.Condition{HeadBranchRegex: `^hotfix/`},
+			matched:   false,
+			reason:    `head branch doesn't match "^hotfix/"`,
+		},
+		"commit message met": {
+			condition: configuration.Condition{CommitMessageRegex: `^feat!:`},
+			matched:   true,
+			reason:    `a commit message matches "^feat!:"`,
+		},
+		"commit message not met": {
+			condition: configuration.Condition{CommitMessageRegex: `^fix!:`},
+			matched:   false,
+			reason:    `no commit message matches "^fix!:"`,
+		},
+	}
+
+	a := NewApproval(logrus.NewEntry(logrus.StandardLogger()), nil, Settings{})
+	a.commits = []*github.RepositoryCommit{
+		{Commit: &github.Commit{Message: github.String("fix: typo")}},
+		{Commit: &github.Commit{Message: github.String("feat!: drop legacy login\n\nBREAKING CHANGE: removed")}},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			matched, reason, err := a.NewMatcher(tt.condition).Match(context.Background(), pr)
+
+			require.NoError(t, err)
+			require.Equal(t, tt.matched, matched)
+			require.Equal(t, tt.reason, reason)
+		})
+	}
+}
+
+func TestNewMatcherWithAuthor(t *testing.T) {
+	cfg, err := configuration.ReadConfiguration(strings.NewReader(`
+pull_request_approval_rules:
+- rules:
+  - when:
+      author: ["@alice", "Bob"]
+    approving_team_handles:
+    - cab
+`))
+	require.NoError(t, err)
+	condition := *cfg.PullRequestApprovalRules[0].Rules[0].When
+
+	tests := map[string]struct {
+		author  string
+		matched bool
+		reason  string
+	}{
+		"author listed": {
+			author:  "alice",
+			matched: true,
+			reason:  `author is "alice"`,
+		},
+		"author listed with another case": {
+			author:  "bob",
+			matched: true,
+			reason:  `author is "bob"`,
+		},
+		"author not listed": {
+			author:  "eve",
+			matched: false,
+			reason:  `author "eve" is not one of [alice Bob]`,
+		},
+	}
+
+	a := NewApproval(logrus.NewEntry(logrus.StandardLogger()), nil, Settings{})
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			pr := &PR{Author: &github.User{Login: github.String(tt.author)}}
+			matched, reason, err := a.NewMatcher(condition).Match(context.Background(), pr)
+
+			require.NoError(t, err)
+			require.Equal(t, tt.matched, matched)
+			require.Equal(t, tt.reason, reason)
+		})
+	}
+}
+
+func TestNewMatcherWithMilestone(t *testing.T) {
+	cfg, err := configuration.ReadConfiguration(strings.NewReader(`
+pull_request_approval_rules:
+- rules:
+  - when:
+      milestone: ["v1.2", "v1.3"]
+    approving_team_handles:
+    - cab
+`))
+	require.NoError(t, err)
+	condition := *cfg.PullRequestApprovalRules[0].Rules[0].When
+
+	tests := map[string]struct {
+		milestone string
+		matched   bool
+		reason    string
+	}{
+		"milestone listed": {
+			milestone: "v1.3",
+			matched:   true,
+			reason:    `milestone is "v1.3"`,
+		},
+		"milestone not listed": {
+			milestone: "v2.0",
+			matched:   false,
+			reason:    `milestone "v2.0" is not one of [v1.2 v1.3]`,
+		},
+		"no milestone": {
+			matched: false,
+			reason:  `no milestone is set, expected one of [v1.2 v1.3]`,
+		},
+	}
+
+	a := NewApproval(logrus.NewEntry(logrus.StandardLogger()), nil, Settings{})
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			matched, reason, err := a.NewMatcher(condition).Match(context.Background(), &PR{Milestone: tt.milestone})
+
+			require.NoError(t, err)
+			require.Equal(t, tt.matched, matched)
+			require.Equal(t, tt.reason, reason)
+		})
+	}
+}
+
+// commitFilesSource serves the files changed by the PR, and nothing else.
+type commitFilesSource struct {
+	DataSource
+	files []*github.CommitFile
+}
+
+func (s commitFilesSource) GetPullRequestCommitFiles(context.Context, string, string, int) ([]*github.CommitFile, error) {
+	return s.files, nil
+}
+
+func TestNewMatcherWithFileCount(t *testing.T) {
+	tests := map[string]struct {
+		fileCount string
+		matched   bool
+		reason    string
+	}{
+		"at least, met": {
+			fileCount: "{min: 2}",
+			matched:   true,
+			reason:    "3 files changed, at least 2",
+		},
+		"at most, not met": {
+			fileCount: "{max: 2}",
+			matched:   false,
+			reason:    "3 files changed, not at most 2",
+		},
+		"between, met": {
+			fileCount: "{min: 3, max: 3}",
+			matched:   true,
+			reason:    "3 files changed, between 3 and 3",
+		},
+	}
+
+	files := []*github.CommitFile{{Filename: github.String("a")}, {Filename: github.String("b")}, {Filename: github.String("c")}}
+	a := NewApproval(logrus.NewEntry(logrus.StandardLogger()), commitFilesSource{files: files}, Settings{})
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg, err := configuration.ReadConfiguration(strings.NewReader(`
+pull_request_approval_rules:
+- rules:
+  - when:
+      file_count: ` + tt.fileCount + `
+    approving_team_handles:
+    - cab
+`))
+			require.NoError(t, err)
+
+			matched, reason, err := a.NewMatcher(*cfg.PullRequestApprovalRules[0].Rules[0].When).Match(context.Background(), &PR{})
+
+			require.NoError(t, err)
+			require.Equal(t, tt.matched, matched)
+			require.Equal(t, tt.reason, reason)
+		})
+	}
+}
+
+func TestReadConfigurationWithInvalidMilestoneAndFileCount(t *testing.T) {
+	_, err := configuration.ReadConfiguration(strings.NewReader(`
+pull_request_approval_rules:
+- rules:
+  - when:
+      any_of:
+      - milestone: v1.2
+      - milestone: [""]
+      - file_count: {}
+      - file_count: {min: 5, max: 2}
+      - file_count: {max: -1}
+      - file_count: {minimum: 1}
+    approving_team_handles:
+    - cab
+`))
+
+	var invalid *configuration.ValidationError
+	require.True(t, errors.As(err, &invalid), "expected a validation error, got %v", err)
+	require.Equal(t, []string{
+		"pull_request_approval_rules[0].rules[0].when.any_of[0].milestone: must be a list of milestone titles",
+		"pull_request_approval_rules[0].rules[0].when.any_of[1].milestone: milestone 0 must not be empty",
+		"pull_request_approval_rules[0].rules[0].when.any_of[2].file_count: must set min and/or max",
+		"pull_request_approval_rules[0].rules[0].when.any_of[3].file_count: min must not be greater than max, got 5 and 2",
+		"pull_request_approval_rules[0].rules[0].when.any_of[4].file_count: min and max must not be negative",
+		"pull_request_approval_rules[0].rules[0].when.any_of[5].file_count: must set min and/or max",
+	}, invalid.Problems)
+}
+
+func TestReadConfigurationWithInvalidAuthor(t *testing.T) {
+	_, err := configuration.ReadConfiguration(strings.NewReader(`
+pull_request_approval_rules:
+- rules:
+  - when:
+      any_of:
+      - author: alice
+      - author: []
+    approving_team_handles:
+    - cab
+`))
+
+	var invalid *configuration.ValidationError
+	require.True(t, errors.As(err, &invalid), "expected a validation error, got %v", err)
+	require.Equal(t, []string{
+		"pull_request_approval_rules[0].rules[0].when.any_of[0].author: must be a list of logins",
+		"pull_request_approval_rules[0].rules[0].when.any_of[1].author: must list at least one login",
+	}, invalid.Problems)
+}
+
+func TestRegisterMatcherTwice(t *testing.T) {
+	require.Panics(t, func() {
+		RegisterMatcher("regex", func(a *Approval, c configuration.Condition) Matcher { return nil })
+	})
+}
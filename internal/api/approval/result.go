@@ -1,18 +1,43 @@
 package approval
 
-import "fmt"
+import (
+	"fmt"
+	"unicode/utf8"
+)
 
 const (
 	statusEventDescriptionMaxLength = 140
 )
 
+// RuleMatch records whether one of the rules for the target branch applies to the PR, and why, along with the
+// approvals recorded for it if it does.
+type RuleMatch struct {
+	// Rule is the index of the rule among the ones for the target branch.
+	Rule    int    `json:"rule"`
+	Matched bool   `json:"matched"`
+	Reason  string `json:"reason"`
+	// Conditions records whether the PR meets each condition of the rule. It is only set when explaining the result.
+	Conditions       []ConditionMatch `json:"conditions,omitempty"`
+	Teams            []TeamApproval   `json:"teams,omitempty"`
+	Approvers        []string         `json:"approvers,omitempty"`
+	MinimumApprovals int              `json:"minimum_approvals,omitempty"`
+	Fulfilled        bool             `json:"fulfilled"`
+}
+
 type Result struct {
 	status           string
 	description      string
 	finalLabels      []string
 	reviewsToRequest []string
-	ignoredReviewers []string
-	invalidReviewers []string
+	// userReviewsToRequest holds the logins of the individual approvers to request a review from.
+	userReviewsToRequest []string
+	ignoredReviewers     []string
+	invalidReviewers     []string
+	staleReviewers       []string
+	// trace explains how the result was computed.
+	trace Trace
+	// source notes where the configuration file was read from, if it was read.
+	source string
 }
 
 func (r *Result) pendingReviewsWaiting() bool {
@@ -20,25 +45,41 @@ func (r *Result) pendingReviewsWaiting() bool {
 }
 
 func (r *Result) Description() string {
-	return truncate(r.description, statusEventDescriptionMaxLength)
+	if r.source == "" {
+		return truncate(r.description, statusEventDescriptionMaxLength)
+	}
+	// Keep the note on where the configuration file was read from visible, however long the description is.
+	n := statusEventDescriptionMaxLength - len(r.source) - 1
+	if n <= 0 {
+		return truncate(r.source, statusEventDescriptionMaxLength)
+	}
+	return fmt.Sprintf("%s\n%s", truncate(r.description, n), r.source)
 }
 
-func (r *Result) Status() string             { return r.status }
-func (r *Result) FinalLabels() []string      { return r.finalLabels }
-func (r *Result) ReviewsToRequest() []string { return r.reviewsToRequest }
-func (r *Result) IgnoredReviewers() []string { return r.ignoredReviewers }
-func (r *Result) InvalidReviewers() []string { return r.invalidReviewers }
+func (r *Result) Status() string                 { return r.status }
+func (r *Result) FinalLabels() []string          { return r.finalLabels }
+func (r *Result) ReviewsToRequest() []string     { return r.reviewsToRequest }
+func (r *Result) UserReviewsToRequest() []string { return r.userReviewsToRequest }
+func (r *Result) IgnoredReviewers() []string     { return r.ignoredReviewers }
+func (r *Result) InvalidReviewers() []string     { return r.invalidReviewers }
+func (r *Result) StaleReviewers() []string       { return r.staleReviewers }
+func (r *Result) RuleMatches() []RuleMatch       { return r.trace.Rules }
+func (r *Result) Trace() Trace                   { return r.trace }
 
+// truncate cuts v to at most n bytes, ending it with "..." when there is room for it. It never cuts a rune in half, so
+// that the result stays valid UTF-8.
 func truncate(v string, n int) string {
-	suffix := "..."
+	if len(v) <= n {
+		return v
+	}
 
+	suffix := "..."
 	if n <= len(suffix) {
-		return v[:n]
+		suffix = ""
 	}
-
-	if len(v) > n {
-		return fmt.Sprintf("%s%s", v[:n-len(suffix)], suffix)
+	end := n - len(suffix)
+	for end > 0 && !utf8.RuneStart(v[end]) {
+		end--
 	}
-
-	return v
+	return v[:end] + suffix
 }
@@ -1,7 +1,9 @@
 package approval
 
 import (
+	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/stretchr/testify/require"
 )
@@ -32,6 +34,21 @@ func TestTruncate(t *testing.T) {
 			n:        4,
 			expected: "f...",
 		},
+		"no truncation expected with a shorter value": {
+			v:        "ba",
+			n:        3,
+			expected: "ba",
+		},
+		"expecting truncation before a multi-byte rune": {
+			v:        "éééé",
+			n:        6,
+			expected: "é...",
+		},
+		"expecting no rune cut in half without suffix": {
+			v:        "éé",
+			n:        3,
+			expected: "é",
+		},
 	}
 	for name, tt := range tests {
 		t.Run(name, func(t *testing.T) {
@@ -39,3 +56,28 @@ func TestTruncate(t *testing.T) {
 		})
 	}
 }
+
+func TestResult_DescriptionKeepsSource(t *testing.T) {
+	source := `Configuration read from base branch "release/1.2".`
+	r := &Result{
+		description: "Needs approval from:\n" + strings.Repeat("team-", 40),
+		source:      source,
+	}
+
+	description := r.Description()
+	require.Len(t, description, statusEventDescriptionMaxLength)
+	require.True(t, strings.HasPrefix(description, "Needs approval from:\nteam-"))
+	require.True(t, strings.HasSuffix(description, "...\n"+source))
+}
+
+func TestResult_DescriptionStaysValidUTF8(t *testing.T) {
+	r := &Result{
+		description: "Needs approval from:\n" + strings.Repeat("équipe-", 40),
+		source:      "Config: default branch.",
+	}
+
+	description := r.Description()
+	require.LessOrEqual(t, len(description), statusEventDescriptionMaxLength)
+	require.True(t, utf8.ValidString(description), description)
+	require.True(t, strings.HasSuffix(description, "...\nConfig: default branch."))
+}
@@ -5,6 +5,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/form3tech-oss/github-team-approver/internal/api/configuration"
 	"github.com/google/go-github/v42/github"
 	log "github.com/sirupsen/logrus"
 )
@@ -15,7 +16,10 @@ const (
 	statusEventDescriptionNoReviewsRequested   = "No teams have been identified as having to be requested for a review."
 	statusEventDescriptionNoRulesMatched       = "The PR's body doesn't meet the requirements."
 	statusEventDescriptionPendingFormatString  = "Needs approval from:\n%s"
+	statusEventDescriptionStaleFormatString    = "Stale approvals from:\n%s"
+	statusEventDescriptionBlockedFormatString  = "Changes requested by:\n%s"
 	statusEventDescriptionInvalidTeamHandles   = "Invalid config: no teams could be found for the following handles:\n%s"
+	statusEventDescriptionConfigurationGuard   = "Changes to " + configuration.ConfigurationFilePath + " need approval from:\n%s"
 )
 
 type state struct {
@@ -32,6 +36,14 @@ type state struct {
 	invalidTeamHandles []string
 	// Reviewers who have approved PR but are not member of any valid team
 	invalidReviewers []string
+	// Reviewers whose approval was given for a previous version of the PR and was therefore discarded
+	staleReviewers []string
+	// Whether each rule for the target branch applies to the PR, and why
+	ruleMatches []RuleMatch
+	// Whether no rules are defined for the target branch
+	noRulesForTargetBranch bool
+	// Built-in rule guarding changes to the configuration file, if the PR changes it
+	configurationGuard *MatchedRule
 }
 
 func newState() *state {
@@ -49,6 +61,10 @@ func (s *state) addMatchedRule(mr MatchedRule) {
 	s.matchedRules = append(s.matchedRules, mr)
 }
 
+func (s *state) addRuleMatch(rm RuleMatch) {
+	s.ruleMatches = append(s.ruleMatches, rm)
+}
+
 func (s *state) addInvalidTeamHandle(name string) {
 	s.invalidTeamHandles = appendIfMissing(s.invalidTeamHandles, name)
 }
@@ -79,6 +95,10 @@ func (s *state) addIgnoredReviewers(ignoredTeamMembers []string) {
 	s.ignoredReviewers = uniqueAppend(s.ignoredReviewers, ignoredReviewers)
 }
 
+func (s *state) addStaleReviewers(staleReviewers []string) {
+	s.staleReviewers = uniqueAppend(s.staleReviewers, staleReviewers)
+}
+
 func (s *state) allRulesFulfilled() bool {
 	for _, rule := range s.matchedRules {
 		if !rule.Fulfilled() {
@@ -109,6 +129,44 @@ func (s *state) pendingTeamNames() []string {
 	return allPending
 }
 
+func (s *state) pendingDescriptions() []string {
+	allPending := make([]string, 0)
+	for _, rule := range s.matchedRules {
+		if !rule.Fulfilled() {
+			allPending = uniqueAppend(allPending, rule.PendingDescriptions())
+		}
+	}
+
+	return allPending
+}
+
+func (s *state) blockingReviewers() []string {
+	allBlocking := make([]string, 0)
+	for _, rule := range s.matchedRules {
+		if !rule.Fulfilled() {
+			allBlocking = uniqueAppend(allBlocking, rule.BlockingReviewers())
+		}
+	}
+
+	return allBlocking
+}
+
+// pendingDescription describes what is preventing the PR from being approved, starting with the reviewers who have
+// requested changes as they are the most actionable piece of information.
+func (s *state) pendingDescription() string {
+	var parts []string
+	if blocking := s.blockingReviewers(); len(blocking) > 0 {
+		parts = append(parts, fmt.Sprintf(statusEventDescriptionBlockedFormatString, strings.Join(blocking, "\n")))
+	}
+	if pending := s.pendingDescriptions(); len(pending) > 0 {
+		parts = append(parts, fmt.Sprintf(statusEventDescriptionPendingFormatString, strings.Join(pending, "\n")))
+	}
+	if len(s.staleReviewers) > 0 {
+		parts = append(parts, fmt.Sprintf(statusEventDescriptionStaleFormatString, strings.Join(s.staleReviewers, "\n")))
+	}
+	return strings.Join(parts, "\n")
+}
+
 func (s *state) approvingTeamNames() []string {
 	allApproving := make([]string, 0)
 	for _, rule := range s.matchedRules {
@@ -119,14 +177,20 @@ func (s *state) approvingTeamNames() []string {
 	return allApproving
 }
 
+// shouldForceApprove returns whether any matched rule forcibly approves the PR. Mandatory rules aren't overridden by
+// forcible approvals, so they must all be fulfilled as well.
 func (s *state) shouldForceApprove() bool {
+	forced := false
 	for _, rule := range s.matchedRules {
+		if rule.ConfigRule.Mandatory && !rule.Fulfilled() {
+			return false
+		}
 		if rule.ConfigRule.ForceApproval {
-			return true
+			forced = true
 		}
 	}
 
-	return false
+	return forced
 }
 
 func (s *state) result(log *log.Entry, teams []*github.Team) *Result {
@@ -134,6 +198,7 @@ func (s *state) result(log *log.Entry, teams []*github.Team) *Result {
 		finalLabels:      s.labels,
 		ignoredReviewers: s.ignoredReviewers,
 		invalidReviewers: s.invalidReviewers,
+		staleReviewers:   s.staleReviewers,
 	}
 
 	pendingTeamNames := s.pendingTeamNames()
@@ -141,25 +206,30 @@ func (s *state) result(log *log.Entry, teams []*github.Team) *Result {
 
 	// Compute the final status based on whether all required approvals have been met.
 	switch {
-	case len(s.matchedRules) == 0:
-		// No rules have been matched, which represents an error.
-		result.description = statusEventDescriptionNoRulesMatched
-		result.status = StatusEventStatusPending
 	case len(s.invalidTeamHandles) > 0:
 		// The configuration references a non-existent team
 		result.description = fmt.Sprintf(statusEventDescriptionInvalidTeamHandles, strings.Join(s.invalidTeamHandles, "\n"))
 		result.status = StatusEventStatusError
+	case len(s.matchedRules) == 0 && s.noRulesForTargetBranch:
+		// Only the configuration guard applies to the PR.
+		result.description = statusEventDescriptionNoRulesForTargetBranch
+		result.status = StatusEventStatusSuccess
+	case len(s.matchedRules) == 0:
+		// No rules have been matched, which represents an error.
+		result.description = statusEventDescriptionNoRulesMatched
+		result.status = StatusEventStatusPending
 	case s.shouldForceApprove():
 		// The PR is being forcibly approved.
 		result.description = statusEventDescriptionForciblyApproved
 		result.status = StatusEventStatusSuccess
 		result.reviewsToRequest = computeReviewsToRequest(log, teams, pendingTeamNames)
-	case len(pendingTeamNames) > 0:
-		// At least one team must still approve the PR before it goes green.
-		result.description = fmt.Sprintf(
-			statusEventDescriptionPendingFormatString, strings.Join(pendingTeamNames, "\n"))
+		result.userReviewsToRequest = computeUserReviewsToRequest(log, pendingTeamNames)
+	case len(pendingTeamNames) > 0 || len(s.blockingReviewers()) > 0:
+		// At least one team must still approve the PR, or a reviewer must withdraw their request for changes, before it goes green.
+		result.description = s.pendingDescription()
 		result.status = StatusEventStatusPending
 		result.reviewsToRequest = computeReviewsToRequest(log, teams, pendingTeamNames)
+		result.userReviewsToRequest = computeUserReviewsToRequest(log, pendingTeamNames)
 	case len(pendingTeamNames) == 0 && len(approvingTeamNames) == 0:
 		// No teams have been identified as having to be requested for a review.
 		// NOTE: This should not really happen in practice.
@@ -170,12 +240,55 @@ func (s *state) result(log *log.Entry, teams []*github.Team) *Result {
 		result.description = fmt.Sprintf(statusEventDescriptionApprovedFormatString, strings.Join(approvingTeamNames, "\n"))
 		result.status = StatusEventStatusSuccess
 	}
+	s.applyConfigurationGuard(log, teams, result)
+	result.trace = s.trace()
 
 	return result
 }
 
+func (s *state) trace() Trace {
+	trace := Trace{
+		Rules:              s.ruleMatches,
+		InvalidTeamHandles: s.invalidTeamHandles,
+		IgnoredReviewers:   s.ignoredReviewers,
+		InvalidReviewers:   s.invalidReviewers,
+		StaleReviewers:     s.staleReviewers,
+	}
+	if s.configurationGuard != nil {
+		guard := RuleMatch{
+			Rule:      -1,
+			Matched:   true,
+			Reason:    fmt.Sprintf("PR changes %s", configuration.ConfigurationFilePath),
+			Fulfilled: true,
+		}
+		guard.record(*s.configurationGuard)
+		trace.ConfigurationGuard = &guard
+	}
+	return trace
+}
+
+// applyConfigurationGuard keeps the PR pending until the configuration admin team approves the changes to the
+// configuration file, whatever the outcome of the other rules (including forcible approvals). The guard is described
+// first, followed by anything else still preventing the PR from being approved.
+func (s *state) applyConfigurationGuard(log *log.Entry, teams []*github.Team, result *Result) {
+	guard := s.configurationGuard
+	if guard == nil || guard.Fulfilled() || result.status == StatusEventStatusError {
+		return
+	}
+
+	description := fmt.Sprintf(statusEventDescriptionConfigurationGuard, strings.Join(guard.PendingDescriptions(), "\n"))
+	if result.status == StatusEventStatusPending {
+		description = fmt.Sprintf("%s\n%s", description, result.description)
+	}
+	pending := guard.PendingTeamNames()
+	result.description = description
+	result.status = StatusEventStatusPending
+	result.reviewsToRequest = uniqueAppend(result.reviewsToRequest, computeReviewsToRequest(log, teams, pending))
+	result.userReviewsToRequest = uniqueAppend(result.userReviewsToRequest, computeUserReviewsToRequest(log, pending))
+}
+
 func (s *state) updateInvalidReviewers(allAllowedMembers map[string]bool) {
-	if len(s.matchedRules) == 0 {
+	if len(s.matchedRules) == 0 && s.configurationGuard == nil {
 		return
 	}
 	for member := range s.approvingReviewers {
@@ -198,3 +311,15 @@ func computeReviewsToRequest(log *log.Entry, teams []*github.Team, pendingTeams
 	log.Tracef("Reviews will be requested from the following teams: %v", reviewsToRequest)
 	return reviewsToRequest
 }
+
+func computeUserReviewsToRequest(log *log.Entry, pendingHandles []string) []string {
+	var reviewsToRequest []string
+
+	for _, handle := range pendingHandles {
+		if login := strings.TrimPrefix(handle, configuration.UserHandlePrefix); login != handle {
+			reviewsToRequest = appendIfMissing(reviewsToRequest, login)
+		}
+	}
+	log.Tracef("Reviews will be requested from the following users: %v", reviewsToRequest)
+	return reviewsToRequest
+}
@@ -0,0 +1,117 @@
+package approval
+
+import (
+	"testing"
+
+	commons "github.com/form3tech-oss/github-team-approver-commons/v2/pkg/configuration"
+	"github.com/google/go-github/v42/github"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/form3tech-oss/github-team-approver/internal/api/configuration"
+)
+
+func TestState_ConfigurationGuard(t *testing.T) {
+	teams := []*github.Team{
+		{Name: github.String("CAB - Foo"), Slug: github.String("cab-foo")},
+		{Name: github.String("Admins"), Slug: github.String("admins")},
+	}
+	rule := func(handle string, approvals int) MatchedRule {
+		mr := NewMatchedRule(configuration.Rule{Rule: commons.Rule{
+			ApprovalMode:         configuration.ApprovalModeRequireAny,
+			ApprovingTeamHandles: []string{handle},
+		}})
+		mr.Approvals[handle] = approvals
+		return mr
+	}
+
+	tests := map[string]struct {
+		matchedRules           []MatchedRule
+		noRulesForTargetBranch bool
+		guard                  MatchedRule
+		status                 string
+		description            string
+		reviewsToRequest       []string
+	}{
+		"guard approved": {
+			matchedRules:     []MatchedRule{rule("cab-foo", 1)},
+			guard:            rule("admins", 1),
+			status:           StatusEventStatusSuccess,
+			description:      "Approved by:\ncab-foo",
+			reviewsToRequest: nil,
+		},
+		"guard pending and rules fulfilled": {
+			matchedRules:     []MatchedRule{rule("cab-foo", 1)},
+			guard:            rule("admins", 0),
+			status:           StatusEventStatusPending,
+			description:      "Changes to .github/GITHUB_TEAM_APPROVER.yaml need approval from:\nadmins",
+			reviewsToRequest: []string{"admins"},
+		},
+		"guard and rules pending": {
+			matchedRules:     []MatchedRule{rule("cab-foo", 0)},
+			guard:            rule("admins", 0),
+			status:           StatusEventStatusPending,
+			description:      "Changes to .github/GITHUB_TEAM_APPROVER.yaml need approval from:\nadmins\nNeeds approval from:\ncab-foo",
+			reviewsToRequest: []string{"cab-foo", "admins"},
+		},
+		"guard pending and no rules for target branch": {
+			noRulesForTargetBranch: true,
+			guard:                  rule("admins", 0),
+			status:                 StatusEventStatusPending,
+			description:            "Changes to .github/GITHUB_TEAM_APPROVER.yaml need approval from:\nadmins",
+			reviewsToRequest:       []string{"admins"},
+		},
+		"guard approved and no rules for target branch": {
+			noRulesForTargetBranch: true,
+			guard:                  rule("admins", 1),
+			status:                 StatusEventStatusSuccess,
+			description:            statusEventDescriptionNoRulesForTargetBranch,
+			reviewsToRequest:       nil,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := newState()
+			for _, mr := range tt.matchedRules {
+				s.addMatchedRule(mr)
+			}
+			s.noRulesForTargetBranch = tt.noRulesForTargetBranch
+			guard := tt.guard
+			s.configurationGuard = &guard
+
+			result := s.result(logrus.NewEntry(logrus.StandardLogger()), teams)
+
+			require.Equal(t, tt.status, result.Status())
+			require.Equal(t, tt.description, result.Description())
+			require.Equal(t, tt.reviewsToRequest, result.ReviewsToRequest())
+		})
+	}
+}
+
+func TestState_PendingDescriptionsOfRulesMissingTheSameApprovals(t *testing.T) {
+	rule := func(index int, handle string) MatchedRule {
+		mr := NewMatchedRule(configuration.Rule{
+			Rule: commons.Rule{
+				ApprovalMode:         configuration.ApprovalModeRequireAny,
+				ApprovingTeamHandles: []string{handle},
+			},
+			MinimumApprovals: 2,
+		})
+		mr.Rule = index
+		mr.Approvals[handle] = 1
+		mr.Approvers["alice"] = true
+		return mr
+	}
+
+	s := newState()
+	s.addMatchedRule(rule(0, "cab-foo"))
+	s.addMatchedRule(rule(2, "cab-bar"))
+
+	require.Equal(t, []string{
+		"cab-foo",
+		"Rule 1 (missing 1 of 2 in total)",
+		"cab-bar",
+		"Rule 3 (missing 1 of 2 in total)",
+	}, s.pendingDescriptions())
+}
@@ -0,0 +1,108 @@
+package approval
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Summary renders the result, and how it was computed, as markdown. Unlike Description, it is not truncated.
+func (r *Result) Summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**Status:** %s\n\n", r.status)
+	b.WriteString(r.description)
+	if r.source != "" {
+		fmt.Fprintf(&b, "\n%s", r.source)
+	}
+	b.WriteString("\n")
+
+	t := r.trace
+	if len(t.ConfigurationErrors) > 0 || len(t.InvalidTeamHandles) > 0 {
+		b.WriteString("\n### Configuration errors\n\n")
+		for _, problem := range t.ConfigurationErrors {
+			fmt.Fprintf(&b, "- %s\n", problem)
+		}
+		for _, handle := range t.InvalidTeamHandles {
+			fmt.Fprintf(&b, "- No team could be found with handle `%s`\n", handle)
+		}
+	}
+
+	if len(t.Rules) > 0 {
+		fmt.Fprintf(&b, "\n### Rules for `%s`\n\n", t.TargetBranch)
+		b.WriteString("| Rule | Applies | Reason | Approving teams | Approvers | Fulfilled |\n")
+		b.WriteString("|------|---------|--------|-----------------|-----------|-----------|\n")
+		for _, rm := range t.Rules {
+			writeRuleRow(&b, fmt.Sprintf("%d", rm.Rule+1), rm)
+		}
+	}
+	if t.ConfigurationGuard != nil {
+		b.WriteString("\n### Configuration file\n\n")
+		b.WriteString("| Rule | Applies | Reason | Approving teams | Approvers | Fulfilled |\n")
+		b.WriteString("|------|---------|--------|-----------------|-----------|-----------|\n")
+		writeRuleRow(&b, "guard", *t.ConfigurationGuard)
+	}
+
+	if len(t.IgnoredReviewers) > 0 || len(t.InvalidReviewers) > 0 || len(t.StaleReviewers) > 0 {
+		b.WriteString("\n### Reviewers\n\n")
+		writeReviewers(&b, "Ignored as they contributed to or reopened the PR", t.IgnoredReviewers)
+		writeReviewers(&b, "Not member of a team with approval capabilities", t.InvalidReviewers)
+		writeReviewers(&b, "Approved a previous version of the PR", t.StaleReviewers)
+	}
+
+	if len(t.Configuration) > 0 {
+		b.WriteString("\n### Configuration\n\n")
+		for _, note := range t.Configuration {
+			fmt.Fprintf(&b, "- %s\n", note)
+		}
+	}
+	return b.String()
+}
+
+func writeRuleRow(b *strings.Builder, name string, rm RuleMatch) {
+	teams := make([]string, 0, len(rm.Teams))
+	for _, team := range rm.Teams {
+		teams = append(teams, fmt.Sprintf("`%s` (%d/%d)", team.Handle, team.Approvals, team.Required))
+	}
+	if rm.MinimumApprovals > 0 {
+		teams = append(teams, fmt.Sprintf("%d/%d distinct approvals", len(rm.Approvers), rm.MinimumApprovals))
+	}
+	fulfilled := "-"
+	if rm.Matched {
+		fulfilled = yesNo(rm.Fulfilled)
+	}
+	fmt.Fprintf(b, "| %s | %s | %s | %s | %s | %s |\n",
+		name,
+		yesNo(rm.Matched),
+		markdownCell(rm.Reason),
+		strings.Join(teams, "<br>"),
+		strings.Join(mentions(rm.Approvers), " "),
+		fulfilled,
+	)
+}
+
+func writeReviewers(b *strings.Builder, title string, reviewers []string) {
+	if len(reviewers) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "- %s: %s\n", title, strings.Join(mentions(reviewers), " "))
+}
+
+func mentions(logins []string) []string {
+	r := make([]string, 0, len(logins))
+	for _, login := range logins {
+		r = append(r, "@"+login)
+	}
+	return r
+}
+
+// markdownCell escapes the characters which would otherwise break a row of a markdown table.
+func markdownCell(v string) string {
+	v = strings.ReplaceAll(v, "|", `\|`)
+	return strings.ReplaceAll(v, "\n", "<br>")
+}
+
+func yesNo(v bool) string {
+	if v {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,68 @@
+package approval
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResult_Summary(t *testing.T) {
+	tests := map[string]struct {
+		result   Result
+		expected string
+	}{
+		"pending with ignored reviewers": {
+			result: Result{
+				status:      StatusEventStatusPending,
+				description: "Needs approval from:\ncab-foo",
+				trace: Trace{
+					TargetBranch:  "master",
+					Configuration: []string{"Configuration read from the default branch."},
+					Rules: []RuleMatch{
+						{Rule: 0, Matched: false, Reason: "body doesn't match \"a|b\""},
+						{
+							Rule:      1,
+							Matched:   true,
+							Reason:    "title matches \"^feat\"",
+							Teams:     []TeamApproval{{Handle: "cab-foo", Approvals: 0, Required: 1}},
+							Fulfilled: false,
+						},
+					},
+					IgnoredReviewers: []string{"alice"},
+				},
+			},
+			expected: "**Status:** pending\n\n" +
+				"Needs approval from:\ncab-foo\n" +
+				"\n### Rules for `master`\n\n" +
+				"| Rule | Applies | Reason | Approving teams | Approvers | Fulfilled |\n" +
+				"|------|---------|--------|-----------------|-----------|-----------|\n" +
+				"| 1 | no | body doesn't match \"a\\|b\" |  |  | - |\n" +
+				"| 2 | yes | title matches \"^feat\" | `cab-foo` (0/1) |  | no |\n" +
+				"\n### Reviewers\n\n" +
+				"- Ignored as they contributed to or reopened the PR: @alice\n" +
+				"\n### Configuration\n\n" +
+				"- Configuration read from the default branch.\n",
+		},
+		"invalid configuration": {
+			result: Result{
+				status:      StatusEventStatusError,
+				description: "Invalid config:\nrules[0].regex: invalid",
+				source:      `Configuration read from base branch "release".`,
+				trace: Trace{
+					TargetBranch:        "release",
+					ConfigurationErrors: []string{"rules[0].regex: invalid"},
+				},
+			},
+			expected: "**Status:** error\n\n" +
+				"Invalid config:\nrules[0].regex: invalid\nConfiguration read from base branch \"release\".\n" +
+				"\n### Configuration errors\n\n" +
+				"- rules[0].regex: invalid\n",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tt.expected, tt.result.Summary())
+		})
+	}
+}
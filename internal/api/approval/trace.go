@@ -0,0 +1,94 @@
+package approval
+
+import (
+	"context"
+	"sort"
+
+	"github.com/form3tech-oss/github-team-approver/internal/api/configuration"
+)
+
+// Trace explains how the approval status of a PR was computed, rule by rule.
+type Trace struct {
+	TargetBranch string `json:"target_branch"`
+	// Configuration notes where the configuration was read from and how it was resolved.
+	Configuration []string `json:"configuration,omitempty"`
+	// ConfigurationErrors lists the problems found in the configuration, if it is invalid.
+	ConfigurationErrors []string `json:"configuration_errors,omitempty"`
+	// Rules lists the rules for the target branch, in the order they were evaluated.
+	Rules []RuleMatch `json:"rules"`
+	// ConfigurationGuard is the built-in rule guarding changes to the configuration file, if the PR changes it.
+	ConfigurationGuard *RuleMatch `json:"configuration_guard,omitempty"`
+	InvalidTeamHandles []string   `json:"invalid_team_handles,omitempty"`
+	IgnoredReviewers   []string   `json:"ignored_reviewers,omitempty"`
+	InvalidReviewers   []string   `json:"invalid_reviewers,omitempty"`
+	StaleReviewers     []string   `json:"stale_reviewers,omitempty"`
+}
+
+// ConditionMatch records whether the PR meets one of the conditions of a rule, and why.
+type ConditionMatch struct {
+	// Condition is the name of the field of the rule setting the condition (e.g. "regex" or "when").
+	Condition string `json:"condition"`
+	Matched   bool   `json:"matched"`
+	Reason    string `json:"reason"`
+}
+
+// TeamApproval records the approvals given on behalf of one of the approving teams or users of a rule.
+type TeamApproval struct {
+	Handle    string `json:"handle"`
+	Approvals int    `json:"approvals"`
+	Required  int    `json:"required"`
+}
+
+// record adds the approvals recorded for a rule derived from the one matched (e.g. from CODEOWNERS) to the match.
+func (rm *RuleMatch) record(mr MatchedRule) {
+	for _, handle := range mr.ConfigRule.ApprovingHandles() {
+		rm.Teams = append(rm.Teams, TeamApproval{
+			Handle:    handle,
+			Approvals: mr.Approvals[handle],
+			Required:  mr.ConfigRule.RequiredApprovalsForTeam(handle),
+		})
+	}
+	for approver := range mr.Approvers {
+		rm.Approvers = appendIfMissing(rm.Approvers, approver)
+	}
+	sort.Strings(rm.Approvers)
+	rm.MinimumApprovals = mr.ConfigRule.MinimumApprovals
+	rm.Fulfilled = rm.Fulfilled && mr.Fulfilled()
+}
+
+// Explain computes the approval status of the PR, recording in the trace of the result which conditions of each rule
+// the PR meets.
+func (a *Approval) Explain(ctx context.Context, pr *PR) (*Result, error) {
+	a.explain = true
+	return a.ComputeApprovalStatus(ctx, pr)
+}
+
+// conditionMatches evaluates each condition of the rule on its own, as opposed to stopping at the first one the PR
+// doesn't meet. The shorthand fields are named after the matchers evaluating them, and When is evaluated as a whole.
+func (a *Approval) conditionMatches(ctx context.Context, rule configuration.Rule, pr *PR) ([]ConditionMatch, error) {
+	var matches []ConditionMatch
+	add := func(name string, m Matcher) error {
+		matched, reason, err := m.Match(ctx, pr)
+		if err != nil {
+			return err
+		}
+		matches = append(matches, ConditionMatch{Condition: name, Matched: matched, Reason: reason})
+		return nil
+	}
+
+	shorthand := rule.Condition()
+	shorthand.AllOf = nil
+	for _, registered := range registeredMatchers() {
+		if leaf := registered.factory(a, shorthand); leaf != nil {
+			if err := add(registered.name, leaf); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if rule.When != nil {
+		if err := add("when", a.NewMatcher(*rule.When)); err != nil {
+			return nil, err
+		}
+	}
+	return matches, nil
+}
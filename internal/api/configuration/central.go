@@ -0,0 +1,54 @@
+package configuration
+
+import "fmt"
+
+// Resolve combines the configuration of a repository with the central configuration of its organisation, either of
+// which may be nil. The rule sets of the result are, in order:
+//
+//  1. the rule sets of the central configuration, if the repository has no configuration or extends it;
+//  2. the rule sets of the repository, including any it declares as mandatory;
+//  3. the mandatory rule sets of the central configuration.
+//
+// As every matching rule must be fulfilled, and the rules of mandatory rule sets are marked as such so that forcible
+// approvals don't override them, a repository can add to the central configuration but never relax its mandatory rule
+// sets. Resolve returns nil if neither configuration exists.
+func Resolve(central, repo *Configuration) *Configuration {
+	if central == nil && repo == nil {
+		return nil
+	}
+
+	resolved := &Configuration{}
+	add := func(rules []PullRequestApprovalRule, from string) {
+		if len(rules) == 0 {
+			return
+		}
+		resolved.PullRequestApprovalRules = append(resolved.PullRequestApprovalRules, rules...)
+		resolved.Resolution = append(resolved.Resolution, fmt.Sprintf("%d %s", len(rules), from))
+	}
+
+	if central != nil && (repo == nil || repo.ExtendsCentralConfiguration) {
+		add(central.PullRequestApprovalRules, "default rule set(s) of the central configuration")
+	}
+	if repo != nil {
+		add(repo.PullRequestApprovalRules, "rule set(s) of the repository configuration")
+		add(mandatory(repo.MandatoryPullRequestApprovalRules), "mandatory rule set(s) of the repository configuration")
+	}
+	if central != nil {
+		add(mandatory(central.MandatoryPullRequestApprovalRules), "mandatory rule set(s) of the central configuration")
+	}
+	return resolved
+}
+
+// mandatory returns a copy of the given rule sets with every rule marked as mandatory.
+func mandatory(ruleSets []PullRequestApprovalRule) []PullRequestApprovalRule {
+	marked := make([]PullRequestApprovalRule, len(ruleSets))
+	for i, ruleSet := range ruleSets {
+		marked[i] = ruleSet
+		marked[i].Rules = make([]Rule, len(ruleSet.Rules))
+		for j, rule := range ruleSet.Rules {
+			rule.Mandatory = true
+			marked[i].Rules[j] = rule
+		}
+	}
+	return marked
+}
@@ -0,0 +1,92 @@
+package configuration
+
+import (
+	"testing"
+
+	commons "github.com/form3tech-oss/github-team-approver-commons/v2/pkg/configuration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve(t *testing.T) {
+	ruleSet := func(branch string) PullRequestApprovalRule {
+		return PullRequestApprovalRule{TargetBranches: []string{branch}}
+	}
+	central := &Configuration{
+		PullRequestApprovalRules:          []PullRequestApprovalRule{ruleSet("central-default")},
+		MandatoryPullRequestApprovalRules: []PullRequestApprovalRule{ruleSet("central-mandatory")},
+	}
+
+	tests := map[string]struct {
+		central  *Configuration
+		repo     *Configuration
+		expected []string
+	}{
+		"no central configuration": {
+			repo:     &Configuration{PullRequestApprovalRules: []PullRequestApprovalRule{ruleSet("repo")}},
+			expected: []string{"repo"},
+		},
+		"no repository configuration": {
+			central:  central,
+			expected: []string{"central-default", "central-mandatory"},
+		},
+		"repository configuration": {
+			central:  central,
+			repo:     &Configuration{PullRequestApprovalRules: []PullRequestApprovalRule{ruleSet("repo")}},
+			expected: []string{"repo", "central-mandatory"},
+		},
+		"repository configuration extending the central configuration": {
+			central: central,
+			repo: &Configuration{
+				ExtendsCentralConfiguration: true,
+				PullRequestApprovalRules:    []PullRequestApprovalRule{ruleSet("repo")},
+			},
+			expected: []string{"central-default", "repo", "central-mandatory"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			resolved := Resolve(tt.central, tt.repo)
+
+			var branches []string
+			for _, r := range resolved.PullRequestApprovalRules {
+				branches = append(branches, r.TargetBranches...)
+			}
+			require.Equal(t, tt.expected, branches)
+			require.Len(t, resolved.Resolution, len(tt.expected))
+		})
+	}
+}
+
+func TestResolveWithoutAnyConfiguration(t *testing.T) {
+	require.Nil(t, Resolve(nil, nil))
+}
+
+func TestResolveMarksRulesOfMandatoryRuleSets(t *testing.T) {
+	ruleSet := func(regex string) PullRequestApprovalRule {
+		return PullRequestApprovalRule{Rules: []Rule{{Rule: commons.Rule{Regex: regex}}}}
+	}
+	central := &Configuration{
+		PullRequestApprovalRules:          []PullRequestApprovalRule{ruleSet("central-default")},
+		MandatoryPullRequestApprovalRules: []PullRequestApprovalRule{ruleSet("central-mandatory")},
+	}
+	repo := &Configuration{
+		ExtendsCentralConfiguration:       true,
+		PullRequestApprovalRules:          []PullRequestApprovalRule{ruleSet("repo")},
+		MandatoryPullRequestApprovalRules: []PullRequestApprovalRule{ruleSet("repo-mandatory")},
+	}
+
+	resolved := Resolve(central, repo)
+
+	mandatory := map[string]bool{}
+	for _, r := range resolved.PullRequestApprovalRules {
+		mandatory[r.Rules[0].Regex] = r.Rules[0].Mandatory
+	}
+	require.Equal(t, map[string]bool{
+		"central-default":   false,
+		"repo":              false,
+		"repo-mandatory":    true,
+		"central-mandatory": true,
+	}, mandatory)
+	require.False(t, central.MandatoryPullRequestApprovalRules[0].Rules[0].Mandatory)
+}
@@ -0,0 +1,277 @@
+// Package configuration extends the configuration format shared through github-team-approver-commons with options
+// that are only understood by github-team-approver itself.
+package configuration
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/form3tech-oss/github-team-approver/internal/api/glob"
+
+	commons "github.com/form3tech-oss/github-team-approver-commons/v2/pkg/configuration"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	ConfigurationFilePath = commons.ConfigurationFilePath
+	// CodeownersFilePath is the path of the CODEOWNERS file read by rules with "codeowners" set.
+	CodeownersFilePath = ".github/CODEOWNERS"
+
+	ApprovalModeRequireAny = commons.ApprovalModeRequireAny
+	ApprovalModeRequireAll = commons.ApprovalModeRequireAll
+
+	// UserHandlePrefix distinguishes the handles of individual approvers from team handles.
+	UserHandlePrefix = "@"
+
+	// StaleApprovalsKeep counts approvals regardless of the commit they were given for.
+	StaleApprovalsKeep = "keep"
+	// StaleApprovalsDismiss only counts approvals given for the head commit of the PR.
+	StaleApprovalsDismiss = "dismiss"
+	// StaleApprovalsDismissIfDiffChanged also counts approvals given for previous commits, as long as the changes
+	// introduced by the PR are the same as in the head commit (e.g. after a rebase).
+	StaleApprovalsDismissIfDiffChanged = "dismiss_if_diff_changed"
+)
+
+type Alert = commons.Alert
+
+type Configuration struct {
+	// ExtendsCentralConfiguration makes the configuration of a repository apply the default rule sets of the central
+	// configuration as well as its own.
+	ExtendsCentralConfiguration bool                      `yaml:"extends_central_configuration,omitempty"`
+	PullRequestApprovalRules    []PullRequestApprovalRule `yaml:"pull_request_approval_rules"`
+	// MandatoryPullRequestApprovalRules are the rule sets of the central configuration which apply to every repository,
+	// whatever its own configuration.
+	MandatoryPullRequestApprovalRules []PullRequestApprovalRule `yaml:"mandatory_pull_request_approval_rules,omitempty"`
+	// Resolution records, in order, where the rule sets of a resolved configuration come from.
+	Resolution []string `yaml:"-"`
+}
+
+type PullRequestApprovalRule struct {
+	TargetBranches []string `yaml:"target_branches"`
+	Rules          []Rule   `yaml:"rules"`
+	Alerts         []Alert  `yaml:"alerts"`
+}
+
+// MatchTargetBranch returns the entry of TargetBranches which selects the given branch, if any. Entries starting with
+// "^" are regular expressions, entries containing any of "*", "?", "[" or "{" are glob patterns, and any other entry
+// must be equal to the branch. Every branch is selected (by an empty entry) when TargetBranches is empty.
+func (r PullRequestApprovalRule) MatchTargetBranch(branch string) (string, bool, error) {
+	if len(r.TargetBranches) == 0 {
+		return "", true, nil
+	}
+
+	for _, pattern := range r.TargetBranches {
+		var (
+			matched bool
+			err     error
+		)
+		switch {
+		case strings.HasPrefix(pattern, "^"):
+			matched, err = regexp.MatchString(pattern, branch)
+		case glob.IsPattern(pattern):
+			matched, err = glob.Match(pattern, branch)
+		default:
+			matched = pattern == branch
+		}
+		if err != nil {
+			return "", false, fmt.Errorf("invalid target branch pattern %q: %w", pattern, err)
+		}
+		if matched {
+			return pattern, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+type Rule struct {
+	commons.Rule `yaml:",inline"`
+
+	// TitleRegex, HeadBranchRegex and CommitMessageRegex are conditions on the PR, with the same meaning as in
+	// Condition.
+	TitleRegex         string `yaml:"title_regex,omitempty"`
+	HeadBranchRegex    string `yaml:"head_branch_regex,omitempty"`
+	CommitMessageRegex string `yaml:"commit_message_regex,omitempty"`
+	// When holds the conditions the PR must meet for the rule to apply, in addition to the ones set directly on the
+	// rule.
+	When *Condition `yaml:"when,omitempty"`
+	// ApprovingUserHandles lists the logins of individual users who may approve alongside the approving teams.
+	ApprovingUserHandles []string `yaml:"approving_user_handles,omitempty"`
+	// IncludeChildTeams also counts approvals from members of the child teams of each approving team, recursively.
+	IncludeChildTeams bool `yaml:"include_child_teams,omitempty"`
+	// ExcludeDirectories lists directories or glob patterns whose changes are disregarded when matching Directories.
+	ExcludeDirectories []string `yaml:"exclude_directories,omitempty"`
+	// MinimumApprovals is the number of distinct approvals required across all approving teams.
+	MinimumApprovals int `yaml:"minimum_approvals,omitempty"`
+	// TeamMinimumApprovals is the number of approvals required from specific approving teams, keyed by team handle.
+	TeamMinimumApprovals map[string]int `yaml:"team_minimum_approvals,omitempty"`
+	// StaleApprovals controls whether approvals given before the latest push are counted.
+	StaleApprovals string `yaml:"stale_approvals,omitempty"`
+	// BlockOnChangesRequested keeps the rule unfulfilled while a member of an approving team has requested changes.
+	BlockOnChangesRequested bool `yaml:"block_on_changes_requested,omitempty"`
+	// Codeowners derives the approving teams from the CODEOWNERS file of the target branch, requiring an approval from
+	// an owner of every changed path instead of from ApprovingTeamHandles.
+	Codeowners bool `yaml:"codeowners,omitempty"`
+	// Mandatory is set by Resolve on the rules of mandatory rule sets, which forcible approvals don't override.
+	Mandatory bool `yaml:"-"`
+}
+
+// Condition is a node in a tree of conditions over a PR. All conditions set on a node must be met.
+type Condition struct {
+	// AllOf is met when every one of the nested conditions is met.
+	AllOf []Condition `yaml:"all_of,omitempty"`
+	// AnyOf is met when at least one of the nested conditions is met.
+	AnyOf []Condition `yaml:"any_of,omitempty"`
+	// Not is met when the nested condition is not met.
+	Not *Condition `yaml:"not,omitempty"`
+	// Regex is met when the body of the PR matches the regular expression (ignoring case).
+	Regex string `yaml:"regex,omitempty"`
+	// RegexLabel is met when any label of the PR matches the regular expression (ignoring case).
+	RegexLabel string `yaml:"regex_label,omitempty"`
+	// TitleRegex is met when the title of the PR matches the regular expression (ignoring case).
+	TitleRegex string `yaml:"title_regex,omitempty"`
+	// HeadBranchRegex is met when the name of the branch the PR is made from matches the regular expression (ignoring
+	// case).
+	HeadBranchRegex string `yaml:"head_branch_regex,omitempty"`
+	// CommitMessageRegex is met when the message of any commit of the PR matches the regular expression (ignoring
+	// case).
+	CommitMessageRegex string `yaml:"commit_message_regex,omitempty"`
+	// Directories is met when the PR changes files in any of the directories, other than in ExcludeDirectories.
+	Directories []string `yaml:"directories,omitempty"`
+	// ExcludeDirectories is met when the PR changes files outside the directories, within Directories if set.
+	ExcludeDirectories []string `yaml:"exclude_directories,omitempty"`
+	// Extensions holds the raw values of the conditions of the kinds registered with RegisterConditionKind, keyed by
+	// the key setting them. Any other key is reported when the configuration is validated.
+	Extensions map[string]interface{} `yaml:",inline"`
+}
+
+// IsEmpty returns true if no conditions are set.
+func (c Condition) IsEmpty() bool {
+	return c.AllOf == nil && c.AnyOf == nil && c.Not == nil &&
+		c.Regex == "" && c.RegexLabel == "" && c.TitleRegex == "" && c.HeadBranchRegex == "" && c.CommitMessageRegex == "" &&
+		len(c.Directories) == 0 && len(c.ExcludeDirectories) == 0 && len(c.Extensions) == 0
+}
+
+// Decode decodes the raw value set for the given key into out, as it would have been decoded from the configuration
+// file. It returns false if the key isn't set.
+func (c Condition) Decode(key string, out interface{}) (bool, error) {
+	value, ok := c.Extensions[key]
+	if !ok {
+		return false, nil
+	}
+	b, err := yaml.Marshal(value)
+	if err != nil {
+		return true, err
+	}
+	return true, yaml.UnmarshalStrict(b, out)
+}
+
+// ConditionKind is a kind of condition set under its own key, in addition to the fields of Condition.
+type ConditionKind struct {
+	// Key is the key setting the condition.
+	Key string
+	// Validate checks the value set for Key on the given condition (read with Condition.Decode), which is only called
+	// when the key is set.
+	Validate func(c Condition) error
+}
+
+var (
+	conditionKindsMu sync.RWMutex
+	conditionKinds   = map[string]ConditionKind{}
+)
+
+// RegisterConditionKind adds a kind of condition to the ones the conditions of rules can set.
+func RegisterConditionKind(kind ConditionKind) {
+	conditionKindsMu.Lock()
+	defer conditionKindsMu.Unlock()
+
+	if _, ok := conditionKinds[kind.Key]; ok {
+		panic(fmt.Sprintf("condition kind %q is already registered", kind.Key))
+	}
+	conditionKinds[kind.Key] = kind
+}
+
+func registeredConditionKind(key string) (ConditionKind, bool) {
+	conditionKindsMu.RLock()
+	defer conditionKindsMu.RUnlock()
+	kind, ok := conditionKinds[key]
+	return kind, ok
+}
+
+// Condition returns the conditions the PR must meet for the rule to apply, combining the shorthand fields of the rule
+// with When.
+func (r Rule) Condition() Condition {
+	c := Condition{
+		Regex:              r.Regex,
+		RegexLabel:         r.RegexLabel,
+		TitleRegex:         r.TitleRegex,
+		HeadBranchRegex:    r.HeadBranchRegex,
+		CommitMessageRegex: r.CommitMessageRegex,
+		Directories:        r.Directories,
+		ExcludeDirectories: r.ExcludeDirectories,
+	}
+	if r.When != nil {
+		c.AllOf = []Condition{*r.When}
+	}
+	return c
+}
+
+// ApprovingHandles returns the handles of the approving teams, followed by the logins of the approving users
+// prefixed with UserHandlePrefix.
+func (r Rule) ApprovingHandles() []string {
+	handles := make([]string, 0, len(r.ApprovingTeamHandles)+len(r.ApprovingUserHandles))
+	handles = append(handles, r.ApprovingTeamHandles...)
+	for _, login := range r.ApprovingUserHandles {
+		handles = append(handles, UserHandlePrefix+strings.TrimPrefix(login, UserHandlePrefix))
+	}
+	return handles
+}
+
+// RequiredApprovalsForTeam returns the number of approvals the team identified by the given handle must give.
+func (r Rule) RequiredApprovalsForTeam(handle string) int {
+	if n := r.TeamMinimumApprovals[handle]; n > 1 {
+		return n
+	}
+	return 1
+}
+
+// ReadConfiguration reads and validates a configuration file. Unknown fields are rejected. Any problem with the
+// contents of the file is reported as a ValidationError.
+func ReadConfiguration(r io.Reader) (*Configuration, error) {
+	var cfg Configuration
+	d := yaml.NewDecoder(r)
+	d.SetStrict(true)
+	if err := d.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("error reading configuration: %w", newReadError(err))
+	}
+	cfg.setDefaults()
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("error reading configuration: %w", err)
+	}
+	return &cfg, nil
+}
+
+// setDefaults fills in the options left unset. Rules which don't set approval_mode require an approval from every
+// approving team, as they would otherwise never be fulfilled.
+func (c *Configuration) setDefaults() {
+	for _, ruleSets := range [][]PullRequestApprovalRule{c.PullRequestApprovalRules, c.MandatoryPullRequestApprovalRules} {
+		for i := range ruleSets {
+			for j := range ruleSets[i].Rules {
+				if ruleSets[i].Rules[j].ApprovalMode == "" {
+					ruleSets[i].Rules[j].ApprovalMode = ApprovalModeRequireAll
+				}
+			}
+		}
+	}
+}
+
+func (c *Configuration) Write(w io.Writer) error {
+	b, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("error writing configuration: %w", err)
+	}
+	_, err = w.Write(b)
+	return err
+}
@@ -0,0 +1,243 @@
+package configuration
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadConfiguration(t *testing.T) {
+	cfg, err := ReadConfiguration(strings.NewReader(`
+pull_request_approval_rules:
+- target_branches:
+  - master
+  rules:
+  - regex: "- \\[x\\] Yes"
+    approval_mode: require_all
+    approving_team_handles:
+    - platform-security
+    - cab
+    approving_user_handles:
+    - release-manager
+    minimum_approvals: 3
+    team_minimum_approvals:
+      platform-security: 2
+`))
+	require.NoError(t, err)
+	require.Len(t, cfg.PullRequestApprovalRules, 1)
+	require.Len(t, cfg.PullRequestApprovalRules[0].Rules, 1)
+
+	rule := cfg.PullRequestApprovalRules[0].Rules[0]
+	require.Equal(t, `- \[x\] Yes`, rule.Regex)
+	require.Equal(t, []string{"platform-security", "cab"}, rule.ApprovingTeamHandles)
+	require.Equal(t, []string{"release-manager"}, rule.ApprovingUserHandles)
+	require.Equal(t, []string{"platform-security", "cab", "@release-manager"}, rule.ApprovingHandles())
+	require.Equal(t, 3, rule.MinimumApprovals)
+	require.Equal(t, 2, rule.RequiredApprovalsForTeam("platform-security"))
+	require.Equal(t, 1, rule.RequiredApprovalsForTeam("cab"))
+}
+
+func TestReadConfigurationDefaultsApprovalModeToRequireAll(t *testing.T) {
+	cfg, err := ReadConfiguration(strings.NewReader(`
+pull_request_approval_rules:
+- rules:
+  - regex: "- \\[x\\] Yes"
+    approving_team_handles:
+    - cab
+mandatory_pull_request_approval_rules:
+- rules:
+  - regex: "- \\[x\\] Yes"
+    approval_mode: require_any
+    approving_team_handles:
+    - platform-security
+  - regex: "- \\[x\\] Yes"
+    approving_team_handles:
+    - platform-security
+`))
+	require.NoError(t, err)
+	require.Equal(t, ApprovalModeRequireAll, cfg.PullRequestApprovalRules[0].Rules[0].ApprovalMode)
+	require.Equal(t, ApprovalModeRequireAny, cfg.MandatoryPullRequestApprovalRules[0].Rules[0].ApprovalMode)
+	require.Equal(t, ApprovalModeRequireAll, cfg.MandatoryPullRequestApprovalRules[0].Rules[1].ApprovalMode)
+}
+
+func TestRule_Condition(t *testing.T) {
+	cfg, err := ReadConfiguration(strings.NewReader(`
+pull_request_approval_rules:
+- rules:
+  - regex: "- \\[x\\] Yes"
+    when:
+      any_of:
+      - regex_label: "^security$"
+      - directories:
+        - "deploy/**"
+        exclude_directories:
+        - "deploy/docs/**"
+      not:
+        regex_label: "^skip-security$"
+    approving_team_handles:
+    - security
+  - approving_team_handles:
+    - cab
+`))
+	require.NoError(t, err)
+	require.Len(t, cfg.PullRequestApprovalRules[0].Rules, 2)
+
+	require.Equal(t, Condition{
+		Regex: `- \[x\] Yes`,
+		AllOf: []Condition{
+			{
+				AnyOf: []Condition{
+					{RegexLabel: "^security$"},
+					{Directories: []string{"deploy/**"}, ExcludeDirectories: []string{"deploy/docs/**"}},
+				},
+				Not: &Condition{RegexLabel: "^skip-security$"},
+			},
+		},
+	}, cfg.PullRequestApprovalRules[0].Rules[0].Condition())
+	require.False(t, cfg.PullRequestApprovalRules[0].Rules[0].Condition().IsEmpty())
+	require.True(t, cfg.PullRequestApprovalRules[0].Rules[1].Condition().IsEmpty())
+}
+
+func TestPullRequestApprovalRule_MatchTargetBranch(t *testing.T) {
+	tests := map[string]struct {
+		targetBranches []string
+		branch         string
+		pattern        string
+		matched        bool
+	}{
+		"no target branches": {
+			targetBranches: nil,
+			branch:         "master",
+			pattern:        "",
+			matched:        true,
+		},
+		"exact name": {
+			targetBranches: []string{"develop", "master"},
+			branch:         "master",
+			pattern:        "master",
+			matched:        true,
+		},
+		"exact name mismatch": {
+			targetBranches: []string{"develop", "master"},
+			branch:         "main",
+			matched:        false,
+		},
+		"glob": {
+			targetBranches: []string{"master", "release/**"},
+			branch:         "release/1.2",
+			pattern:        "release/**",
+			matched:        true,
+		},
+		"glob mismatch": {
+			targetBranches: []string{"release/*"},
+			branch:         "release-1.2",
+			matched:        false,
+		},
+		"regex": {
+			targetBranches: []string{`^hotfix-\d+$`},
+			branch:         "hotfix-42",
+			pattern:        `^hotfix-\d+$`,
+			matched:        true,
+		},
+		"regex mismatch": {
+			targetBranches: []string{`^hotfix-\d+$`},
+			branch:         "hotfix-42a",
+			matched:        false,
+		},
+		"first matching entry": {
+			targetBranches: []string{"release/*", `^release/`},
+			branch:         "release/1.2",
+			pattern:        "release/*",
+			matched:        true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			pattern, matched, err := PullRequestApprovalRule{TargetBranches: tt.targetBranches}.MatchTargetBranch(tt.branch)
+
+			require.NoError(t, err)
+			require.Equal(t, tt.matched, matched)
+			require.Equal(t, tt.pattern, pattern)
+		})
+	}
+}
+
+func TestPullRequestApprovalRule_MatchTargetBranchWithInvalidPattern(t *testing.T) {
+	_, _, err := PullRequestApprovalRule{TargetBranches: []string{"^hotfix-(\\d+$"}}.MatchTargetBranch("hotfix-1")
+	require.Error(t, err)
+}
+
+func TestConfiguration_Write(t *testing.T) {
+	cfg, err := ReadConfiguration(strings.NewReader(`
+pull_request_approval_rules:
+- rules:
+  - approving_team_handles:
+    - cab
+    minimum_approvals: 2
+`))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, cfg.Write(&buf))
+
+	written, err := ReadConfiguration(&buf)
+	require.NoError(t, err)
+	require.Len(t, written.PullRequestApprovalRules, 1)
+	require.Len(t, written.PullRequestApprovalRules[0].Rules, 1)
+
+	rule := written.PullRequestApprovalRules[0].Rules[0]
+	require.Equal(t, []string{"cab"}, rule.ApprovingTeamHandles)
+	require.Equal(t, 2, rule.MinimumApprovals)
+}
+
+func TestRegisterConditionKind(t *testing.T) {
+	RegisterConditionKind(ConditionKind{
+		Key: "changed_files_over",
+		Validate: func(c Condition) error {
+			var n int
+			if _, err := c.Decode("changed_files_over", &n); err != nil || n < 0 {
+				return errors.New("must be a number of files")
+			}
+			return nil
+		},
+	})
+	require.Panics(t, func() {
+		RegisterConditionKind(ConditionKind{Key: "changed_files_over"})
+	})
+
+	cfg, err := ReadConfiguration(strings.NewReader(`
+pull_request_approval_rules:
+- rules:
+  - when:
+      changed_files_over: 50
+    approving_team_handles:
+    - cab
+`))
+	require.NoError(t, err)
+	when := cfg.PullRequestApprovalRules[0].Rules[0].When
+	require.False(t, when.IsEmpty())
+	var n int
+	ok, err := when.Decode("changed_files_over", &n)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, 50, n)
+	ok, err = when.Decode("changed_files_under", &n)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, err = ReadConfiguration(strings.NewReader(`
+pull_request_approval_rules:
+- rules:
+  - when:
+      changed_files_over: many
+    approving_team_handles:
+    - cab
+`))
+	var invalid *ValidationError
+	require.True(t, errors.As(err, &invalid), "expected a validation error, got %v", err)
+	require.Equal(t, []string{"pull_request_approval_rules[0].rules[0].when.changed_files_over: must be a number of files"}, invalid.Problems)
+}
@@ -0,0 +1,102 @@
+package configuration
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// SourceDefaultBranch reads the configuration file from the default branch of the repository.
+	SourceDefaultBranch = "default_branch"
+	// SourceBaseBranch reads the configuration file from the branch the PR is made against.
+	SourceBaseBranch = "base_branch"
+	// SourceRefPrefix prefixes the ref the configuration file is pinned to (e.g. "ref:policy/v2").
+	SourceRefPrefix = "ref:"
+)
+
+// Source is where the configuration file of a repository is read from. The zero value reads it from the default branch.
+type Source struct {
+	baseBranch        bool
+	ref               string
+	centralRepository string
+	// path is the local path the configuration file was read from, when it isn't read from GitHub.
+	path string
+}
+
+// ParseSource parses one of "default_branch", "base_branch" or "ref:<ref>". An empty value means "default_branch".
+func ParseSource(v string) (Source, error) {
+	switch {
+	case v == "" || v == SourceDefaultBranch:
+		return Source{}, nil
+	case v == SourceBaseBranch:
+		return Source{baseBranch: true}, nil
+	case strings.HasPrefix(v, SourceRefPrefix) && len(v) > len(SourceRefPrefix):
+		return Source{ref: strings.TrimPrefix(v, SourceRefPrefix)}, nil
+	default:
+		return Source{}, fmt.Errorf("invalid configuration source %q: must be one of %q, %q or %q followed by a ref", v, SourceDefaultBranch, SourceBaseBranch, SourceRefPrefix)
+	}
+}
+
+// FileSource returns the source of a configuration file read from the given local path rather than from GitHub.
+func FileSource(path string) Source {
+	return Source{path: path}
+}
+
+// Ref returns the ref to read the configuration file at for a PR made against the given branch. An empty ref stands
+// for the default branch of the repository.
+func (s Source) Ref(baseBranch string) string {
+	if s.baseBranch {
+		return baseBranch
+	}
+	return s.ref
+}
+
+// Describe returns a human-readable note on where the configuration file was read from for a PR made against the given
+// branch.
+func (s Source) Describe(baseBranch string) string {
+	switch {
+	case s.baseBranch:
+		return fmt.Sprintf("Configuration read from base branch %q.", baseBranch)
+	case s.ref != "":
+		return fmt.Sprintf("Configuration read from ref %q.", s.ref)
+	case s.path != "":
+		return fmt.Sprintf("Configuration read from %s.", s.path)
+	default:
+		return "Configuration read from the default branch."
+	}
+}
+
+// Note returns the note on where the configuration file was read from which ends status descriptions. It is short
+// for the default branch, so as to leave room for the rest of the description, Describe giving the full note.
+func (s Source) Note(baseBranch string) string {
+	if !s.baseBranch && s.ref == "" && s.path == "" {
+		return "Config: default branch."
+	}
+	return s.Describe(baseBranch)
+}
+
+// WithCentralRepository returns a copy of the source which resolves the configuration of each repository against the
+// one of the given repository of the same organisation (e.g. ".github"). An empty name disables the central
+// configuration.
+func (s Source) WithCentralRepository(name string) Source {
+	s.centralRepository = name
+	return s
+}
+
+// CentralRepository returns the name of the repository holding the central configuration, if any.
+func (s Source) CentralRepository() string {
+	return s.centralRepository
+}
+
+func (s Source) String() string {
+	switch {
+	case s.baseBranch:
+		return SourceBaseBranch
+	case s.ref != "":
+		return SourceRefPrefix + s.ref
+	case s.path != "":
+		return s.path
+	default:
+		return SourceDefaultBranch
+	}
+}
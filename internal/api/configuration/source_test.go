@@ -0,0 +1,70 @@
+package configuration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSource(t *testing.T) {
+	tests := map[string]struct {
+		value    string
+		ref      string
+		describe string
+		note     string
+		str      string
+	}{
+		"empty": {
+			value:    "",
+			describe: "Configuration read from the default branch.",
+			note:     "Config: default branch.",
+			str:      SourceDefaultBranch,
+		},
+		"default branch": {
+			value:    "default_branch",
+			describe: "Configuration read from the default branch.",
+			note:     "Config: default branch.",
+			str:      SourceDefaultBranch,
+		},
+		"base branch": {
+			value:    "base_branch",
+			ref:      "release/1.2",
+			describe: `Configuration read from base branch "release/1.2".`,
+			note:     `Configuration read from base branch "release/1.2".`,
+			str:      SourceBaseBranch,
+		},
+		"pinned ref": {
+			value:    "ref:policy/v2",
+			ref:      "policy/v2",
+			describe: `Configuration read from ref "policy/v2".`,
+			note:     `Configuration read from ref "policy/v2".`,
+			str:      "ref:policy/v2",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			source, err := ParseSource(tt.value)
+
+			require.NoError(t, err)
+			require.Equal(t, tt.ref, source.Ref("release/1.2"))
+			require.Equal(t, tt.describe, source.Describe("release/1.2"))
+			require.Equal(t, tt.note, source.Note("release/1.2"))
+			require.Equal(t, tt.str, source.String())
+		})
+	}
+}
+
+func TestFileSource(t *testing.T) {
+	source := FileSource("policy/GITHUB_TEAM_APPROVER.yaml")
+
+	require.Equal(t, "Configuration read from policy/GITHUB_TEAM_APPROVER.yaml.", source.Describe("master"))
+	require.Equal(t, "Configuration read from policy/GITHUB_TEAM_APPROVER.yaml.", source.Note("master"))
+}
+
+func TestParseSourceWithInvalidValue(t *testing.T) {
+	for _, v := range []string{"master", "ref:", "base-branch"} {
+		_, err := ParseSource(v)
+		require.Error(t, err, v)
+	}
+}
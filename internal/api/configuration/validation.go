@@ -0,0 +1,232 @@
+package configuration
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/form3tech-oss/github-team-approver/internal/api/glob"
+	"gopkg.in/yaml.v2"
+)
+
+// ValidationError lists the problems found in a configuration file, each prefixed with the path of the offending field.
+type ValidationError struct {
+	// Repository is the name of the repository the configuration file was read from, if known.
+	Repository string
+	Problems   []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid configuration: %s", strings.Join(e.Problems, "; "))
+}
+
+// newReadError turns an error from decoding a configuration file into a ValidationError, with one problem per unknown
+// field or mistyped value.
+func newReadError(err error) error {
+	if err == io.EOF {
+		return &ValidationError{Problems: []string{"the file is empty"}}
+	}
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		return &ValidationError{Problems: typeErr.Errors}
+	}
+	return &ValidationError{Problems: []string{strings.TrimPrefix(err.Error(), "yaml: ")}}
+}
+
+// Validate checks the whole configuration up front, so that mistakes are reported as such rather than when the rules
+// are evaluated. It returns a ValidationError listing every problem found, or nil.
+func (c *Configuration) Validate() error {
+	var problems []string
+	for i, ruleSet := range c.PullRequestApprovalRules {
+		problems = append(problems, ruleSet.validate(fmt.Sprintf("pull_request_approval_rules[%d]", i))...)
+	}
+	for i, ruleSet := range c.MandatoryPullRequestApprovalRules {
+		problems = append(problems, ruleSet.validate(fmt.Sprintf("mandatory_pull_request_approval_rules[%d]", i))...)
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+func (r PullRequestApprovalRule) validate(path string) []string {
+	var problems []string
+	for i, pattern := range r.TargetBranches {
+		var err error
+		switch {
+		case strings.HasPrefix(pattern, "^"):
+			_, err = regexp.Compile(pattern)
+		case glob.IsPattern(pattern):
+			_, err = glob.Compile(pattern)
+		}
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s.target_branches[%d]: %v", path, i, err))
+		}
+	}
+	for i, rule := range r.Rules {
+		problems = append(problems, rule.validate(fmt.Sprintf("%s.rules[%d]", path, i))...)
+	}
+	for i, alert := range r.Alerts {
+		problems = append(problems, validateRegex(fmt.Sprintf("%s.alerts[%d].regex", path, i), alert.Regex)...)
+	}
+	return problems
+}
+
+func (r Rule) validate(path string) []string {
+	var problems []string
+	problemf := func(format string, args ...interface{}) {
+		problems = append(problems, path+"."+fmt.Sprintf(format, args...))
+	}
+
+	// ReadConfiguration defaults an unset approval_mode to require_all, so that it is only reported as such for
+	// configurations which weren't read from a file.
+	switch r.ApprovalMode {
+	case ApprovalModeRequireAny, ApprovalModeRequireAll:
+	default:
+		problemf("approval_mode: must be one of %q or %q, got %q", ApprovalModeRequireAny, ApprovalModeRequireAll, r.ApprovalMode)
+	}
+	switch r.StaleApprovals {
+	case "", StaleApprovalsKeep, StaleApprovalsDismiss, StaleApprovalsDismissIfDiffChanged:
+	default:
+		problemf("stale_approvals: must be one of %q, %q or %q, got %q", StaleApprovalsKeep, StaleApprovalsDismiss, StaleApprovalsDismissIfDiffChanged, r.StaleApprovals)
+	}
+
+	// Rules deriving their approvers from CODEOWNERS, and rules approving PRs by themselves (e.g. an emergency label
+	// bypass), need no approving team or user.
+	if !r.Codeowners && !r.ForceApproval && len(r.ApprovingHandles()) == 0 {
+		problemf("approving_team_handles: at least one approving team or user is required")
+	}
+	for i, handle := range r.ApprovingTeamHandles {
+		if strings.TrimSpace(handle) == "" {
+			problemf("approving_team_handles[%d]: must not be empty", i)
+		}
+	}
+	for i, login := range r.ApprovingUserHandles {
+		if strings.TrimPrefix(strings.TrimSpace(login), UserHandlePrefix) == "" {
+			problemf("approving_user_handles[%d]: must not be empty", i)
+		}
+	}
+
+	if r.MinimumApprovals < 0 {
+		problemf("minimum_approvals: must not be negative, got %d", r.MinimumApprovals)
+	}
+	handles := make([]string, 0, len(r.TeamMinimumApprovals))
+	for handle := range r.TeamMinimumApprovals {
+		handles = append(handles, handle)
+	}
+	sort.Strings(handles)
+	for _, handle := range handles {
+		n := r.TeamMinimumApprovals[handle]
+		if !isMember(r.ApprovingTeamHandles, handle) {
+			problemf("team_minimum_approvals[%s]: %q is not one of approving_team_handles", handle, handle)
+		}
+		if n < 1 {
+			problemf("team_minimum_approvals[%s]: must be at least 1, got %d", handle, n)
+		}
+	}
+
+	shorthand := r.Condition()
+	shorthand.AllOf = nil
+	problems = append(problems, shorthand.validate(path)...)
+	if r.When != nil {
+		problems = append(problems, r.When.validateNested(path+".when")...)
+	}
+	return problems
+}
+
+func (c Condition) validate(path string) []string {
+	var problems []string
+	problems = append(problems, validateRegex(path+".regex", c.Regex)...)
+	problems = append(problems, validateRegex(path+".regex_label", c.RegexLabel)...)
+	problems = append(problems, validateRegex(path+".title_regex", c.TitleRegex)...)
+	problems = append(problems, validateRegex(path+".head_branch_regex", c.HeadBranchRegex)...)
+	problems = append(problems, validateRegex(path+".commit_message_regex", c.CommitMessageRegex)...)
+	problems = append(problems, validateDirectories(path+".directories", c.Directories)...)
+	problems = append(problems, validateDirectories(path+".exclude_directories", c.ExcludeDirectories)...)
+	problems = append(problems, c.validateExtensions(path)...)
+
+	if c.AllOf != nil && len(c.AllOf) == 0 {
+		problems = append(problems, path+".all_of: must list at least one condition")
+	}
+	for i, child := range c.AllOf {
+		problems = append(problems, child.validateNested(fmt.Sprintf("%s.all_of[%d]", path, i))...)
+	}
+	if c.AnyOf != nil && len(c.AnyOf) == 0 {
+		problems = append(problems, path+".any_of: must list at least one condition")
+	}
+	for i, child := range c.AnyOf {
+		problems = append(problems, child.validateNested(fmt.Sprintf("%s.any_of[%d]", path, i))...)
+	}
+	if c.Not != nil {
+		problems = append(problems, c.Not.validateNested(path+".not")...)
+	}
+	return problems
+}
+
+// validateExtensions checks the keys of the condition other than its fields, each of which must set a registered kind
+// of condition.
+func (c Condition) validateExtensions(path string) []string {
+	keys := make([]string, 0, len(c.Extensions))
+	for key := range c.Extensions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var problems []string
+	for _, key := range keys {
+		kind, ok := registeredConditionKind(key)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s.%s: unknown condition", path, key))
+			continue
+		}
+		if err := kind.Validate(c); err != nil {
+			problems = append(problems, fmt.Sprintf("%s.%s: %v", path, key, err))
+		}
+	}
+	return problems
+}
+
+// validateNested checks a condition set under when, all_of, any_of or not, which must set at least one condition, as an
+// empty one would silently be met by every PR, or by none once negated.
+func (c Condition) validateNested(path string) []string {
+	if c.IsEmpty() {
+		return []string{path + ": must set at least one condition"}
+	}
+	return c.validate(path)
+}
+
+// validateRegex checks the regular expression the same way it is compiled when matched, i.e. ignoring case.
+func validateRegex(path, regex string) []string {
+	if regex == "" {
+		return nil
+	}
+	if _, err := regexp.Compile("(?i)" + regex); err != nil {
+		return []string{fmt.Sprintf("%s: invalid regular expression %q: %v", path, regex, err)}
+	}
+	return nil
+}
+
+func validateDirectories(path string, directories []string) []string {
+	var problems []string
+	for i, directory := range directories {
+		if !glob.IsPattern(directory) {
+			continue
+		}
+		if _, err := glob.Compile(strings.Trim(directory, "/")); err != nil {
+			problems = append(problems, fmt.Sprintf("%s[%d]: %v", path, i, err))
+		}
+	}
+	return problems
+}
+
+func isMember(items []string, v string) bool {
+	for _, item := range items {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
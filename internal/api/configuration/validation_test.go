@@ -0,0 +1,175 @@
+package configuration
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	commons "github.com/form3tech-oss/github-team-approver-commons/v2/pkg/configuration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadConfigurationWithInvalidConfiguration(t *testing.T) {
+	tests := map[string]struct {
+		content  string
+		problems []string
+	}{
+		"empty file": {
+			content:  ``,
+			problems: []string{"the file is empty"},
+		},
+		"unknown field": {
+			content: `
+pull_request_approval_rules:
+- rules:
+  - regex_labels: "^security$"
+    approving_team_handles:
+    - cab
+`,
+			problems: []string{"line 4: field regex_labels not found in type configuration.Rule"},
+		},
+		"invalid regular expressions": {
+			content: `
+pull_request_approval_rules:
+- target_branches:
+  - "^release-(\\d+$"
+  rules:
+  - regex: "- [x Yes"
+    when:
+      any_of:
+      - title_regex: "("
+    approving_team_handles:
+    - cab
+  alerts:
+  - regex: "*emergency"
+`,
+			problems: []string{
+				"pull_request_approval_rules[0].target_branches[0]: error parsing regexp: missing closing ): `^release-(\\d+$`",
+				"pull_request_approval_rules[0].rules[0].regex: invalid regular expression \"- [x Yes\": error parsing regexp: missing closing ]: `[x Yes`",
+				"pull_request_approval_rules[0].rules[0].when.any_of[0].title_regex: invalid regular expression \"(\": error parsing regexp: missing closing ): `(?i)(`",
+				"pull_request_approval_rules[0].alerts[0].regex: invalid regular expression \"*emergency\": error parsing regexp: missing argument to repetition operator: `*`",
+			},
+		},
+		"invalid rule options": {
+			content: `
+pull_request_approval_rules:
+- rules:
+  - regex: "- \\[x\\] Yes"
+    approval_mode: require_most
+    stale_approvals: forget
+    team_minimum_approvals:
+      security: 2
+mandatory_pull_request_approval_rules:
+- rules:
+  - regex: "- \\[x\\] Yes"
+    approving_team_handles:
+    - cab
+    directories:
+    - "deploy/{a,b"
+`,
+			problems: []string{
+				`pull_request_approval_rules[0].rules[0].approval_mode: must be one of "require_any" or "require_all", got "require_most"`,
+				`pull_request_approval_rules[0].rules[0].stale_approvals: must be one of "keep", "dismiss" or "dismiss_if_diff_changed", got "forget"`,
+				`pull_request_approval_rules[0].rules[0].approving_team_handles: at least one approving team or user is required`,
+				`pull_request_approval_rules[0].rules[0].team_minimum_approvals[security]: "security" is not one of approving_team_handles`,
+				`mandatory_pull_request_approval_rules[0].rules[0].directories[0]: invalid pattern "deploy/{a,b": unterminated alternation`,
+			},
+		},
+		"empty conditions": {
+			content: `
+pull_request_approval_rules:
+- rules:
+  - when: {}
+    approving_team_handles:
+    - cab
+  - when:
+      all_of: []
+      any_of:
+      - {}
+      - not: {}
+    approving_team_handles:
+    - cab
+`,
+			problems: []string{
+				`pull_request_approval_rules[0].rules[0].when: must set at least one condition`,
+				`pull_request_approval_rules[0].rules[1].when.all_of: must list at least one condition`,
+				`pull_request_approval_rules[0].rules[1].when.any_of[0]: must set at least one condition`,
+				`pull_request_approval_rules[0].rules[1].when.any_of[1].not: must set at least one condition`,
+			},
+		},
+		"invalid character classes in glob patterns": {
+			content: `
+pull_request_approval_rules:
+- target_branches:
+  - "release-[z-a]"
+  rules:
+  - regex: "- \\[x\\] Yes"
+    approving_team_handles:
+    - cab
+    directories:
+    - "deploy/[]"
+    exclude_directories:
+    - "deploy/[z-a]/**"
+`,
+			problems: []string{
+				"pull_request_approval_rules[0].target_branches[0]: invalid pattern \"release-[z-a]\": error parsing regexp: invalid character class range: `z-a`",
+				"pull_request_approval_rules[0].rules[0].directories[0]: invalid pattern \"deploy/[]\": error parsing regexp: missing closing ]: `[]$`",
+				"pull_request_approval_rules[0].rules[0].exclude_directories[0]: invalid pattern \"deploy/[z-a]/**\": error parsing regexp: invalid character class range: `z-a`",
+			},
+		},
+		"unknown condition": {
+			content: `
+pull_request_approval_rules:
+- rules:
+  - when:
+      authored_by: alice
+    approving_team_handles:
+    - cab
+`,
+			problems: []string{
+				`pull_request_approval_rules[0].rules[0].when.authored_by: unknown condition`,
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ReadConfiguration(strings.NewReader(tt.content))
+
+			var invalid *ValidationError
+			require.True(t, errors.As(err, &invalid), "expected a validation error, got %v", err)
+			require.Equal(t, tt.problems, invalid.Problems)
+		})
+	}
+}
+
+func TestReadConfigurationWithCodeownersRuleWithoutApprovers(t *testing.T) {
+	_, err := ReadConfiguration(strings.NewReader(`
+pull_request_approval_rules:
+- rules:
+  - codeowners: true
+`))
+	require.NoError(t, err)
+}
+
+func TestReadConfigurationWithForceApprovalRuleWithoutApprovers(t *testing.T) {
+	_, err := ReadConfiguration(strings.NewReader(`
+pull_request_approval_rules:
+- rules:
+  - regex_label: "^emergency$"
+    force_approval: true
+`))
+	require.NoError(t, err)
+}
+
+func TestValidateRejectsRulesWithoutApprovalMode(t *testing.T) {
+	cfg := &Configuration{
+		PullRequestApprovalRules: []PullRequestApprovalRule{
+			{Rules: []Rule{{Rule: commons.Rule{Regex: "- \\[x\\] Yes", ApprovingTeamHandles: []string{"cab"}}}}},
+		},
+	}
+
+	var invalid *ValidationError
+	require.True(t, errors.As(cfg.Validate(), &invalid))
+	require.Equal(t, []string{`pull_request_approval_rules[0].rules[0].approval_mode: must be one of "require_any" or "require_all", got ""`}, invalid.Problems)
+}
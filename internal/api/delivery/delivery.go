@@ -0,0 +1,197 @@
+package delivery
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store remembers the IDs of the webhook deliveries handled, so that deliveries made again by GitHub (e.g. redelivered
+// from the settings of the app) are not handled twice.
+type Store interface {
+	// Record records the delivery, returning false if it was already recorded and hasn't expired since.
+	Record(id string) (bool, error)
+	// Forget forgets about the delivery, so that it is handled if it is made again.
+	Forget(id string) error
+}
+
+// MemoryStore remembers deliveries in memory for a fixed amount of time.
+type MemoryStore struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu sync.Mutex
+	// expiries holds when each delivery recorded is forgotten about, keyed by delivery ID.
+	expiries map[string]time.Time
+	// swept is when expired deliveries were last removed.
+	swept time.Time
+}
+
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{
+		ttl:      ttl,
+		now:      time.Now,
+		expiries: map[string]time.Time{},
+	}
+}
+
+func (s *MemoryStore) Record(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.record(id), nil
+}
+
+func (s *MemoryStore) Forget(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.expiries, id)
+	return nil
+}
+
+// record records the delivery, returning false if it was already recorded. s.mu must be held.
+func (s *MemoryStore) record(id string) bool {
+	now := s.now()
+	s.sweep(now)
+	if expiry, ok := s.expiries[id]; ok && now.Before(expiry) {
+		return false
+	}
+	s.expiries[id] = now.Add(s.ttl)
+	return true
+}
+
+// sweep removes the expired deliveries, at most once per TTL. s.mu must be held.
+func (s *MemoryStore) sweep(now time.Time) bool {
+	if now.Sub(s.swept) < s.ttl {
+		return false
+	}
+	s.swept = now
+	for id, expiry := range s.expiries {
+		if !now.Before(expiry) {
+			delete(s.expiries, id)
+		}
+	}
+	return true
+}
+
+// FileStore remembers deliveries for a fixed amount of time in a file, so that they are remembered across restarts.
+// Each line of the file holds the ID of a delivery and when it expires, as nanoseconds since the Unix epoch. Deliveries
+// are appended to the file as they are recorded, and the file is rewritten as deliveries are forgotten about.
+type FileStore struct {
+	path string
+	// mu guards the file, while the deliveries are kept in memory as well.
+	mu     sync.Mutex
+	memory *MemoryStore
+}
+
+// NewFileStore returns a store backed by the file at the given path, created if it doesn't exist.
+func NewFileStore(path string, ttl time.Duration) (*FileStore, error) {
+	s := &FileStore{
+		path:   path,
+		memory: NewMemoryStore(ttl),
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	// Drop the deliveries which expired while the store wasn't running.
+	if err := s.rewrite(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileStore) Record(id string) (bool, error) {
+	if strings.ContainsAny(id, " \n") {
+		return false, fmt.Errorf("invalid delivery id %q", id)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.memory.mu.Lock()
+	now := s.memory.now()
+	swept := s.memory.sweep(now)
+	recorded := s.memory.record(id)
+	expiry := s.memory.expiries[id]
+	s.memory.mu.Unlock()
+
+	if swept {
+		// Compact the file, which includes the delivery just recorded.
+		return recorded, s.rewrite()
+	}
+	if !recorded {
+		return false, nil
+	}
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return true, fmt.Errorf("error opening delivery store: %w", err)
+	}
+	defer f.Close()
+	if _, err := fmt.Fprintf(f, "%s %d\n", id, expiry.UnixNano()); err != nil {
+		return true, fmt.Errorf("error writing delivery store: %w", err)
+	}
+	return true, nil
+}
+
+func (s *FileStore) Forget(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.memory.Forget(id); err != nil {
+		return err
+	}
+	return s.rewrite()
+}
+
+// load reads the deliveries recorded in the file, if it exists.
+func (s *FileStore) load() error {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error opening delivery store: %w", err)
+	}
+	defer f.Close()
+
+	s.memory.mu.Lock()
+	defer s.memory.mu.Unlock()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		id, v, ok := strings.Cut(scanner.Text(), " ")
+		if !ok {
+			continue
+		}
+		expiry, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			continue
+		}
+		s.memory.expiries[id] = time.Unix(0, expiry)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading delivery store: %w", err)
+	}
+	s.memory.sweep(s.memory.now())
+	return nil
+}
+
+// rewrite replaces the file with the deliveries remembered. s.mu must be held.
+func (s *FileStore) rewrite() error {
+	var b strings.Builder
+	s.memory.mu.Lock()
+	for id, expiry := range s.memory.expiries {
+		fmt.Fprintf(&b, "%s %d\n", id, expiry.UnixNano())
+	}
+	s.memory.mu.Unlock()
+
+	// Write to a temporary file first, so that the store isn't lost if writing fails halfway.
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0600); err != nil {
+		return fmt.Errorf("error writing delivery store: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("error writing delivery store: %w", err)
+	}
+	return nil
+}
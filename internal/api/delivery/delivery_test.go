@@ -0,0 +1,76 @@
+package delivery
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Unix(0, 0)
+	s := NewMemoryStore(time.Hour)
+	s.now = func() time.Time { return now }
+
+	tests := []struct {
+		name     string
+		elapsed  time.Duration
+		forget   bool
+		id       string
+		expected bool
+	}{
+		{name: "first delivery", id: "a", expected: true},
+		{name: "other delivery", id: "b", expected: true},
+		{name: "redelivery", elapsed: 30 * time.Minute, id: "a", expected: false},
+		{name: "redelivery once forgotten", forget: true, id: "b", expected: true},
+		{name: "redelivery once expired", elapsed: 31 * time.Minute, id: "a", expected: true},
+		{name: "redelivery once recorded again", id: "a", expected: false},
+	}
+	for _, tt := range tests {
+		now = now.Add(tt.elapsed)
+		if tt.forget {
+			require.NoError(t, s.Forget(tt.id))
+		}
+		recorded, err := s.Record(tt.id)
+		require.NoError(t, err)
+		require.Equal(t, tt.expected, recorded, tt.name)
+	}
+	require.Len(t, s.expiries, 2)
+}
+
+func TestFileStore_RemembersDeliveriesAcrossRestarts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deliveries")
+
+	s, err := NewFileStore(path, time.Hour)
+	require.NoError(t, err)
+	for _, id := range []string{"a", "b", "c"} {
+		recorded, err := s.Record(id)
+		require.NoError(t, err)
+		require.True(t, recorded)
+	}
+	require.NoError(t, s.Forget("b"))
+
+	s, err = NewFileStore(path, time.Hour)
+	require.NoError(t, err)
+	for id, expected := range map[string]bool{"a": false, "b": true, "c": false, "d": true} {
+		recorded, err := s.Record(id)
+		require.NoError(t, err)
+		require.Equal(t, expected, recorded, id)
+	}
+}
+
+func TestFileStore_ForgetsExpiredDeliveriesOnRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deliveries")
+
+	s, err := NewFileStore(path, time.Nanosecond)
+	require.NoError(t, err)
+	recorded, err := s.Record("a")
+	require.NoError(t, err)
+	require.True(t, recorded)
+
+	time.Sleep(time.Millisecond)
+	s, err = NewFileStore(path, time.Hour)
+	require.NoError(t, err)
+	require.Empty(t, s.memory.expiries)
+}
@@ -14,6 +14,7 @@ type event interface {
 	GetAction() string
 	GetPullRequest() *github.PullRequest
 	GetRepo() *github.Repository
+	GetInstallation() *github.Installation
 }
 
 func isPrMergeEvent(event event) bool {
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/form3tech-oss/github-team-approver/internal/api/approval"
+	ghclient "github.com/form3tech-oss/github-team-approver/internal/api/github"
+)
+
+const (
+	explainPathPrefix = "/api/v1/repos/"
+	explainPathSuffix = "/explain"
+
+	httpHeaderAuthorization = "Authorization"
+	bearerPrefix            = "Bearer "
+)
+
+// explanation is the body of the response of the explain endpoint.
+type explanation struct {
+	Status               string         `json:"status"`
+	Description          string         `json:"description"`
+	Labels               []string       `json:"labels"`
+	ReviewsToRequest     []string       `json:"reviews_to_request"`
+	UserReviewsToRequest []string       `json:"user_reviews_to_request"`
+	Trace                approval.Trace `json:"trace"`
+}
+
+// HandleExplain serves "GET /api/v1/repos/{owner}/{repo}/pulls/{number}/explain", which computes the approval status
+// of a PR without reporting anything, and explains how it was computed rule by rule.
+func (api *API) HandleExplain(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		sendHttpMethodNotAllowedResponse(w, fmt.Errorf("unsupported method %q", req.Method))
+		return
+	}
+	if api.explainAPIToken == nil {
+		sendHttpResponse(w, http.StatusForbidden, "explain endpoint disabled: no API token configured")
+		return
+	}
+	if !api.isExplainAuthorized(req) {
+		sendHttpResponse(w, http.StatusUnauthorized, "invalid or missing API token")
+		return
+	}
+
+	ownerLogin, repoName, prNumber, err := parseExplainPath(req.URL.Path)
+	if err != nil {
+		sendHttpResponse(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	log := logrus.NewEntry(logrus.StandardLogger()).WithFields(logrus.Fields{
+		logFieldServiceName: api.AppName,
+		logFieldRepo:        fmt.Sprintf("%s/%s", ownerLogin, repoName),
+		logFieldPR:          prNumber,
+	})
+	if isMember(api.ignoredRepositories, fmt.Sprintf("%s/%s", ownerLogin, repoName)) {
+		sendHttpResponse(w, http.StatusNotFound, "ignored repository")
+		return
+	}
+
+	ctx := context.Background()
+	client, err := api.clients.ForRepository(ctx, ownerLogin, repoName)
+	if errors.Is(err, ghclient.ErrNoInstallation) {
+		sendHttpResponse(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		log.WithError(err).Warn("failed to find installation")
+		sendHttpInternalServerErrorResponse(w, err)
+		return
+	}
+	pr, err := client.GetPullRequest(ctx, ownerLogin, repoName, prNumber)
+	if errors.Is(err, ghclient.ErrNoPullRequest) {
+		sendHttpResponse(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		log.WithError(err).Warn("failed to get pull request")
+		sendHttpInternalServerErrorResponse(w, err)
+		return
+	}
+
+	app := approval.NewApproval(log, client, api.approvalSettings())
+	result, err := app.Explain(ctx, newPR(ownerLogin, repoName, pr))
+	if errors.Is(err, ghclient.ErrNoConfigurationFile) {
+		sendHttpResponse(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		log.WithError(err).Warn("failed to explain status")
+		sendHttpInternalServerErrorResponse(w, fmt.Errorf("failed to compute status: %w", err))
+		return
+	}
+
+	payload, err := json.Marshal(explanation{
+		Status:               result.Status(),
+		Description:          result.Description(),
+		Labels:               result.FinalLabels(),
+		ReviewsToRequest:     result.ReviewsToRequest(),
+		UserReviewsToRequest: result.UserReviewsToRequest(),
+		Trace:                result.Trace(),
+	})
+	if err != nil {
+		sendHttpInternalServerErrorResponse(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	sendHttpResponse(w, http.StatusOK, string(payload))
+}
+
+func (api *API) isExplainAuthorized(req *http.Request) bool {
+	token := strings.TrimPrefix(req.Header.Get(httpHeaderAuthorization), bearerPrefix)
+	return subtle.ConstantTimeCompare([]byte(token), api.explainAPIToken) == 1
+}
+
+// parseExplainPath extracts the owner, repository and number of the PR from "/api/v1/repos/{owner}/{repo}/pulls/{number}/explain".
+func parseExplainPath(path string) (string, string, int, error) {
+	parts := strings.Split(strings.TrimSuffix(strings.TrimPrefix(path, explainPathPrefix), explainPathSuffix), "/")
+	if !strings.HasSuffix(path, explainPathSuffix) || len(parts) != 4 || parts[0] == "" || parts[1] == "" || parts[2] != "pulls" {
+		return "", "", 0, fmt.Errorf("unknown path %q", path)
+	}
+	number, err := strconv.Atoi(parts[3])
+	if err != nil || number <= 0 {
+		return "", "", 0, fmt.Errorf("invalid pull request number %q", parts[3])
+	}
+	return parts[0], parts[1], number, nil
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExplainPath(t *testing.T) {
+	tests := map[string]struct {
+		path     string
+		owner    string
+		repo     string
+		number   int
+		hasError bool
+	}{
+		"valid": {
+			path:   "/api/v1/repos/form3tech/some-service/pulls/12/explain",
+			owner:  "form3tech",
+			repo:   "some-service",
+			number: 12,
+		},
+		"missing suffix": {
+			path:     "/api/v1/repos/form3tech/some-service/pulls/12",
+			hasError: true,
+		},
+		"missing owner": {
+			path:     "/api/v1/repos//some-service/pulls/12/explain",
+			hasError: true,
+		},
+		"issues instead of pulls": {
+			path:     "/api/v1/repos/form3tech/some-service/issues/12/explain",
+			hasError: true,
+		},
+		"invalid number": {
+			path:     "/api/v1/repos/form3tech/some-service/pulls/twelve/explain",
+			hasError: true,
+		},
+		"nested path": {
+			path:     "/api/v1/repos/form3tech/some-service/extra/pulls/12/explain",
+			hasError: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			owner, repo, number, err := parseExplainPath(tt.path)
+			if tt.hasError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.owner, owner)
+			require.Equal(t, tt.repo, repo)
+			require.Equal(t, tt.number, number)
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package github
+
+import (
+	"container/list"
+	"sync"
+)
+
+const (
+	// maxCacheSize is the maximum size, in bytes, of the responses cached by the caching transport.
+	maxCacheSize = 32 << 20
+)
+
+// boundedCache is an in-memory cache of responses which evicts the least recently used ones as the size of the
+// responses it holds exceeds its maximum size. As the caching transport is shared by all installations, an unbounded
+// cache would grow with every file, pull request and team ever requested.
+type boundedCache struct {
+	maxSize int
+
+	mu   sync.Mutex
+	size int
+	// entries holds the cached responses, the most recently used first.
+	entries *list.List
+	byKey   map[string]*list.Element
+}
+
+type boundedCacheEntry struct {
+	key   string
+	value []byte
+}
+
+func newBoundedCache(maxSize int) *boundedCache {
+	return &boundedCache{
+		maxSize: maxSize,
+		entries: list.New(),
+		byKey:   map[string]*list.Element{},
+	}
+}
+
+func (c *boundedCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.byKey[key]
+	if !ok {
+		return nil, false
+	}
+	c.entries.MoveToFront(e)
+	return e.Value.(*boundedCacheEntry).value, true
+}
+
+func (c *boundedCache) Set(key string, value []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove(key)
+	if len(value) > c.maxSize {
+		return
+	}
+	c.byKey[key] = c.entries.PushFront(&boundedCacheEntry{key: key, value: value})
+	c.size += len(value)
+	for c.size > c.maxSize {
+		c.remove(c.entries.Back().Value.(*boundedCacheEntry).key)
+	}
+}
+
+func (c *boundedCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove(key)
+}
+
+// remove removes the response cached for the given key, if any. c.mu must be held.
+func (c *boundedCache) remove(key string) {
+	e, ok := c.byKey[key]
+	if !ok {
+		return
+	}
+	c.entries.Remove(e)
+	delete(c.byKey, key)
+	c.size -= len(e.Value.(*boundedCacheEntry).value)
+}
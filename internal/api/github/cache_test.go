@@ -0,0 +1,28 @@
+package github
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoundedCache_EvictsLeastRecentlyUsedResponses(t *testing.T) {
+	c := newBoundedCache(6)
+	c.Set("a", []byte("aa"))
+	c.Set("b", []byte("bb"))
+	c.Set("c", []byte("cc"))
+	_, _ = c.Get("a")
+	c.Set("d", []byte("dd"))
+
+	for key, expected := range map[string]bool{"a": true, "b": false, "c": true, "d": true} {
+		_, ok := c.Get(key)
+		require.Equal(t, expected, ok, key)
+	}
+	require.Equal(t, 6, c.size)
+
+	c.Set("e", []byte("eeeeeee"))
+	_, ok := c.Get("e")
+	require.False(t, ok)
+	c.Delete("a")
+	require.Equal(t, 4, c.size)
+}
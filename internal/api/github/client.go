@@ -12,11 +12,11 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/form3tech-oss/github-team-approver/internal/api/configuration"
 	"github.com/form3tech-oss/github-team-approver/internal/api/secret"
 
-	"github.com/bradleyfalzon/ghinstallation"
-	"github.com/form3tech-oss/github-team-approver-commons/v2/pkg/configuration"
 	"github.com/google/go-github/v42/github"
 	"github.com/gregjones/httpcache"
 	log "github.com/sirupsen/logrus"
@@ -25,7 +25,6 @@ import (
 const (
 	// DefaultGitHubOperationTimeout is the maximum duration of requests against the GitHub API.
 	DefaultGitHubOperationTimeout = 15 * time.Second
-	defaultBranch                 = "master"
 
 	// defaultListOptionsPerPage is the number of items per page that we request by default from the GitHub API.
 	defaultListOptionsPerPage = 100
@@ -37,28 +36,89 @@ const (
 	envGitHubAppInstallationId = "GITHUB_APP_INSTALLATION_ID"
 	envGitHubAppPrivateKeyPath = "GITHUB_APP_PRIVATE_KEY_PATH"
 
-	ignoredReviewersTitle = "Following reviewers do not have approval capabilities for this review as they either contributed to or reopened the PR:\n"
-	invalidReviewersTitle = "Following reviewers are not member of a team with approval capabilities:\n"
+	// summaryCommentMarker identifies the comment holding the approval matrix of a PR. It is hidden when rendered.
+	summaryCommentMarker = "<!-- github-team-approver:summary -->"
+
+	// The titles of the comments previous versions left on PRs for each category of reviews not counted.
+	legacyIgnoredReviewersTitle = "Following reviewers do not have approval capabilities for this review as they either contributed to or reopened the PR:\n"
+	legacyInvalidReviewersTitle = "Following reviewers are not member of a team with approval capabilities:\n"
+	legacyStaleReviewersTitle   = "Following reviewers approved a previous version of the PR and need to approve it again:\n"
+
+	// userTypeBot is the type of the users GitHub Apps act as.
+	userTypeBot = "Bot"
+
+	// statusSuccess and statusError are the states of a commit status mapped onto completed check runs.
+	statusSuccess = "success"
+	statusError   = "error"
+
+	checkRunStatusCompleted   = "completed"
+	checkRunStatusInProgress  = "in_progress"
+	checkRunConclusionSuccess = "success"
+	checkRunConclusionFailure = "failure"
+	checkRunSummaryMaxLength  = 65535
+	checkRunSummaryTruncated  = "\n\n_The summary was truncated._"
+
+	// comparisonMaxFiles is the number of files beyond which GitHub leaves files out of a comparison.
+	comparisonMaxFiles = 300
 )
 
 var (
 	ErrNoConfigurationFile = errors.New("no configuration file exists in the source repository")
+	ErrNoCodeownersFile    = errors.New("no CODEOWNERS file exists in the source repository")
+	ErrNoPullRequest       = errors.New("no such pull request exists in the source repository")
+	ErrNoInstallation      = errors.New("the app is not installed for the source repository")
+	ErrComparisonTruncated = errors.New("the comparison lists the maximum number of files")
 )
 
 type Client struct {
 	githubClient *github.Client
 }
 
-func (c *Client) GetConfiguration(ctx context.Context, ownerLogin, repoName string) (*configuration.Configuration, error) {
+// GetResolvedConfiguration reads the configuration of a repository from the given source and resolves it against the
+// central configuration of the organisation, if the source names a central repository. ErrNoConfigurationFile is only
+// returned when neither exists.
+func (c *Client) GetResolvedConfiguration(ctx context.Context, ownerLogin, repoName, baseBranch string, source configuration.Source) (*configuration.Configuration, error) {
+	repo, err := c.GetConfiguration(ctx, ownerLogin, repoName, source.Ref(baseBranch))
+	if err != nil && !errors.Is(err, ErrNoConfigurationFile) {
+		return nil, err
+	}
+
+	centralRepoName := source.CentralRepository()
+	if centralRepoName == "" || centralRepoName == repoName {
+		if err != nil {
+			return nil, err
+		}
+		return configuration.Resolve(nil, repo), nil
+	}
+
+	// The central configuration is always read from the default branch of the central repository.
+	central, err := c.GetConfiguration(ctx, ownerLogin, centralRepoName, "")
+	if err != nil && !errors.Is(err, ErrNoConfigurationFile) {
+		return nil, fmt.Errorf("error reading central configuration: %w", err)
+	}
+
+	resolved := configuration.Resolve(central, repo)
+	if resolved == nil {
+		return nil, ErrNoConfigurationFile
+	}
+	return resolved, nil
+}
+
+// GetConfiguration reads the configuration file at the given ref, or at the default branch if ref is empty.
+func (c *Client) GetConfiguration(ctx context.Context, ownerLogin, repoName, ref string) (*configuration.Configuration, error) {
 	ctxTimeout, fn := context.WithTimeout(ctx, DefaultGitHubOperationTimeout)
 	defer fn()
 
+	var opts *github.RepositoryContentGetOptions
+	if ref != "" {
+		opts = &github.RepositoryContentGetOptions{Ref: ref}
+	}
 	file, _, resp, err := c.githubClient.Repositories.GetContents(
 		ctxTimeout,
 		ownerLogin,
 		repoName,
 		configuration.ConfigurationFilePath,
-		nil,
+		opts,
 	)
 
 	if err != nil {
@@ -73,7 +133,55 @@ func (c *Client) GetConfiguration(ctx context.Context, ownerLogin, repoName stri
 		return nil, err
 	}
 
-	return configuration.ReadConfiguration(strings.NewReader(content))
+	cfg, err := configuration.ReadConfiguration(strings.NewReader(content))
+	var invalid *configuration.ValidationError
+	if errors.As(err, &invalid) {
+		invalid.Repository = repoName
+	}
+	return cfg, err
+}
+
+// GetCodeowners returns the contents of the CODEOWNERS file as of the given ref.
+func (c *Client) GetCodeowners(ctx context.Context, ownerLogin, repoName, ref string) (string, error) {
+	ctxTimeout, fn := context.WithTimeout(ctx, DefaultGitHubOperationTimeout)
+	defer fn()
+
+	file, _, resp, err := c.githubClient.Repositories.GetContents(
+		ctxTimeout,
+		ownerLogin,
+		repoName,
+		configuration.CodeownersFilePath,
+		&github.RepositoryContentGetOptions{Ref: ref},
+	)
+
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return "", ErrNoCodeownersFile
+		}
+		return "", fmt.Errorf("error downloading CODEOWNERS: %w", err)
+	}
+
+	return file.GetContent()
+}
+
+func (c *Client) GetPullRequest(ctx context.Context, ownerLogin, repoName string, prNumber int) (*github.PullRequest, error) {
+	log.WithFields(
+		log.Fields{
+			"pr":   prNumber,
+			"repo": fmt.Sprintf("%s/%s", ownerLogin, repoName),
+			"api":  "PullRequests.Get",
+		}).Tracef("requesting")
+
+	ctxTimeout, fn := context.WithTimeout(ctx, DefaultGitHubOperationTimeout)
+	defer fn()
+	pr, res, err := c.githubClient.PullRequests.Get(ctxTimeout, ownerLogin, repoName, prNumber)
+	if err != nil {
+		if res != nil && res.StatusCode == http.StatusNotFound {
+			return nil, ErrNoPullRequest
+		}
+		return nil, fmt.Errorf("error getting pull request %d: %w", prNumber, err)
+	}
+	return pr, nil
 }
 
 func (c *Client) GetPullRequestReviews(ctx context.Context, ownerLogin, repoName string, prNumber int) ([]*github.PullRequestReview, error) {
@@ -115,6 +223,86 @@ func (c *Client) GetPullRequestReviews(ctx context.Context, ownerLogin, repoName
 	return reviews, nil
 }
 
+// ListInstallationRepositories lists the repositories the installation the client authenticates as has access to.
+func (c *Client) ListInstallationRepositories(ctx context.Context) ([]*github.Repository, error) {
+	repos := make([]*github.Repository, 0, 0)
+
+	opts := &github.ListOptions{
+		Page:    1,
+		PerPage: defaultListOptionsPerPage,
+	}
+
+	logger := log.WithFields(
+		log.Fields{
+			"api":      "Apps.ListRepos",
+			"per_page": opts.PerPage,
+		})
+
+	for {
+		logger.WithFields(log.Fields{"page": opts.Page}).Tracef("requesting")
+
+		ctxTimeout, fn := context.WithTimeout(ctx, DefaultGitHubOperationTimeout)
+		r, res, err := c.githubClient.Apps.ListRepos(ctxTimeout, opts)
+		if err != nil {
+			fn()
+			return nil, fmt.Errorf("error listing installation repositories: %w", err)
+		}
+		if res.StatusCode >= 300 {
+			fn()
+			return nil, fmt.Errorf("error listing installation repositories (status: %d): %s", res.StatusCode, readAllClose(res.Body))
+		}
+		fn()
+		repos = append(repos, r.Repositories...)
+		if res.NextPage == 0 {
+			break
+		}
+		opts.Page = res.NextPage
+	}
+	return repos, nil
+}
+
+// ListOpenPullRequests lists the open pull requests of the given repository.
+func (c *Client) ListOpenPullRequests(ctx context.Context, ownerLogin, repoName string) ([]*github.PullRequest, error) {
+	prs := make([]*github.PullRequest, 0, 0)
+
+	opts := &github.PullRequestListOptions{
+		State: "open",
+		ListOptions: github.ListOptions{
+			Page:    1,
+			PerPage: defaultListOptionsPerPage,
+		},
+	}
+
+	logger := log.WithFields(
+		log.Fields{
+			"repo":     fmt.Sprintf("%s/%s", ownerLogin, repoName),
+			"api":      "PullRequests.List",
+			"per_page": opts.PerPage,
+		})
+
+	for {
+		logger.WithFields(log.Fields{"page": opts.Page}).Tracef("requesting")
+
+		ctxTimeout, fn := context.WithTimeout(ctx, DefaultGitHubOperationTimeout)
+		r, res, err := c.githubClient.PullRequests.List(ctxTimeout, ownerLogin, repoName, opts)
+		if err != nil {
+			fn()
+			return nil, fmt.Errorf("error listing pull requests: %w", err)
+		}
+		if res.StatusCode >= 300 {
+			fn()
+			return nil, fmt.Errorf("error listing pull requests (status: %d): %s", res.StatusCode, readAllClose(res.Body))
+		}
+		fn()
+		prs = append(prs, r...)
+		if res.NextPage == 0 {
+			break
+		}
+		opts.Page = res.NextPage
+	}
+	return prs, nil
+}
+
 // https://docs.github.com/en/rest/reference/pulls#list-pull-requests-files
 func (c *Client) GetPullRequestCommitFiles(ctx context.Context, ownerLogin, repoName string, prNumber int) ([]*github.CommitFile, error) {
 
@@ -156,6 +344,59 @@ func (c *Client) GetPullRequestCommitFiles(ctx context.Context, ownerLogin, repo
 	return commitFiles, nil
 }
 
+// CompareCommits returns the files changed between the merge base of "base" and "head", and "head". Every page of the
+// comparison is read, and ErrComparisonTruncated is returned when GitHub may have left files out of it.
+// https://docs.github.com/en/rest/commits/commits#compare-two-commits
+func (c *Client) CompareCommits(ctx context.Context, ownerLogin, repoName, base, head string) ([]*github.CommitFile, error) {
+	commitFiles := make([]*github.CommitFile, 0, 0)
+	seen := make(map[string]bool)
+
+	opts := &github.ListOptions{
+		Page:    1,
+		PerPage: defaultListOptionsPerPage,
+	}
+
+	logger := log.WithFields(
+		log.Fields{
+			"repo":     fmt.Sprintf("%s/%s", ownerLogin, repoName),
+			"api":      "Repositories.CompareCommits",
+			"base":     base,
+			"head":     head,
+			"per_page": opts.PerPage,
+		})
+
+	for {
+		logger.WithFields(log.Fields{"page": opts.Page}).Tracef("requesting")
+
+		ctxTimeout, fn := context.WithTimeout(ctx, DefaultGitHubOperationTimeout)
+		comparison, res, err := c.githubClient.Repositories.CompareCommits(ctxTimeout, ownerLogin, repoName, base, head, opts)
+		if err != nil {
+			fn()
+			return nil, fmt.Errorf("error comparing commits %q and %q: %w", base, head, err)
+		}
+		if res.StatusCode >= 300 {
+			fn()
+			return nil, fmt.Errorf("error comparing commits %q and %q (status: %d): %s", base, head, res.StatusCode, readAllClose(res.Body))
+		}
+		fn()
+		// Depending on the size of the comparison, the files are listed on the first page only or on every page.
+		for _, f := range comparison.Files {
+			if !seen[f.GetFilename()] {
+				seen[f.GetFilename()] = true
+				commitFiles = append(commitFiles, f)
+			}
+		}
+		if res.NextPage == 0 {
+			break
+		}
+		opts.Page = res.NextPage
+	}
+	if len(commitFiles) >= comparisonMaxFiles {
+		return commitFiles, fmt.Errorf("error comparing commits %q and %q: %w", base, head, ErrComparisonTruncated)
+	}
+	return commitFiles, nil
+}
+
 func (c *Client) GetTeams(ctx context.Context, organisation string) ([]*github.Team, error) {
 	// Grab a list of all the teams in the organization.
 	teams := make([]*github.Team, 0, 0)
@@ -354,7 +595,9 @@ func (c *Client) getIssuesEventsPage(ctx context.Context, owner, repo string, nu
 	return events, resp.NextPage, nil
 }
 
-func (c *Client) ReportStatus(ctx context.Context, ownerLogin, repoName, statusesURL, status, description string) error {
+// ReportStatus publishes the approval status as a commit status on the given commit, which must be the one the status
+// was computed for.
+func (c *Client) ReportStatus(ctx context.Context, ownerLogin, repoName, sha, status, description string) error {
 	n := os.Getenv(envGitHubStatusName)
 	v := &github.RepoStatus{
 		State:       &status,
@@ -363,7 +606,7 @@ func (c *Client) ReportStatus(ctx context.Context, ownerLogin, repoName, statuse
 	}
 	ctxTimeout, fn := context.WithTimeout(ctx, DefaultGitHubOperationTimeout)
 	defer fn()
-	_, res, err := c.githubClient.Repositories.CreateStatus(ctxTimeout, ownerLogin, repoName, readStatusSHAFromStatusURL(statusesURL), v)
+	_, res, err := c.githubClient.Repositories.CreateStatus(ctxTimeout, ownerLogin, repoName, sha, v)
 	if err != nil {
 		return fmt.Errorf("error reporting status: %w", err)
 	}
@@ -373,63 +616,158 @@ func (c *Client) ReportStatus(ctx context.Context, ownerLogin, repoName, statuse
 	return nil
 }
 
-func (c *Client) ReportIgnoredReviews(ctx context.Context, owner, repo string, prNumber int, reviewers []string) error {
-	return c.reportIgnoredReviews(ctx, owner, repo, prNumber, reviewers, ignoredReviewersTitle)
-}
-
-func (c *Client) ReportInvalidReviews(ctx context.Context, owner, repo string, prNumber int, reviewers []string) error {
-	return c.reportIgnoredReviews(ctx, owner, repo, prNumber, reviewers, invalidReviewersTitle)
-}
-
-func (c *Client) reportIgnoredReviews(ctx context.Context, owner, repo string, prNumber int, reviewers []string, title string) error {
-	if len(reviewers) == 0 {
-		return nil
+// ReportCheckRun publishes the approval status as a check run on the given commit, named after the status. Pending
+// approvals leave the check run in progress, and errors complete it as failed. The check run the app already reported on
+// the commit is updated, if any, so that the commit doesn't pile up a check run per evaluation.
+func (c *Client) ReportCheckRun(ctx context.Context, ownerLogin, repoName, headSHA, status, title, summary string) error {
+	opts := github.UpdateCheckRunOptions{
+		Name: os.Getenv(envGitHubStatusName),
+		Output: &github.CheckRunOutput{
+			Title:   github.String(title),
+			Summary: github.String(truncateCheckRunSummary(summary)),
+		},
+	}
+	switch status {
+	case statusSuccess:
+		opts.Status = github.String(checkRunStatusCompleted)
+		opts.Conclusion = github.String(checkRunConclusionSuccess)
+		opts.CompletedAt = &github.Timestamp{Time: time.Now()}
+	case statusError:
+		opts.Status = github.String(checkRunStatusCompleted)
+		opts.Conclusion = github.String(checkRunConclusionFailure)
+		opts.CompletedAt = &github.Timestamp{Time: time.Now()}
+	default:
+		opts.Status = github.String(checkRunStatusInProgress)
 	}
 
-	err := c.removeOldBotComments(ctx, owner, repo, prNumber, title)
+	ctxTimeout, fn := context.WithTimeout(ctx, DefaultGitHubOperationTimeout)
+	defer fn()
+	checkRunID, err := c.findCheckRun(ctxTimeout, ownerLogin, repoName, headSHA, opts.Name)
 	if err != nil {
 		return err
 	}
-
-	ctxTimeout, cancel := context.WithTimeout(ctx, DefaultGitHubOperationTimeout)
-	defer cancel()
-
-	msg := title
-	for _, r := range reviewers {
-		msg += fmt.Sprintf("- @%s\n", r)
+	var res *github.Response
+	if checkRunID != 0 {
+		_, res, err = c.githubClient.Checks.UpdateCheckRun(ctxTimeout, ownerLogin, repoName, checkRunID, opts)
+	} else {
+		_, res, err = c.githubClient.Checks.CreateCheckRun(ctxTimeout, ownerLogin, repoName, github.CreateCheckRunOptions{
+			Name:        opts.Name,
+			HeadSHA:     headSHA,
+			Status:      opts.Status,
+			Conclusion:  opts.Conclusion,
+			CompletedAt: opts.CompletedAt,
+			Output:      opts.Output,
+		})
 	}
-
-	payload := &github.IssueComment{
-		Body: github.String(msg),
+	if err != nil {
+		return fmt.Errorf("error reporting check run: %w", err)
+	}
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("error reporting check run (status: %d): %s", res.StatusCode, readAllClose(res.Body))
 	}
+	return nil
+}
 
-	// using Issues API over PullRequests as we only have an interest in commenting on the PR
-	// not commenting on a given line in a specific commit
-	_, _, err = c.githubClient.Issues.CreateComment(ctxTimeout, owner, repo, prNumber, payload)
+// findCheckRun returns the ID of the latest check run with the given name the app reported on the given commit, or zero
+// if there is none. Check runs of other apps are ignored when the app is known by GITHUB_APP_ID.
+func (c *Client) findCheckRun(ctx context.Context, ownerLogin, repoName, headSHA, name string) (int64, error) {
+	opts := &github.ListCheckRunsOptions{
+		CheckName: github.String(name),
+	}
+	if appID, err := strconv.ParseInt(os.Getenv(envGitHubAppId), 10, 64); err == nil {
+		opts.AppID = github.Int64(appID)
+	}
+	runs, res, err := c.githubClient.Checks.ListCheckRunsForRef(ctx, ownerLogin, repoName, headSHA, opts)
 	if err != nil {
-		return fmt.Errorf("reportIgnoredReviews: %w", err)
+		return 0, fmt.Errorf("error listing check runs: %w", err)
+	}
+	if res.StatusCode >= 300 {
+		return 0, fmt.Errorf("error listing check runs (status: %d): %s", res.StatusCode, readAllClose(res.Body))
+	}
+	for _, run := range runs.CheckRuns {
+		if run.GetName() == name {
+			return run.GetID(), nil
+		}
 	}
+	return 0, nil
+}
 
-	return nil
+// truncateCheckRunSummary keeps the summary within the size GitHub accepts, cutting it on a rune boundary so that it
+// remains valid UTF-8.
+func truncateCheckRunSummary(summary string) string {
+	if len(summary) <= checkRunSummaryMaxLength {
+		return summary
+	}
+	end := checkRunSummaryMaxLength - len(checkRunSummaryTruncated)
+	for end > 0 && !utf8.RuneStart(summary[end]) {
+		end--
+	}
+	return summary[:end] + checkRunSummaryTruncated
 }
 
-func (c *Client) removeOldBotComments(ctx context.Context, owner, repo string, prNumber int, title string) error {
+// ReportSummaryComment keeps the comment holding the approval matrix of a PR, found by a hidden marker, in sync with
+// the given body. The comment is edited in place, and only when its body changes. It is only created if createIfMissing
+// is set, so that PRs with nothing worth commenting on are left alone, and it is deleted if the body is empty, so that it
+// doesn't outlive the rules it describes. Duplicates of the comment, and the comments previous versions left for each
+// category of reviews not counted, are deleted. Only comments left by bots are considered, so that users quoting the
+// comment don't have their own comments edited or deleted.
+func (c *Client) ReportSummaryComment(ctx context.Context, owner, repo string, prNumber int, body string, createIfMissing bool) error {
 	comments, err := c.getPRComments(ctx, owner, repo, prNumber)
 	if err != nil {
 		return err
 	}
 
+	var summary *github.IssueComment
 	for _, comment := range comments {
-		if strings.Contains(comment.GetBody(), title) {
-			_, err = c.DeletePRComment(ctx, owner, repo, comment.GetID())
-			if err != nil {
-				return err
-			}
+		if !isBotComment(comment) {
+			continue
+		}
+		if summary == nil && body != "" && strings.HasPrefix(comment.GetBody(), summaryCommentMarker) {
+			summary = comment
+			continue
+		}
+		if _, err := c.DeletePRComment(ctx, owner, repo, comment.GetID()); err != nil {
+			return err
+		}
+	}
+	if body == "" {
+		return nil
+	}
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, DefaultGitHubOperationTimeout)
+	defer cancel()
+
+	body = fmt.Sprintf("%s\n%s", summaryCommentMarker, body)
+	switch {
+	case summary == nil && !createIfMissing:
+		return nil
+	case summary == nil:
+		// using Issues API over PullRequests as we only have an interest in commenting on the PR
+		// not commenting on a given line in a specific commit
+		if _, _, err := c.githubClient.Issues.CreateComment(ctxTimeout, owner, repo, prNumber, &github.IssueComment{Body: github.String(body)}); err != nil {
+			return fmt.Errorf("ReportSummaryComment: %w", err)
+		}
+	case summary.GetBody() != body:
+		if _, _, err := c.githubClient.Issues.EditComment(ctxTimeout, owner, repo, summary.GetID(), &github.IssueComment{Body: github.String(body)}); err != nil {
+			return fmt.Errorf("ReportSummaryComment: %w", err)
 		}
 	}
 	return nil
 }
 
+// isBotComment reports whether a comment was left by ourselves, be it the summary comment or a legacy one.
+func isBotComment(comment *github.IssueComment) bool {
+	if comment.GetUser().GetType() != userTypeBot {
+		return false
+	}
+	for _, prefix := range []string{summaryCommentMarker, legacyIgnoredReviewersTitle, legacyInvalidReviewersTitle, legacyStaleReviewersTitle} {
+		if strings.HasPrefix(comment.GetBody(), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *Client) DeletePRComment(ctx context.Context, owner, repo string, commentID int64) (*github.Response, error) {
 	ctxTimeout, cancel := context.WithTimeout(ctx, DefaultGitHubOperationTimeout)
 	defer cancel()
@@ -491,13 +829,14 @@ func (c *Client) getPRCommentsPage(ctx context.Context, owner, repo string, prNu
 	return comments, resp.NextPage, nil
 }
 
-func (c *Client) RequestReviews(ctx context.Context, ownerLogin, repoName string, prNumber int, reviewsToRequest []string) error {
-	if len(reviewsToRequest) == 0 {
+func (c *Client) RequestReviews(ctx context.Context, ownerLogin, repoName string, prNumber int, reviewsToRequest, userReviewsToRequest []string) error {
+	if len(reviewsToRequest) == 0 && len(userReviewsToRequest) == 0 {
 		return nil
 	}
 	ctxTimeout, fn := context.WithTimeout(ctx, DefaultGitHubOperationTimeout)
 	defer fn()
 	_, res, err := c.githubClient.PullRequests.RequestReviewers(ctxTimeout, ownerLogin, repoName, prNumber, github.ReviewersRequest{
+		Reviewers:     userReviewsToRequest,
 		TeamReviewers: reviewsToRequest,
 	})
 	if err != nil {
@@ -581,19 +920,21 @@ func alwaysStale(_ http.Request, _ http.Response) httpcache.Freshness {
 	return httpcache.Stale
 }
 
+// New returns a client authenticating as the installation set by GITHUB_APP_INSTALLATION_ID. Use Clients to serve
+// several installations.
 func New(store secret.Store) *Client {
-	var baseTransport http.RoundTripper
-
-	if v, err := strconv.ParseBool(os.Getenv(envUseCachingTransport)); err == nil && v {
-		t := httpcache.NewMemoryCacheTransport()
-		t.FreshnessFunc = alwaysStale
-		baseTransport = t
-	} else {
-		baseTransport = http.DefaultTransport
+	transport, err := installationTransport(baseTransport(), store, defaultInstallationID())
+	if err != nil {
+		log.WithError(err).Warn("failed to create authenticating transport")
+		return newClient(baseTransport())
 	}
+	return newClient(transport)
+}
+
+func newClient(transport http.RoundTripper) *Client {
 	client := &Client{
 		githubClient: github.NewClient(&http.Client{
-			Transport: maybeWrapInAuthenticatingTransport(baseTransport, store),
+			Transport: transport,
 		}),
 	}
 	if v := os.Getenv(envGitHubBaseURL); v != "" {
@@ -602,31 +943,15 @@ func New(store secret.Store) *Client {
 	return client
 }
 
-func maybeWrapInAuthenticatingTransport(baseTransport http.RoundTripper, store secret.Store) http.RoundTripper {
-	// Grab our GitHub application ID.
-	applicationId, err := strconv.Atoi(os.Getenv(envGitHubAppId))
-	if err != nil {
-		log.WithError(err).Warn("failed to parse application id")
-		return baseTransport
-	}
-	// Grab our GitHub installation ID.
-	installationId, err := strconv.Atoi(os.Getenv(envGitHubAppInstallationId))
-	if err != nil {
-		log.WithError(err).Warn("failed to parse installation id")
-		return baseTransport
-	}
-	// Use a transport that authenticates us as an installation.
-
-	privateKey, err := store.Get(envGitHubAppPrivateKeyPath)
-	if err != nil {
-		log.WithError(err).Warn("Failed to read secret key from configured path")
-	}
-	authenticatingTransport, err := ghinstallation.New(baseTransport, int64(applicationId), int64(installationId), privateKey)
-	if err != nil {
-		log.WithError(err).Warn("failed to create authenticating transport")
-		return baseTransport
+// baseTransport returns the transport requests are made with, which caches the responses, up to maxCacheSize bytes of
+// them, if USE_CACHING_TRANSPORT is set.
+func baseTransport() http.RoundTripper {
+	if v, err := strconv.ParseBool(os.Getenv(envUseCachingTransport)); err == nil && v {
+		t := httpcache.NewTransport(newBoundedCache(maxCacheSize))
+		t.FreshnessFunc = alwaysStale
+		return t
 	}
-	return authenticatingTransport
+	return http.DefaultTransport
 }
 
 func mustParseURL(v string) *url.URL {
@@ -656,8 +981,3 @@ func readAllClose(r io.ReadCloser) []byte {
 	}
 	return v
 }
-
-func readStatusSHAFromStatusURL(url string) string {
-	parts := strings.Split(url, "/")
-	return parts[len(parts)-1]
-}
@@ -0,0 +1,27 @@
+package github
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncateCheckRunSummary(t *testing.T) {
+	t.Run("summary within limit", func(t *testing.T) {
+		require.Equal(t, "summary", truncateCheckRunSummary("summary"))
+	})
+	t.Run("summary over limit", func(t *testing.T) {
+		summary := truncateCheckRunSummary(strings.Repeat("a", checkRunSummaryMaxLength+1))
+		require.Len(t, summary, checkRunSummaryMaxLength)
+		require.True(t, strings.HasSuffix(summary, checkRunSummaryTruncated))
+	})
+	t.Run("summary over limit in the middle of a rune", func(t *testing.T) {
+		// Each "✓" is 3 bytes long, so the limit doesn't fall on a rune boundary.
+		summary := truncateCheckRunSummary("a" + strings.Repeat("✓", checkRunSummaryMaxLength/3))
+		require.LessOrEqual(t, len(summary), checkRunSummaryMaxLength)
+		require.True(t, utf8.ValidString(summary))
+		require.True(t, strings.HasSuffix(summary, checkRunSummaryTruncated))
+	})
+}
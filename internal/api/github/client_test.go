@@ -35,3 +35,72 @@ func TestDeletePRCommentWhenCommentAlreadyDeleted(t *testing.T) {
 	then.
 		ExpectCommentDeleted()
 }
+
+func TestClientsAuthenticateAsEachInstallation(t *testing.T) {
+	given, when, then := stages.ClientTest(t)
+
+	given.
+		FakeGHRunning().
+		Organisation().
+		Repo().
+		PR().
+		AppInstalled()
+	when.
+		GettingPullRequestAsInstallations(1, 1, 2)
+	then.
+		ExpectClientReusedForSameInstallation().
+		ExpectRequestsAuthenticatedAsEachInstallation().
+		ExpectOneTokenIssuedPerInstallation()
+}
+
+func TestClientsRefreshExpiringInstallationTokens(t *testing.T) {
+	given, when, then := stages.ClientTest(t)
+
+	given.
+		FakeGHRunning().
+		Organisation().
+		Repo().
+		PR().
+		AppInstalledWithExpiringTokens()
+	when.
+		GettingPullRequestAsInstallations(1, 1, 2)
+	then.
+		ExpectRequestsAuthenticatedAsEachInstallation().
+		ExpectTokenRefreshedOnEveryRequest()
+}
+
+func TestClientsLookUpInstallationAgainOnceTokenRejected(t *testing.T) {
+	given, when, then := stages.ClientTest(t)
+
+	given.
+		FakeGHRunning().
+		Organisation().
+		Repo().
+		PR().
+		AppInstalled()
+	when.
+		GettingPullRequestForRepository().
+		AppReinstalled().
+		GettingPullRequestForRepository().
+		GettingPullRequestForRepository()
+	then.
+		ExpectInstallationLookedUpAgainOnceRejected()
+}
+
+func TestClientsLookUpInstallationAgainOnceNoTokenIssued(t *testing.T) {
+	given, when, then := stages.ClientTest(t)
+
+	given.
+		FakeGHRunning().
+		Organisation().
+		Repo().
+		PR().
+		AppInstalledWithExpiringTokens()
+	when.
+		GettingPullRequestForRepository().
+		AppReinstalled().
+		GettingPullRequestForRepository().
+		GettingPullRequestForRepository()
+	then.
+		ExpectInstallationLookedUpAgainOnceRejected()
+}
@@ -0,0 +1,191 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bradleyfalzon/ghinstallation"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/form3tech-oss/github-team-approver/internal/api/secret"
+)
+
+// Clients hands out clients authenticating as each installation of the GitHub App, so that a single deployment can
+// serve every organisation the app is installed in. Clients are cached per installation, which lets them reuse their
+// installation token until it expires and is refreshed.
+type Clients struct {
+	store secret.Store
+	// base is the transport shared by all installations, so that connections are reused.
+	base http.RoundTripper
+
+	mu sync.Mutex
+	// clients caches the client of each installation, keyed by installation ID.
+	clients map[int64]*Client
+	// installations caches the ID of the installation of the app in each organisation, keyed by owner login, until the
+	// installation is found to no longer be valid.
+	installations map[string]int64
+}
+
+func NewClients(store secret.Store) *Clients {
+	return &Clients{
+		store:         store,
+		base:          baseTransport(),
+		clients:       map[int64]*Client{},
+		installations: map[string]int64{},
+	}
+}
+
+// ForInstallation returns the client authenticating as the given installation, as found in the payload of webhook
+// events. The installation set by GITHUB_APP_INSTALLATION_ID is used when the ID is zero, if any. An error is returned
+// if the client can't authenticate as the installation, rather than making requests GitHub would answer as if the
+// repositories of private installations didn't exist.
+func (c *Clients) ForInstallation(installationID int64) (*Client, error) {
+	if installationID == 0 {
+		installationID = defaultInstallationID()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if client, ok := c.clients[installationID]; ok {
+		return client, nil
+	}
+	base := &forgettingTransport{
+		transport: c.base,
+		forget:    func() { c.forget(installationID) },
+	}
+	transport, err := installationTransport(base, c.store, installationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create authenticating transport for installation %d: %w", installationID, err)
+	}
+	client := newClient(transport)
+	c.clients[installationID] = client
+	return client, nil
+}
+
+// forget drops the client of the given installation, and the organisations it was looked up for, so that the
+// installation is looked up again the next time the app is requested for them.
+func (c *Clients) forget(installationID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.clients, installationID)
+	for ownerLogin, id := range c.installations {
+		if id == installationID {
+			delete(c.installations, ownerLogin)
+		}
+	}
+}
+
+// ForRepository returns the client authenticating as the installation of the app for the given repository, for
+// requests which don't originate from a webhook event. The installation is looked up authenticating as the app itself.
+func (c *Clients) ForRepository(ctx context.Context, ownerLogin, repoName string) (*Client, error) {
+	c.mu.Lock()
+	installationID, ok := c.installations[ownerLogin]
+	c.mu.Unlock()
+	if ok {
+		return c.ForInstallation(installationID)
+	}
+
+	transport, err := appTransport(c.base, c.store)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create app transport: %w", err)
+	}
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, DefaultGitHubOperationTimeout)
+	defer cancel()
+	installation, resp, err := newClient(transport).githubClient.Apps.FindRepositoryInstallation(ctxTimeout, ownerLogin, repoName)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNoInstallation
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error finding installation for %s/%s: %w", ownerLogin, repoName, err)
+	}
+
+	// Installations are per organisation or user account, whatever the repositories they grant access to.
+	c.mu.Lock()
+	c.installations[ownerLogin] = installation.GetID()
+	c.mu.Unlock()
+	return c.ForInstallation(installation.GetID())
+}
+
+// forgettingTransport makes the requests of an installation of the app, both for its tokens and authenticated as it,
+// calling forget when the installation is no longer valid, i.e. when it isn't found as its token is requested or GitHub
+// rejects its token. This happens when the app is uninstalled, or reinstalled under a different installation ID.
+type forgettingTransport struct {
+	transport http.RoundTripper
+	forget    func()
+}
+
+func (t *forgettingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.transport.RoundTrip(req)
+	if resp != nil && (resp.StatusCode == http.StatusUnauthorized || (resp.StatusCode == http.StatusNotFound && strings.HasSuffix(req.URL.Path, "/access_tokens"))) {
+		t.forget()
+	}
+	return resp, err
+}
+
+// installationTransport authenticates requests as the given installation of the app, refreshing the installation token
+// as it expires.
+func installationTransport(base http.RoundTripper, store secret.Store, installationID int64) (http.RoundTripper, error) {
+	if installationID == 0 {
+		return nil, fmt.Errorf("no installation id")
+	}
+	applicationID, privateKey, err := appCredentials(store)
+	if err != nil {
+		return nil, err
+	}
+	transport, err := ghinstallation.New(base, applicationID, installationID, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	if v := os.Getenv(envGitHubBaseURL); v != "" {
+		transport.BaseURL = strings.TrimSuffix(v, "/")
+	}
+	return transport, nil
+}
+
+// appTransport authenticates requests as the app itself, as opposed to one of its installations.
+func appTransport(base http.RoundTripper, store secret.Store) (http.RoundTripper, error) {
+	applicationID, privateKey, err := appCredentials(store)
+	if err != nil {
+		return nil, err
+	}
+	transport, err := ghinstallation.NewAppsTransport(base, applicationID, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	if v := os.Getenv(envGitHubBaseURL); v != "" {
+		transport.BaseURL = strings.TrimSuffix(v, "/")
+	}
+	return transport, nil
+}
+
+func appCredentials(store secret.Store) (int64, []byte, error) {
+	applicationID, err := strconv.ParseInt(os.Getenv(envGitHubAppId), 10, 64)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to parse application id: %w", err)
+	}
+	privateKey, err := store.Get(envGitHubAppPrivateKeyPath)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read private key: %w", err)
+	}
+	return applicationID, privateKey, nil
+}
+
+// defaultInstallationID returns the installation set by GITHUB_APP_INSTALLATION_ID, or zero if there is none.
+func defaultInstallationID() int64 {
+	v := os.Getenv(envGitHubAppInstallationId)
+	if v == "" {
+		return 0
+	}
+	installationID, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		log.WithError(err).Warn("failed to parse installation id")
+		return 0
+	}
+	return installationID
+}
@@ -2,8 +2,14 @@ package stages
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	ghclient "github.com/form3tech-oss/github-team-approver/internal/api/github"
 	"github.com/form3tech-oss/github-team-approver/internal/api/secret"
@@ -21,6 +27,12 @@ type ClientStage struct {
 
 	response    *github.Response
 	errResponse error
+
+	clients []*ghclient.Client
+	// prErrs holds the error getting the PR for the repository each time it was got, in order.
+	prErrs []error
+	// repoClients hands out the clients the PR is got for the repository with.
+	repoClients *ghclient.Clients
 }
 
 func ClientTest(t *testing.T) (*ClientStage, *ClientStage, *ClientStage) {
@@ -113,3 +125,87 @@ func (c *ClientStage) CommentsDeleted() {
 	c.commentMsg = "some message"
 	c.fakeGitHub.SetIssueComments([]*github.IssueComment{})
 }
+
+func (c *ClientStage) AppInstalled() *ClientStage {
+	return c.appInstalledWithTokenLifetime(time.Hour)
+}
+
+// AppInstalledWithExpiringTokens issues installation tokens which are about to expire, and are hence refreshed on use.
+func (c *ClientStage) AppInstalledWithExpiringTokens() *ClientStage {
+	return c.appInstalledWithTokenLifetime(30 * time.Second)
+}
+
+func (c *ClientStage) appInstalledWithTokenLifetime(lifetime time.Duration) *ClientStage {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(c.t, err)
+	path := filepath.Join(c.t.TempDir(), "private-key.pem")
+	err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0600)
+	require.NoError(c.t, err)
+
+	c.setupEnv("GITHUB_APP_ID", "1")
+	c.setupEnv("GITHUB_APP_PRIVATE_KEY_PATH", path)
+	c.fakeGitHub.SetAppInstalled(lifetime)
+
+	return c
+}
+
+func (c *ClientStage) GettingPullRequestAsInstallations(installationIDs ...int64) *ClientStage {
+	clients := ghclient.NewClients(secret.NewEnvSecretStore())
+	for _, id := range installationIDs {
+		client, err := clients.ForInstallation(id)
+		require.NoError(c.t, err)
+		_, err = client.GetPullRequest(context.TODO(), c.fakeGitHub.Org().OwnerName, c.fakeGitHub.Repo().Name, c.fakeGitHub.PR().PRNumber)
+		require.NoError(c.t, err)
+		c.clients = append(c.clients, client)
+	}
+
+	return c
+}
+
+func (c *ClientStage) ExpectClientReusedForSameInstallation() *ClientStage {
+	require.Len(c.t, c.clients, 3)
+	require.Same(c.t, c.clients[0], c.clients[1])
+	require.NotSame(c.t, c.clients[0], c.clients[2])
+	return c
+}
+
+func (c *ClientStage) ExpectRequestsAuthenticatedAsEachInstallation() *ClientStage {
+	require.Equal(c.t, []string{"token installation-1", "token installation-1", "token installation-2"}, c.fakeGitHub.PRAuthorizations())
+	return c
+}
+
+func (c *ClientStage) ExpectOneTokenIssuedPerInstallation() *ClientStage {
+	require.Equal(c.t, []string{"1", "2"}, c.fakeGitHub.IssuedTokens())
+	return c
+}
+
+func (c *ClientStage) ExpectTokenRefreshedOnEveryRequest() *ClientStage {
+	require.Equal(c.t, []string{"1", "1", "2"}, c.fakeGitHub.IssuedTokens())
+	return c
+}
+
+// AppReinstalled uninstalls the app, and installs it again as installation 2.
+func (c *ClientStage) AppReinstalled() *ClientStage {
+	c.fakeGitHub.ReinstallApp(2)
+	return c
+}
+
+func (c *ClientStage) GettingPullRequestForRepository() *ClientStage {
+	if c.repoClients == nil {
+		c.repoClients = ghclient.NewClients(secret.NewEnvSecretStore())
+	}
+	client, err := c.repoClients.ForRepository(context.TODO(), c.fakeGitHub.Org().OwnerName, c.fakeGitHub.Repo().Name)
+	require.NoError(c.t, err)
+	_, err = client.GetPullRequest(context.TODO(), c.fakeGitHub.Org().OwnerName, c.fakeGitHub.Repo().Name, c.fakeGitHub.PR().PRNumber)
+	c.prErrs = append(c.prErrs, err)
+	return c
+}
+
+func (c *ClientStage) ExpectInstallationLookedUpAgainOnceRejected() *ClientStage {
+	require.Len(c.t, c.prErrs, 3)
+	require.NoError(c.t, c.prErrs[0])
+	require.Error(c.t, c.prErrs[1])
+	require.NoError(c.t, c.prErrs[2])
+	require.Equal(c.t, "token installation-2", c.fakeGitHub.PRAuthorizations()[len(c.fakeGitHub.PRAuthorizations())-1])
+	return c
+}
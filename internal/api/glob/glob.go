@@ -0,0 +1,83 @@
+// Package glob translates doublestar glob patterns into regular expressions.
+package glob
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// IsPattern returns true if the given string contains any glob metacharacter.
+func IsPattern(s string) bool {
+	return strings.ContainsAny(s, "*?[{")
+}
+
+// ToRegexp translates a doublestar glob pattern into a regular expression (without anchors).
+// "*" and "?" don't match path separators, "**" matches any number of path segments, "[...]" matches a character
+// class and "{a,b}" matches any of the given alternatives.
+func ToRegexp(pattern string) (string, error) {
+	var b strings.Builder
+	alternations := 0
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '*' && strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(.*/)?")
+			i += 2
+		case c == '*' && strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				return "", fmt.Errorf("invalid pattern %q: unterminated character class", pattern)
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '{':
+			alternations++
+			b.WriteString("(")
+		case c == ',' && alternations > 0:
+			b.WriteString("|")
+		case c == '}' && alternations > 0:
+			alternations--
+			b.WriteString(")")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if alternations > 0 {
+		return "", fmt.Errorf("invalid pattern %q: unterminated alternation", pattern)
+	}
+	return b.String(), nil
+}
+
+// Compile translates a doublestar glob pattern into a regular expression matching whole strings. Character classes the
+// regular expression can't hold (e.g. "[]" or "[z-a]") are reported as errors.
+func Compile(pattern string) (*regexp.Regexp, error) {
+	expr, err := ToRegexp(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regex, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return regex, nil
+}
+
+// Match returns true if the whole of the given string matches the glob pattern.
+func Match(pattern, s string) (bool, error) {
+	regex, err := Compile(pattern)
+	if err != nil {
+		return false, err
+	}
+	return regex.MatchString(s), nil
+}
@@ -0,0 +1,52 @@
+package glob
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		s       string
+		want    bool
+	}{
+		{pattern: "release/*", s: "release/1.2", want: true},
+		{pattern: "release/*", s: "release/1.2/hotfix", want: false},
+		{pattern: "release/**", s: "release/1.2/hotfix", want: true},
+		{pattern: "**/values.yaml", s: "values.yaml", want: true},
+		{pattern: "**/values.yaml", s: "charts/api/values.yaml", want: true},
+		{pattern: "v?.x", s: "v1.x", want: true},
+		{pattern: "v?.x", s: "v10.x", want: false},
+		{pattern: "env-[0-9]", s: "env-1", want: true},
+		{pattern: "env-[!0-9]", s: "env-1", want: false},
+		{pattern: "{main,master}", s: "master", want: true},
+		{pattern: "{main,master}", s: "develop", want: false},
+		{pattern: "feature.x", s: "featureAx", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.s, func(t *testing.T) {
+			matched, err := Match(tt.pattern, tt.s)
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, matched)
+		})
+	}
+}
+
+func TestMatchInvalidPattern(t *testing.T) {
+	_, err := Match("{main,master", "main")
+	require.EqualError(t, err, `invalid pattern "{main,master": unterminated alternation`)
+
+	_, err = Match("env-[0-9", "env-1")
+	require.EqualError(t, err, `invalid pattern "env-[0-9": unterminated character class`)
+}
+
+func TestCompileInvalidCharacterClass(t *testing.T) {
+	for _, pattern := range []string{"env-[]", "env-[z-a]"} {
+		_, err := Compile(pattern)
+		require.Error(t, err, pattern)
+	}
+}
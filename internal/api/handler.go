@@ -14,11 +14,12 @@ import (
 )
 
 const (
-	logFieldDeliveryID  = "delivery_id"
-	logFieldEventType   = "event_type"
-	logFieldPR          = "pr"
-	logFieldRepo        = "repo"
-	logFieldServiceName = "service_name"
+	logFieldDeliveryID     = "delivery_id"
+	logFieldEventType      = "event_type"
+	logFieldInstallationID = "installation_id"
+	logFieldPR             = "pr"
+	logFieldRepo           = "repo"
+	logFieldServiceName    = "service_name"
 
 	httpHeaderXFinalStatus    = "X-Final-Status"
 	httpHeaderXGithubDelivery = "X-GitHub-Delivery"
@@ -66,6 +67,17 @@ func (api *API) Handle(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if api.isRedelivery(log, deliveryID) {
+		log.Info("ignoring event: already delivered")
+		sendHttpOkResponse(w)
+		return
+	}
+
+	if isTeamEvent(eventType) {
+		api.handleTeamEvent(w, log, eventType, deliveryID, body)
+		return
+	}
+
 	event, err := getSupportedEvent(eventType)
 	if err != nil {
 		log.WithError(err).Warn("not handled")
@@ -82,8 +94,9 @@ func (api *API) Handle(w http.ResponseWriter, req *http.Request) {
 	repoName := event.GetRepo().GetFullName()
 	log = log.WithFields(
 		logrus.Fields{
-			logFieldRepo: repoName,
-			logFieldPR:   event.GetPullRequest().GetNumber(),
+			logFieldRepo:           repoName,
+			logFieldPR:             event.GetPullRequest().GetNumber(),
+			logFieldInstallationID: event.GetInstallation().GetID(),
 		})
 
 	if isMember(api.ignoredRepositories, repoName) {
@@ -91,22 +104,51 @@ func (api *API) Handle(w http.ResponseWriter, req *http.Request) {
 		sendHttpNoContentResponse(w)
 		return
 	}
-	client := ghclient.New(api.SecretStore)
 
-	ctx := context.Background()
-	if isPrMergeEvent(event) {
-		mergeHandler := NewMergeEventHandler(api, log, client)
-		if err := mergeHandler.handlePrMergeEvent(ctx, event); err != nil {
-			sendHttpInternalServerErrorResponse(w, fmt.Errorf("failed to handle event: %w", err))
+	if isManagedLabelChange(eventType, event) {
+		log.Debug("ignoring event: change of a label managed by github-team-approver")
+		sendHttpNoContentResponse(w)
+		return
+	}
+
+	// Evaluations of the same PR are serialized, and skipped if superseded by a more recent event for the PR.
+	j := &job{log: log, eventType: eventType, event: event, deliveryID: deliveryID}
+	var key string
+	if isEvaluated(eventType, event) {
+		key = prEventsKey(event)
+		j.seq = api.prEvents.received(key)
+	}
+
+	if api.eventQueue != nil {
+		enqueue := api.eventQueue.enqueue
+		if key != "" {
+			// Coalesce bursts of events for the PR into a single evaluation of the latest one.
+			enqueue = func(j *job) error { return api.eventQueue.enqueueDebounced(key, j) }
+		}
+		if err := enqueue(j); err != nil {
+			log.WithError(err).Error("failed to enqueue event")
+			api.forgetDelivery(log, deliveryID)
+			sendHttpResponse(w, http.StatusServiceUnavailable, err.Error())
 			return
 		}
-		sendHttpOkResponse(w)
+		sendHttpResponse(w, http.StatusAccepted, "")
 		return
 	}
 
-	handler := NewPullRequestEventHandler(api, log, client)
-
-	status, err := handler.handleEvent(ctx, eventType, event)
+	var status string
+	if key == "" {
+		status, err = api.processEvent(context.Background(), log, eventType, event)
+	} else {
+		err = api.prEvents.serialize(key, j.seq, func() (err error) {
+			status, err = api.processEvent(context.Background(), log, eventType, event)
+			return err
+		})
+	}
+	if errors.Is(err, errEventSuperseded) {
+		log.WithError(err).Info("ignoring event")
+		sendHttpResponse(w, http.StatusAccepted, err.Error())
+		return
+	}
 	if errors.Is(err, ghclient.ErrNoConfigurationFile) {
 		log.WithError(err).Warn("ignoring event")
 		sendHttpNoContentResponse(w)
@@ -116,14 +158,88 @@ func (api *API) Handle(w http.ResponseWriter, req *http.Request) {
 		log.WithField("event", event).
 			WithError(err).
 			Warn("failed to handle event")
+		api.forgetDelivery(log, deliveryID)
 		sendHttpInternalServerErrorResponse(w, fmt.Errorf("failed to handle event: %w", err))
 		return
 	}
+	if isPrMergeEvent(event) {
+		sendHttpOkResponse(w)
+		return
+	}
 
 	sendHttpOkWithStatusResponse(w, status)
 	return
 }
 
+// isRedelivery records the delivery, returning whether it was already handled. Deliveries are handled if they can't be
+// recorded, as they would rather be handled twice than not at all.
+func (api *API) isRedelivery(log *logrus.Entry, deliveryID string) bool {
+	if api.deliveries == nil || deliveryID == "" {
+		return false
+	}
+	recorded, err := api.deliveries.Record(deliveryID)
+	if err != nil {
+		log.WithError(err).Warn("failed to record delivery")
+		return false
+	}
+	if !recorded {
+		api.metrics.deliveriesDuplicated.Add(1)
+	}
+	return !recorded
+}
+
+// forgetDelivery forgets about a delivery which failed to be handled, so that it is handled if GitHub delivers it again.
+func (api *API) forgetDelivery(log *logrus.Entry, deliveryID string) {
+	if api.deliveries == nil || deliveryID == "" {
+		return
+	}
+	if err := api.deliveries.Forget(deliveryID); err != nil {
+		log.WithError(err).Warn("failed to forget delivery")
+	}
+}
+
+// processEvent handles a supported event, returning the approval status reported for the PR if any.
+func (api *API) processEvent(ctx context.Context, log *logrus.Entry, eventType string, event event) (string, error) {
+	// Authenticate as the installation the event was delivered for, so that every organisation the app is installed in is served.
+	client, err := api.clients.ForInstallation(event.GetInstallation().GetID())
+	if err != nil {
+		return "", err
+	}
+
+	if isPrMergeEvent(event) {
+		mergeHandler := NewMergeEventHandler(api, log, client)
+		return "", mergeHandler.handlePrMergeEvent(ctx, event)
+	}
+
+	handler := NewPullRequestEventHandler(api, log, client)
+	return handler.handleEvent(ctx, eventType, event)
+}
+
+// processJob processes an event taken from the event queue. Events of repositories without a configuration file are
+// ignored rather than retried, and so are events superseded by a more recent event for the same PR.
+func (api *API) processJob(ctx context.Context, j *job) error {
+	process := func() error {
+		_, err := api.processEvent(ctx, j.log, j.eventType, j.event)
+		return err
+	}
+	var err error
+	if j.seq == 0 {
+		err = process()
+	} else {
+		err = api.prEvents.serialize(prEventsKey(j.event), j.seq, process)
+	}
+	if errors.Is(err, errEventSuperseded) {
+		j.log.WithError(err).Debug("ignoring event")
+		api.metrics.eventsCoalesced.Add(1)
+		return nil
+	}
+	if errors.Is(err, ghclient.ErrNoConfigurationFile) {
+		j.log.WithError(err).Warn("ignoring event")
+		return nil
+	}
+	return err
+}
+
 func (api *API) validateSignature(signature string, body []byte) error {
 	if api.githubWebhookSecretToken == nil {
 		// TODO we should make this more clear, following what we had from before now
@@ -2,6 +2,10 @@ package api
 
 import (
 	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -30,6 +34,12 @@ var (
 
 func Test_Handle(t *testing.T) {
 	PactTest(t)
+	// Events are handled authenticating as their installation, whose token is created as described by the
+	// "app_installation_token_*" pacts.
+	t.Setenv("GITHUB_APP_ID", "1")
+	t.Setenv("GITHUB_APP_PRIVATE_KEY_PATH", writeAppPrivateKey(t))
+	// Events are processed within the webhook request, so that the response carries their final status.
+	t.Setenv("EVENT_QUEUE_WORKERS", "0")
 	tests := []struct {
 		name string
 
@@ -47,7 +57,9 @@ func Test_Handle(t *testing.T) {
 			eventBody:      readGitHubExampleFile("pull_request_opened.json"),
 			eventSignature: "sha256=6d4d96d879720606802102a5892b51634c25d52f7827d2d9d0113cef17709c0e",
 			pacts: []pacttesting.Pact{
+				"app_installation_token_1116708",
 				"pull_request_opened_pending",
+				"pull_request_get_comments_pr_5",
 				"issue_events_pr_5",
 			},
 
@@ -59,7 +71,11 @@ func Test_Handle(t *testing.T) {
 			eventType:      eventTypePullRequest,
 			eventBody:      readGitHubExampleFile("pull_request_opened_no_rules_for_branch.json"),
 			eventSignature: "sha256=f91b8ed784708050a1c332b07376a014a1f1e4ef1f94fe37d9532a37417c5bf6",
-			pacts:          []pacttesting.Pact{"pull_request_opened_no_rules_for_branch"},
+			pacts: []pacttesting.Pact{
+				"app_installation_token_1116708",
+				"pull_request_opened_no_rules_for_branch",
+				"pull_request_get_comments_pr_5",
+			},
 
 			expectedFinalStatus: approval.StatusEventStatusSuccess,
 		},
@@ -70,7 +86,9 @@ func Test_Handle(t *testing.T) {
 			eventBody:      readGitHubExampleFile("pull_request_review_submitted.json"),
 			eventSignature: "sha256=802a01c378001fbbbea8f59e7d5eab688550bcbd097491abc907d8850cef6e17",
 			pacts: []pacttesting.Pact{
+				"app_installation_token_1116708",
 				"pull_request_review_submitted_approved",
+				"pull_request_get_comments_pr_7",
 				"issue_events_pr_7",
 			},
 
@@ -83,7 +101,9 @@ func Test_Handle(t *testing.T) {
 			eventBody:      readGitHubExampleFile("pull_request_review_submitted.json"),
 			eventSignature: "sha256=802a01c378001fbbbea8f59e7d5eab688550bcbd097491abc907d8850cef6e17",
 			pacts: []pacttesting.Pact{
+				"app_installation_token_1116708",
 				"pull_request_review_submitted_pending",
+				"pull_request_get_comments_pr_7",
 				"issue_events_pr_7",
 			},
 
@@ -96,7 +116,9 @@ func Test_Handle(t *testing.T) {
 			eventBody:      readGitHubExampleFile("pull_request_review_submitted_force_approval.json"),
 			eventSignature: "sha256=c4d9e2a311de0322c4b7c09c1a2239d23668542c9caf187be03c7acb62f3ca5b",
 			pacts: []pacttesting.Pact{
+				"app_installation_token_1116708",
 				"pull_request_review_submitted_force_approval",
+				"pull_request_get_comments_pr_7",
 				"issue_events_pr_7",
 			},
 
@@ -109,7 +131,9 @@ func Test_Handle(t *testing.T) {
 			eventBody:      readGitHubExampleFile("pull_request_review_submitted_no_regexes_matched.json"),
 			eventSignature: "sha256=9b5e234c6deff549b631d7e08363e9e90e0bdf635e3a440e2b40cef5fab3205a",
 			pacts: []pacttesting.Pact{
+				"app_installation_token_1116708",
 				"pull_request_review_submitted_no_regexes_matched",
+				"pull_request_get_comments_pr_7",
 			},
 
 			expectedFinalStatus: approval.StatusEventStatusPending,
@@ -121,7 +145,9 @@ func Test_Handle(t *testing.T) {
 			eventBody:      readGitHubExampleFile("pull_request_review_submitted.json"),
 			eventSignature: "sha256=802a01c378001fbbbea8f59e7d5eab688550bcbd097491abc907d8850cef6e17",
 			pacts: []pacttesting.Pact{
+				"app_installation_token_1116708",
 				"pull_request_review_submitted_approval_mode_require_any",
+				"pull_request_get_comments_pr_7",
 				"issue_events_pr_7",
 			},
 
@@ -134,6 +160,7 @@ func Test_Handle(t *testing.T) {
 			eventBody:      readGitHubExampleFile("pull_request_merged_to_master.json"),
 			eventSignature: "sha256=2324407137f738fc9e5e335e5ed6d52ab5d8a8b33705937d04463d7b9c678fcd",
 			pacts: []pacttesting.Pact{
+				"app_installation_token_1516705",
 				"pull_request_merged_single_alert",
 				"slack_post_message_for_emergency_change",
 			},
@@ -153,6 +180,7 @@ func Test_Handle(t *testing.T) {
 			eventBody:      readGitHubExampleFile("pull_request_review_submitted.json"),
 			eventSignature: "sha256=802a01c378001fbbbea8f59e7d5eab688550bcbd097491abc907d8850cef6e17",
 			pacts: []pacttesting.Pact{
+				"app_installation_token_1116708",
 				"pull_request_commits_alice_contributed",
 				"pull_request_get_comments_pr_7",
 				"pull_request_post_comment_pr_7",
@@ -169,8 +197,10 @@ func Test_Handle(t *testing.T) {
 			eventBody:      readGitHubExampleFile("pull_request_review_submitted.json"),
 			eventSignature: "sha256=802a01c378001fbbbea8f59e7d5eab688550bcbd097491abc907d8850cef6e17",
 			pacts: []pacttesting.Pact{
+				"app_installation_token_1116708",
 				"pull_request_commits_alice_contributed",
 				"pull_request_review_submitted_alice_bob_approved",
+				"pull_request_get_comments_pr_7",
 				"issue_events_pr_7",
 			},
 
@@ -183,8 +213,10 @@ func Test_Handle(t *testing.T) {
 			eventBody:      readGitHubExampleFile("pull_request_review_submitted.json"),
 			eventSignature: "sha256=802a01c378001fbbbea8f59e7d5eab688550bcbd097491abc907d8850cef6e17",
 			pacts: []pacttesting.Pact{
+				"app_installation_token_1116708",
 				"pull_request_commits_alice_coauthor",
 				"pull_request_review_submitted_alice_bob_approved",
+				"pull_request_get_comments_pr_7",
 				"issue_events_pr_7",
 			},
 
@@ -197,6 +229,7 @@ func Test_Handle(t *testing.T) {
 			eventBody:      readGitHubExampleFile("pull_request_review_submitted.json"),
 			eventSignature: "sha256=802a01c378001fbbbea8f59e7d5eab688550bcbd097491abc907d8850cef6e17",
 			pacts: []pacttesting.Pact{
+				"app_installation_token_1116708",
 				"pull_request_commits_pr_7",
 				"pull_request_get_comments_pr_7",
 				"pull_request_post_comment_pr_7",
@@ -258,6 +291,19 @@ func Test_Handle(t *testing.T) {
 	}
 }
 
+// writeAppPrivateKey writes a private key for the app, returning its path.
+func writeAppPrivateKey(t *testing.T) string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := path.Join(t.TempDir(), "private-key.pem")
+	if err := os.WriteFile(p, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
 func buildRequest(eventType string, eventBody []byte, eventSignature string) *http.Request {
 	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(eventBody))
 	r.Header.Set(httpHeaderXGithubEvent, eventType)
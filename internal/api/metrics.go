@@ -0,0 +1,66 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	metricsPath   = "/metrics"
+	metricsPrefix = "github_team_approver_"
+)
+
+// metrics holds the counters exposed in the Prometheus text format for monitoring.
+type metrics struct {
+	queueDepth      atomic.Int64
+	eventsProcessed atomic.Int64
+	eventsRetried   atomic.Int64
+	eventsFailed    atomic.Int64
+	eventsRejected  atomic.Int64
+	eventsCoalesced atomic.Int64
+
+	deliveriesDuplicated atomic.Int64
+
+	teamChanges    atomic.Int64
+	prsReevaluated atomic.Int64
+}
+
+type metric struct {
+	name  string
+	kind  string
+	help  string
+	value *atomic.Int64
+}
+
+func (m *metrics) all() []metric {
+	return []metric{
+		{"event_queue_depth", "gauge", "Number of events waiting to be processed.", &m.queueDepth},
+		{"events_processed_total", "counter", "Number of events processed successfully in the background.", &m.eventsProcessed},
+		{"events_retried_total", "counter", "Number of failed attempts at processing an event which were retried.", &m.eventsRetried},
+		{"events_failed_total", "counter", "Number of events given up on after failing to process them.", &m.eventsFailed},
+		{"events_rejected_total", "counter", "Number of events rejected because the event queue was full.", &m.eventsRejected},
+		{"events_coalesced_total", "counter", "Number of events not evaluated as superseded by a more recent event for the same pull request.", &m.eventsCoalesced},
+		{"deliveries_duplicated_total", "counter", "Number of deliveries acknowledged without being handled as they were already handled.", &m.deliveriesDuplicated},
+		{"team_changes_total", "counter", "Number of team changes processed by re-evaluating the open pull requests.", &m.teamChanges},
+		{"pull_requests_reevaluated_total", "counter", "Number of pull requests whose status was reported again following a team change.", &m.prsReevaluated},
+	}
+}
+
+// HandleMetrics serves "GET /metrics", exposing the metrics in the Prometheus text format.
+func (api *API) HandleMetrics(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		sendHttpMethodNotAllowedResponse(w, fmt.Errorf("unsupported method %q", req.Method))
+		return
+	}
+
+	var b strings.Builder
+	for _, m := range api.metrics.all() {
+		fmt.Fprintf(&b, "# HELP %s%s %s\n", metricsPrefix, m.name, m.help)
+		fmt.Fprintf(&b, "# TYPE %s%s %s\n", metricsPrefix, m.name, m.kind)
+		fmt.Fprintf(&b, "%s%s %d\n", metricsPrefix, m.name, m.value.Load())
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	sendHttpResponse(w, http.StatusOK, b.String())
+}
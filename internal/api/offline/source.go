@@ -0,0 +1,203 @@
+package offline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/google/go-github/v42/github"
+
+	"github.com/form3tech-oss/github-team-approver/internal/api/approval"
+	"github.com/form3tech-oss/github-team-approver/internal/api/configuration"
+	ghclient "github.com/form3tech-oss/github-team-approver/internal/api/github"
+)
+
+// Files lists the files the data needed to evaluate a PR is read from. Only Config and Event are required.
+type Files struct {
+	// Config is the path to the configuration file.
+	Config string
+	// Event is the path to the payload of a "pull_request" or "pull_request_review" event.
+	Event string
+	// Reviews is the path to the reviews of the PR, as returned by the GitHub API.
+	Reviews string
+	// Teams is the path to the teams of the organisation, each listing the logins of its members under "members".
+	Teams string
+	// Commits is the path to the commits of the PR, as returned by the GitHub API.
+	Commits string
+	// CommitFiles is the path to the files changed by the PR, as returned by the GitHub API.
+	CommitFiles string
+	// Codeowners is the path to the CODEOWNERS file of the repository.
+	Codeowners string
+}
+
+// team is a team of the organisation along with the logins of its members.
+type team struct {
+	github.Team
+	Members []string `json:"members"`
+}
+
+// Source serves the data needed to evaluate a PR from files rather than from GitHub. It implements
+// approval.DataSource.
+type Source struct {
+	configuration *configuration.Configuration
+	event         *github.PullRequestEvent
+	reviews       []*github.PullRequestReview
+	teams         []team
+	commits       []*github.RepositoryCommit
+	commitFiles   []*github.CommitFile
+	codeowners    *string
+}
+
+var _ approval.DataSource = (*Source)(nil)
+
+// Load reads the given files.
+func Load(files Files) (*Source, error) {
+	cfg, err := ReadConfiguration(files.Config)
+	if err != nil {
+		return nil, err
+	}
+	s := &Source{configuration: cfg}
+
+	if files.Event == "" {
+		return nil, fmt.Errorf("the path to the event is required")
+	}
+	if err := readJSON(files.Event, &s.event); err != nil {
+		return nil, err
+	}
+	if s.event.GetPullRequest() == nil {
+		return nil, fmt.Errorf("%s is not the payload of a pull request event", files.Event)
+	}
+	if err := readJSON(files.Reviews, &s.reviews); err != nil {
+		return nil, err
+	}
+	if err := readJSON(files.Teams, &s.teams); err != nil {
+		return nil, err
+	}
+	if err := readJSON(files.Commits, &s.commits); err != nil {
+		return nil, err
+	}
+	if err := readJSON(files.CommitFiles, &s.commitFiles); err != nil {
+		return nil, err
+	}
+	if files.Codeowners != "" {
+		b, err := os.ReadFile(files.Codeowners)
+		if err != nil {
+			return nil, fmt.Errorf("error reading CODEOWNERS: %w", err)
+		}
+		codeowners := string(b)
+		s.codeowners = &codeowners
+	}
+	return s, nil
+}
+
+// ReadConfiguration reads and validates the configuration file at the given path.
+func ReadConfiguration(path string) (*configuration.Configuration, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return configuration.ReadConfiguration(f)
+}
+
+// readJSON decodes the JSON file at the given path into v, unless the path is empty.
+func readJSON(path string, v interface{}) error {
+	if path == "" {
+		return nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("error decoding %s: %w", path, err)
+	}
+	return nil
+}
+
+// PR returns the PR the event was sent for.
+func (s *Source) PR() *approval.PR {
+	pr := s.event.GetPullRequest()
+	labels := make([]string, 0, len(pr.Labels))
+	for _, l := range pr.Labels {
+		labels = append(labels, l.GetName())
+	}
+	p := approval.NewPR(
+		s.event.GetRepo().GetOwner().GetLogin(),
+		s.event.GetRepo().GetName(),
+		pr.GetBase().GetRef(),
+		pr.GetHead().GetRef(),
+		pr.GetHead().GetSHA(),
+		pr.GetTitle(),
+		pr.GetBody(),
+		pr.GetNumber(),
+		labels,
+		pr.GetUser(),
+	)
+	p.Milestone = pr.GetMilestone().GetTitle()
+	return p
+}
+
+// GetResolvedConfiguration returns the configuration file as is, whatever the source. Central configurations are not
+// supported offline.
+func (s *Source) GetResolvedConfiguration(_ context.Context, _, _, _ string, _ configuration.Source) (*configuration.Configuration, error) {
+	return configuration.Resolve(nil, s.configuration), nil
+}
+
+func (s *Source) GetCodeowners(_ context.Context, _, _, _ string) (string, error) {
+	if s.codeowners == nil {
+		return "", ghclient.ErrNoCodeownersFile
+	}
+	return *s.codeowners, nil
+}
+
+func (s *Source) GetTeams(_ context.Context, _ string) ([]*github.Team, error) {
+	teams := make([]*github.Team, 0, len(s.teams))
+	for i := range s.teams {
+		teams = append(teams, &s.teams[i].Team)
+	}
+	return teams, nil
+}
+
+func (s *Source) GetTeamMembers(_ context.Context, _ []*github.Team, organisation, name string) ([]*github.User, error) {
+	for _, t := range s.teams {
+		if t.GetName() != name {
+			continue
+		}
+		members := make([]*github.User, 0, len(t.Members))
+		for _, login := range t.Members {
+			members = append(members, &github.User{Login: github.String(login)})
+		}
+		return members, nil
+	}
+	return nil, fmt.Errorf("could not find team %q in organisation %q", name, organisation)
+}
+
+func (s *Source) GetPullRequestReviews(_ context.Context, _, _ string, _ int) ([]*github.PullRequestReview, error) {
+	return s.reviews, nil
+}
+
+func (s *Source) GetPullRequestCommitFiles(_ context.Context, _, _ string, _ int) ([]*github.CommitFile, error) {
+	return s.commitFiles, nil
+}
+
+func (s *Source) GetPRCommits(_ context.Context, _, _ string, _ int) ([]*github.RepositoryCommit, error) {
+	return s.commits, nil
+}
+
+// GetIssuesEvents returns no events, so that no reviewer is ignored for having reopened the PR.
+func (s *Source) GetIssuesEvents(_ context.Context, _, _ string, _ int) ([]*github.IssueEvent, error) {
+	return nil, nil
+}
+
+// GetLabels returns the labels of the PR at the time of the event.
+func (s *Source) GetLabels(_ context.Context, _, _ string, _ int) ([]string, error) {
+	return s.PR().InitialLabels, nil
+}
+
+// CompareCommits returns the files changed by the PR whatever the commit, as the diff as of previous commits is not
+// known offline. Approvals given for previous commits are hence never found to be stale because of a changed diff.
+func (s *Source) CompareCommits(_ context.Context, _, _, _, _ string) ([]*github.CommitFile, error) {
+	return s.commitFiles, nil
+}
@@ -0,0 +1,76 @@
+package offline
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/form3tech-oss/github-team-approver/internal/api/approval"
+	"github.com/form3tech-oss/github-team-approver/internal/api/configuration"
+)
+
+func TestSource_ComputeApprovalStatus(t *testing.T) {
+	tests := map[string]struct {
+		reviews          string
+		status           string
+		labels           []string
+		reviewsToRequest []string
+	}{
+		"no reviews": {
+			status:           approval.StatusEventStatusPending,
+			labels:           []string{"feature", "github-team-approver/approved-by-foo"},
+			reviewsToRequest: []string{"cab-foo"},
+		},
+		"commented": {
+			reviews:          "testdata/reviews_commented.json",
+			status:           approval.StatusEventStatusPending,
+			labels:           []string{"feature", "github-team-approver/approved-by-foo"},
+			reviewsToRequest: []string{"cab-foo"},
+		},
+		"approved": {
+			reviews: "testdata/reviews_approved.json",
+			status:  approval.StatusEventStatusSuccess,
+			labels:  []string{"feature", "github-team-approver/approved-by-foo"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			source, err := Load(Files{
+				Config:  "testdata/GITHUB_TEAM_APPROVER.yaml",
+				Event:   "testdata/pull_request_opened.json",
+				Reviews: tt.reviews,
+				Teams:   "testdata/teams.json",
+			})
+			require.NoError(t, err)
+
+			app := approval.NewApproval(logrus.NewEntry(logrus.StandardLogger()), source, approval.Settings{})
+			result, err := app.ComputeApprovalStatus(context.Background(), source.PR())
+
+			require.NoError(t, err)
+			require.Equal(t, tt.status, result.Status())
+			require.ElementsMatch(t, tt.labels, result.FinalLabels())
+			require.ElementsMatch(t, tt.reviewsToRequest, result.ReviewsToRequest())
+		})
+	}
+}
+
+func TestLoad_InvalidConfiguration(t *testing.T) {
+	_, err := Load(Files{
+		Config: "testdata/invalid.yaml",
+		Event:  "testdata/pull_request_opened.json",
+	})
+
+	var invalid *configuration.ValidationError
+	require.True(t, errors.As(err, &invalid))
+	require.Len(t, invalid.Problems, 1)
+}
+
+func TestLoad_RequiresEvent(t *testing.T) {
+	_, err := Load(Files{Config: "testdata/GITHUB_TEAM_APPROVER.yaml"})
+
+	require.Error(t, err)
+}
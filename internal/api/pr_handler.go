@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/form3tech-oss/github-team-approver/internal/api/approval"
@@ -16,10 +17,14 @@ const (
 	eventTypePullRequest       = "pull_request"
 	eventTypePullRequestReview = "pull_request_review"
 
-	pullRequestActionEdited      = "edited"
-	pullRequestActionOpened      = "opened"
-	pullRequestActionReopened    = "reopened"
-	pullRequestActionSynchronize = "synchronize"
+	pullRequestActionConvertedToDraft = "converted_to_draft"
+	pullRequestActionEdited           = "edited"
+	pullRequestActionLabeled          = "labeled"
+	pullRequestActionOpened           = "opened"
+	pullRequestActionReadyForReview   = "ready_for_review"
+	pullRequestActionReopened         = "reopened"
+	pullRequestActionSynchronize      = "synchronize"
+	pullRequestActionUnlabeled        = "unlabeled"
 
 	pullRequestReviewActionDismissed = "dismissed"
 	pullRequestReviewActionEdited    = pullRequestActionEdited
@@ -46,8 +51,6 @@ func (handler *PullRequestEventHandler) handleEvent(ctx context.Context, eventTy
 	var (
 		ownerLogin = event.GetRepo().GetOwner().GetLogin()
 		repoName   = event.GetRepo().GetName()
-		prNumber   = event.GetPullRequest().GetNumber()
-		prAuthor   = event.GetPullRequest().GetUser()
 	)
 
 	// Make sure the combination of event type and action is supported.
@@ -57,12 +60,19 @@ func (handler *PullRequestEventHandler) handleEvent(ctx context.Context, eventTy
 		return "", nil
 	}
 
-	prTargetBranch := event.GetPullRequest().GetBase().GetRef()
-	prBody := event.GetPullRequest().GetBody()
-	prLabels := getLabelNames(event.GetPullRequest().Labels)
+	// Evaluate the PR as it is now rather than as described by the event, which may be outdated if it was retried or
+	// waited for the evaluation of a previous event.
+	current, err := handler.client.GetPullRequest(ctx, ownerLogin, repoName, event.GetPullRequest().GetNumber())
+	if err != nil {
+		return "", fmt.Errorf("failed to get pull request: %w", err)
+	}
+	if current.GetDraft() && handler.api.draftPolicy == draftPolicySkip {
+		handler.log.Info("ignoring event: draft PR")
+		return "", nil
+	}
 
-	pr := approval.NewPR(ownerLogin, repoName, prTargetBranch, prBody, prNumber, prLabels, prAuthor)
-	app := approval.NewApproval(handler.log, handler.client)
+	pr := newPR(ownerLogin, repoName, current)
+	app := approval.NewApproval(handler.log, handler.client, handler.api.approvalSettings())
 	result, err := app.ComputeApprovalStatus(ctx, pr)
 	if errors.Is(err, ghclient.ErrNoConfigurationFile) {
 		return "", err
@@ -71,25 +81,33 @@ func (handler *PullRequestEventHandler) handleEvent(ctx context.Context, eventTy
 	if err != nil {
 		return "", fmt.Errorf("failed to compute status: %w", err)
 	}
+	return handler.report(ctx, pr, result)
+}
+
+// report reports the approval status of the PR, requests reviews from the approving teams, and updates the PR's labels
+// and its comment, returning the status reported.
+func (handler *PullRequestEventHandler) report(ctx context.Context, pr *approval.PR, result *approval.Result) (string, error) {
+	var (
+		ownerLogin = pr.OwnerLogin
+		repoName   = pr.RepoName
+		prNumber   = pr.Number
+	)
 
-	// Report the approval status, request reviews from the approving teams, and update the PR's labels.
-	ch := make(chan error, 3)
+	// Report the approval status, request reviews from the approving teams, update the PR's labels and its comment.
+	ch := make(chan error, 4)
 	wg := sync.WaitGroup{}
-	wg.Add(3)
+	wg.Add(4)
 
 	go func() {
 		defer wg.Done()
-		handler.log.Tracef("Reporting %q as the status", result.Status())
-		statusesURL := event.GetPullRequest().GetStatusesURL()
-		if err := handler.client.ReportStatus(ctx, ownerLogin, repoName, statusesURL, result.Status(), result.Description()); err != nil {
-			handler.log.WithError(err).Error("Failed to report status")
+		if err := handler.reportStatus(ctx, pr, result); err != nil {
 			ch <- err
 		}
 	}()
 	go func() {
 		defer wg.Done()
-		handler.log.Tracef("Requesting reviews from %v", result.ReviewsToRequest())
-		if err := handler.client.RequestReviews(ctx, ownerLogin, repoName, prNumber, result.ReviewsToRequest()); err != nil {
+		handler.log.Tracef("Requesting reviews from %v", append(result.ReviewsToRequest(), result.UserReviewsToRequest()...))
+		if err := handler.client.RequestReviews(ctx, ownerLogin, repoName, prNumber, result.ReviewsToRequest(), result.UserReviewsToRequest()); err != nil {
 			handler.log.WithError(err).Error("Failed to request reviews")
 			ch <- err
 		}
@@ -102,6 +120,15 @@ func (handler *PullRequestEventHandler) handleEvent(ctx context.Context, eventTy
 			ch <- err
 		}
 	}()
+	go func() {
+		defer wg.Done()
+		// An empty comment deletes the existing one, as no rules apply to the PR anymore or the configuration is invalid.
+		handler.log.Trace("Updating the summary comment")
+		if err := handler.client.ReportSummaryComment(ctx, ownerLogin, repoName, prNumber, result.Comment(), result.NeedsComment()); err != nil {
+			handler.log.WithError(err).Error("Failed to update the summary comment")
+			ch <- err
+		}
+	}()
 	wg.Wait()
 
 	// Propagate a single error, or return the computed state.
@@ -113,10 +140,36 @@ func (handler *PullRequestEventHandler) handleEvent(ctx context.Context, eventTy
 	}
 }
 
+// reportStatus publishes the approval status as a commit status, a check run, or both, depending on the settings.
+func (handler *PullRequestEventHandler) reportStatus(ctx context.Context, pr *approval.PR, result *approval.Result) error {
+	if handler.api.reportCommitStatus {
+		handler.log.Tracef("Reporting %q as the status of %s", result.Status(), pr.HeadSHA)
+		if err := handler.client.ReportStatus(ctx, pr.OwnerLogin, pr.RepoName, pr.HeadSHA, result.Status(), result.Description()); err != nil {
+			handler.log.WithError(err).Error("Failed to report status")
+			return err
+		}
+	}
+	if handler.api.reportCheckRun {
+		handler.log.Tracef("Reporting %q as the check run of %s", result.Status(), pr.HeadSHA)
+		title := strings.ReplaceAll(result.Description(), "\n", " ")
+		if err := handler.client.ReportCheckRun(ctx, pr.OwnerLogin, pr.RepoName, pr.HeadSHA, result.Status(), title, result.Summary()); err != nil {
+			handler.log.WithError(err).Error("Failed to report check run")
+			return err
+		}
+	}
+	return nil
+}
+
 func isSupportedAction(eventType, action string) bool {
 	switch {
 	case eventType == eventTypePullRequest:
-		return action == pullRequestActionEdited || action == pullRequestActionOpened || action == pullRequestActionReopened || action == pullRequestActionSynchronize
+		switch action {
+		case pullRequestActionConvertedToDraft, pullRequestActionEdited, pullRequestActionLabeled, pullRequestActionOpened,
+			pullRequestActionReadyForReview, pullRequestActionReopened, pullRequestActionSynchronize, pullRequestActionUnlabeled:
+			return true
+		default:
+			return false
+		}
 	case eventType == eventTypePullRequestReview:
 		return action == pullRequestReviewActionDismissed || action == pullRequestReviewActionEdited || action == pullRequestReviewActionSubmitted
 	default:
@@ -124,6 +177,41 @@ func isSupportedAction(eventType, action string) bool {
 	}
 }
 
+// isManagedLabelChange returns whether the event reports a label github-team-approver manages being added or removed.
+// As github-team-approver only ever changes the labels it manages, this covers the events caused by its own updates of
+// the labels of the PR, whose evaluation would only supersede the evaluation of more meaningful events.
+func isManagedLabelChange(eventType string, event event) bool {
+	e, ok := event.(*github.PullRequestEvent)
+	if !ok || eventType != eventTypePullRequest {
+		return false
+	}
+	switch e.GetAction() {
+	case pullRequestActionLabeled, pullRequestActionUnlabeled:
+		return approval.IsManagedLabel(e.GetLabel().GetName())
+	default:
+		return false
+	}
+}
+
+// newPR builds the PR whose approval status is computed from its details on GitHub.
+func newPR(ownerLogin, repoName string, pr *github.PullRequest) *approval.PR {
+	p := approval.NewPR(
+		ownerLogin,
+		repoName,
+		pr.GetBase().GetRef(),
+		pr.GetHead().GetRef(),
+		pr.GetHead().GetSHA(),
+		pr.GetTitle(),
+		pr.GetBody(),
+		pr.GetNumber(),
+		getLabelNames(pr.Labels),
+		pr.GetUser(),
+	)
+	p.Draft = pr.GetDraft()
+	p.Milestone = pr.GetMilestone().GetTitle()
+	return p
+}
+
 func getLabelNames(labels []*github.Label) []string {
 	if labels == nil {
 		return make([]string, 0, 0)
@@ -3,10 +3,11 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"regexp"
 
-	"github.com/form3tech-oss/github-team-approver-commons/v2/pkg/configuration"
+	"github.com/form3tech-oss/github-team-approver/internal/api/configuration"
 	"github.com/form3tech-oss/github-team-approver/internal/api/github"
 	"github.com/sirupsen/logrus"
 	"github.com/slack-go/slack"
@@ -43,6 +44,12 @@ func (handler *MergeEventHandler) handlePrMergeEvent(ctx context.Context, event
 	handler.log.Tracef("Computing the set of alerts that applies to target branch %q", prTargetBranch)
 
 	alerts, err := handler.computeAlertsForTargetBranch(ctx, ownerLogin, repoName, prTargetBranch)
+	var invalid *configuration.ValidationError
+	if errors.As(err, &invalid) {
+		// There is no status to report the problems on once the PR is merged.
+		handler.log.WithError(err).Warn("Ignoring alerts: invalid configuration")
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("could not compute alerts for target branch: %s on repo: %s, err: %w", prTargetBranch, repoName, err)
 	}
@@ -82,14 +89,22 @@ func (handler *MergeEventHandler) handlePrMergeEvent(ctx context.Context, event
 
 func (handler *MergeEventHandler) computeAlertsForTargetBranch(ctx context.Context, ownerLogin, repoName, targetBranch string) ([]configuration.Alert, error) {
 	// Get the configuration for approvals in the current repository.
-	cfg, err := handler.client.GetConfiguration(ctx, ownerLogin, repoName)
+	cfg, err := handler.client.GetResolvedConfiguration(ctx, ownerLogin, repoName, targetBranch, handler.api.configurationSource)
 	if err != nil {
 		return nil, err
 	}
+	for _, step := range cfg.Resolution {
+		handler.log.Tracef("Configuration resolved with %s", step)
+	}
 	// Compute the set of alerts that applies to the target branch.
 	var alerts []configuration.Alert
-	for _, prCfg := range cfg.PullRequestApprovalRules {
-		if len(prCfg.TargetBranches) == 0 || isMember(prCfg.TargetBranches, targetBranch) {
+	for i, prCfg := range cfg.PullRequestApprovalRules {
+		pattern, matched, err := prCfg.MatchTargetBranch(targetBranch)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			handler.log.WithField("rule_set", i).Tracef("Alerts selected by target branch pattern %q", pattern)
 			alerts = append(alerts, prCfg.Alerts...)
 		}
 	}
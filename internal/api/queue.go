@@ -0,0 +1,222 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultEventQueueWorkers      = 4
+	defaultEventQueueSize         = 100
+	defaultEventQueueMaxAttempts  = 5
+	defaultEventQueueRetryBackoff = time.Second
+	// eventQueueMaxRetryBackoff caps the exponential backoff between two attempts at processing an event.
+	eventQueueMaxRetryBackoff = time.Minute
+)
+
+var (
+	errEventQueueFull    = errors.New("event queue full")
+	errEventQueueStopped = errors.New("event queue stopped")
+)
+
+// job is an event waiting to be processed in the background.
+type job struct {
+	log       *logrus.Entry
+	eventType string
+	event     event
+	// seq is the number of the event among those received for its PR, if it is evaluated for the PR.
+	seq uint64
+	// deliveryID is the ID of the webhook delivery the event was received in.
+	deliveryID string
+	// attempts is the number of times processing the event was attempted.
+	attempts int
+}
+
+// eventQueue processes events in the background through a bounded pool of workers, so that webhook deliveries can be
+// acknowledged immediately. Failed jobs are retried with exponential backoff, up to a maximum number of attempts.
+type eventQueue struct {
+	jobs        chan *job
+	process     func(ctx context.Context, j *job) error
+	maxAttempts int
+	backoff     time.Duration
+	metrics     *metrics
+	// debounceWindow is how long debounced jobs wait for a more recent job with the same key to replace them.
+	debounceWindow time.Duration
+	// failed is called with the jobs given up on, if set.
+	failed func(j *job)
+
+	// ctx is the context jobs are processed in. It is only cancelled if stopping the queue times out.
+	ctx     context.Context
+	cancel  context.CancelFunc
+	workers sync.WaitGroup
+
+	// mu guards stopped, so that jobs are never sent once the channel of jobs is closed, and the debounced jobs.
+	mu      sync.Mutex
+	stopped bool
+	// debounced holds the jobs waiting for their debounce window to elapse, keyed by the key they were debounced with.
+	debounced map[string]*debouncedJob
+}
+
+// debouncedJob is the latest job received for a key, waiting for its debounce window to elapse.
+type debouncedJob struct {
+	job   *job
+	timer *time.Timer
+}
+
+func newEventQueue(size, maxAttempts int, backoff time.Duration, m *metrics, process func(ctx context.Context, j *job) error) *eventQueue {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &eventQueue{
+		jobs:        make(chan *job, size),
+		process:     process,
+		maxAttempts: maxAttempts,
+		backoff:     backoff,
+		metrics:     m,
+		ctx:         ctx,
+		cancel:      cancel,
+		debounced:   map[string]*debouncedJob{},
+	}
+}
+
+// start starts the given number of workers.
+func (q *eventQueue) start(workers int) {
+	q.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer q.workers.Done()
+			for j := range q.jobs {
+				q.metrics.queueDepth.Add(-1)
+				q.run(j)
+			}
+		}()
+	}
+}
+
+// enqueue adds a job to the queue, failing rather than blocking if the queue is full.
+func (q *eventQueue) enqueue(j *job) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.stopped {
+		return errEventQueueStopped
+	}
+	select {
+	case q.jobs <- j:
+		q.metrics.queueDepth.Add(1)
+		return nil
+	default:
+		q.metrics.eventsRejected.Add(1)
+		return errEventQueueFull
+	}
+}
+
+// enqueueDebounced adds a job to the queue once no other job with the same key was received for the debounce window,
+// so that a burst of jobs is coalesced into the latest one. The jobs replaced are counted as coalesced.
+func (q *eventQueue) enqueueDebounced(key string, j *job) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.stopped {
+		return errEventQueueStopped
+	}
+	if d, ok := q.debounced[key]; ok {
+		d.job.log.Debug("event coalesced with a more recent one")
+		q.metrics.eventsCoalesced.Add(1)
+		d.job = j
+		d.timer.Reset(q.debounceWindow)
+		return nil
+	}
+
+	d := &debouncedJob{job: j}
+	d.timer = time.AfterFunc(q.debounceWindow, func() {
+		q.mu.Lock()
+		// The timer fires again if it was reset while firing, once the job was already handed over.
+		if q.stopped || q.debounced[key] != d {
+			q.mu.Unlock()
+			return
+		}
+		delete(q.debounced, key)
+		q.mu.Unlock()
+
+		if err := q.enqueue(d.job); err != nil {
+			q.giveUp(d.job, err, "giving up on event: failed to enqueue")
+		}
+	})
+	q.debounced[key] = d
+	return nil
+}
+
+func (q *eventQueue) run(j *job) {
+	j.attempts++
+	err := q.process(q.ctx, j)
+	if err == nil {
+		q.metrics.eventsProcessed.Add(1)
+		return
+	}
+
+	if j.attempts >= q.maxAttempts {
+		q.giveUp(j, err, "giving up on event")
+		return
+	}
+
+	delay := q.retryBackoff(j.attempts)
+	j.log.WithError(err).WithFields(logrus.Fields{"attempts": j.attempts, "retry_in": delay.String()}).Warn("failed to handle event, retrying")
+	q.metrics.eventsRetried.Add(1)
+	time.AfterFunc(delay, func() {
+		if err := q.enqueue(j); err != nil {
+			q.giveUp(j, err, "giving up on event: failed to retry")
+		}
+	})
+}
+
+// giveUp gives up on a job which failed to be processed.
+func (q *eventQueue) giveUp(j *job, err error, msg string) {
+	q.metrics.eventsFailed.Add(1)
+	j.log.WithError(err).WithField("attempts", j.attempts).Error(msg)
+	if q.failed != nil {
+		q.failed(j)
+	}
+}
+
+// retryBackoff returns how long to wait before the next attempt, doubling with each attempt made so far.
+func (q *eventQueue) retryBackoff(attempts int) time.Duration {
+	delay := q.backoff
+	for i := 1; i < attempts && delay < eventQueueMaxRetryBackoff; i++ {
+		delay *= 2
+	}
+	if delay > eventQueueMaxRetryBackoff {
+		return eventQueueMaxRetryBackoff
+	}
+	return delay
+}
+
+// stop stops accepting jobs, and waits for the workers to process the jobs already queued, including the debounced ones.
+// Processing is cancelled if ctx is done first. Jobs waiting to be retried are given up on.
+func (q *eventQueue) stop(ctx context.Context) {
+	q.mu.Lock()
+	for key, d := range q.debounced {
+		d.timer.Stop()
+		delete(q.debounced, key)
+		select {
+		case q.jobs <- d.job:
+			q.metrics.queueDepth.Add(1)
+		default:
+			q.giveUp(d.job, errEventQueueFull, "giving up on event: failed to enqueue")
+		}
+	}
+	q.stopped = true
+	close(q.jobs)
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		q.cancel()
+	}
+}
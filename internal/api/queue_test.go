@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventQueue_RetryBackoff(t *testing.T) {
+	q := newEventQueue(1, 10, time.Second, &metrics{}, nil)
+
+	tests := map[int]time.Duration{
+		1: time.Second,
+		2: 2 * time.Second,
+		3: 4 * time.Second,
+		7: time.Minute,
+		9: time.Minute,
+	}
+	for attempts, expected := range tests {
+		require.Equal(t, expected, q.retryBackoff(attempts), "attempts: %d", attempts)
+	}
+}
+
+func TestEventQueue_RetriesFailedJobs(t *testing.T) {
+	tests := map[string]struct {
+		failures          int
+		expectedAttempts  int
+		expectedProcessed int64
+		expectedRetried   int64
+		expectedFailed    int64
+	}{
+		"succeeding first time": {
+			failures:          0,
+			expectedAttempts:  1,
+			expectedProcessed: 1,
+		},
+		"succeeding after retries": {
+			failures:          2,
+			expectedAttempts:  3,
+			expectedProcessed: 1,
+			expectedRetried:   2,
+		},
+		"failing every attempt": {
+			failures:         5,
+			expectedAttempts: 3,
+			expectedRetried:  2,
+			expectedFailed:   1,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := &metrics{}
+			attempts := make(chan int, 10)
+			failed := make(chan *job, 10)
+			q := newEventQueue(1, 3, time.Millisecond, m, func(_ context.Context, j *job) error {
+				attempts <- j.attempts
+				if j.attempts <= tt.failures {
+					return errors.New("failed")
+				}
+				return nil
+			})
+			q.failed = func(j *job) { failed <- j }
+			q.start(1)
+
+			require.NoError(t, q.enqueue(&job{log: logrus.NewEntry(logrus.StandardLogger())}))
+			require.Eventually(t, func() bool {
+				return m.eventsProcessed.Load()+m.eventsFailed.Load() == 1
+			}, time.Second, time.Millisecond)
+			q.stop(context.Background())
+
+			require.Len(t, attempts, tt.expectedAttempts)
+			require.Equal(t, tt.expectedProcessed, m.eventsProcessed.Load())
+			require.Equal(t, tt.expectedRetried, m.eventsRetried.Load())
+			require.Equal(t, tt.expectedFailed, m.eventsFailed.Load())
+			require.Len(t, failed, int(tt.expectedFailed))
+			require.Zero(t, m.queueDepth.Load())
+		})
+	}
+}
+
+func TestEventQueue_RejectsJobsWhenFull(t *testing.T) {
+	m := &metrics{}
+	q := newEventQueue(1, 1, time.Millisecond, m, nil)
+
+	require.NoError(t, q.enqueue(&job{}))
+	require.ErrorIs(t, q.enqueue(&job{}), errEventQueueFull)
+	require.Equal(t, int64(1), m.eventsRejected.Load())
+	require.Equal(t, int64(1), m.queueDepth.Load())
+
+	q.stop(context.Background())
+	require.ErrorIs(t, q.enqueue(&job{}), errEventQueueStopped)
+}
+
+func TestEventQueue_CoalescesDebouncedJobs(t *testing.T) {
+	m := &metrics{}
+	processed := make(chan *job, 10)
+	q := newEventQueue(10, 1, time.Millisecond, m, func(_ context.Context, j *job) error {
+		processed <- j
+		return nil
+	})
+	q.debounceWindow = 50 * time.Millisecond
+	q.start(1)
+
+	log := logrus.NewEntry(logrus.StandardLogger())
+	first, second, other := &job{log: log}, &job{log: log}, &job{log: log}
+	require.NoError(t, q.enqueueDebounced("org/repo#1", first))
+	require.NoError(t, q.enqueueDebounced("org/repo#2", other))
+	require.NoError(t, q.enqueueDebounced("org/repo#1", second))
+	require.Eventually(t, func() bool {
+		return m.eventsProcessed.Load() == 2
+	}, time.Second, time.Millisecond)
+	q.stop(context.Background())
+
+	require.ElementsMatch(t, []*job{second, other}, []*job{<-processed, <-processed})
+	require.Empty(t, processed)
+	require.Equal(t, int64(1), m.eventsCoalesced.Load())
+}
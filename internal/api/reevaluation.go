@@ -0,0 +1,138 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v42/github"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultTeamReevaluationInterval = time.Second
+	teamReevaluationQueueSize       = 10
+)
+
+var (
+	errTeamReevaluationQueueFull = errors.New("team re-evaluation queue full")
+)
+
+// teamChange is a change to a team calling for the open PRs whose rules reference it to be re-evaluated.
+type teamChange struct {
+	log            *logrus.Entry
+	installationID int64
+	ownerLogin     string
+	// deliveryID is the ID of the webhook delivery the change was received in.
+	deliveryID string
+	// handles holds the slug of the team, followed by the ones of its ancestors, as the members of child teams may count
+	// towards any of their ancestors.
+	handles []string
+	// teams lists the teams of the organisation, if they could be listed, so that the handles rules reference teams by
+	// can be resolved.
+	teams []*github.Team
+}
+
+func (c *teamChange) key() string {
+	return c.ownerLogin + "/" + c.handles[0]
+}
+
+// teamReevaluator re-evaluates the PRs affected by team changes in the background. PRs are re-evaluated one at a time,
+// at most once per interval, so that re-evaluating every open PR of an organisation doesn't exhaust the rate limit of
+// the GitHub API. Changes to a team waiting to be processed absorb further changes to the same team.
+type teamReevaluator struct {
+	changes  chan *teamChange
+	interval time.Duration
+	// process re-evaluates the PRs affected by a change, receiving from throttle before re-evaluating each PR.
+	process func(ctx context.Context, c *teamChange, throttle <-chan time.Time) error
+	// failed is called with the changes which failed to be processed or were given up on, if set.
+	failed func(c *teamChange)
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	// mu guards stopped, so that changes are never sent once the channel of changes is closed, and pending.
+	mu      sync.Mutex
+	stopped bool
+	// pending holds the keys of the changes waiting to be processed.
+	pending map[string]bool
+}
+
+func newTeamReevaluator(interval time.Duration, process func(ctx context.Context, c *teamChange, throttle <-chan time.Time) error) *teamReevaluator {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &teamReevaluator{
+		changes:  make(chan *teamChange, teamReevaluationQueueSize),
+		interval: interval,
+		process:  process,
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+		pending:  map[string]bool{},
+	}
+}
+
+func (r *teamReevaluator) start() {
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for c := range r.changes {
+			r.mu.Lock()
+			delete(r.pending, c.key())
+			stopped := r.stopped
+			r.mu.Unlock()
+			if stopped {
+				c.log.WithError(errEventQueueStopped).Error("giving up on team re-evaluation")
+				r.fail(c)
+				continue
+			}
+			if err := r.process(r.ctx, c, ticker.C); err != nil {
+				c.log.WithError(err).Error("failed to re-evaluate pull requests affected by team change")
+				r.fail(c)
+			}
+		}
+	}()
+}
+
+func (r *teamReevaluator) fail(c *teamChange) {
+	if r.failed != nil {
+		r.failed(c)
+	}
+}
+
+// enqueue adds a change to be processed, unless a change to the same team is already waiting to be processed.
+func (r *teamReevaluator) enqueue(c *teamChange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stopped {
+		return errEventQueueStopped
+	}
+	if r.pending[c.key()] {
+		c.log.Debug("team re-evaluation already pending")
+		return nil
+	}
+	select {
+	case r.changes <- c:
+		r.pending[c.key()] = true
+		return nil
+	default:
+		return errTeamReevaluationQueueFull
+	}
+}
+
+// stop stops accepting changes, and gives up on the ones waiting to be processed. The change being processed is
+// cancelled if ctx is done before it is.
+func (r *teamReevaluator) stop(ctx context.Context) {
+	r.mu.Lock()
+	r.stopped = true
+	close(r.changes)
+	r.mu.Unlock()
+
+	select {
+	case <-r.done:
+	case <-ctx.Done():
+		r.cancel()
+	}
+}
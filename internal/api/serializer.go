@@ -0,0 +1,133 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultEventDebounceWindow = 2 * time.Second
+	// prEventsIdleTTL is how long the latest event received for a PR is remembered once its evaluations are done. It
+	// must outlast the retries of any event, so that a retried event never overwrites the evaluation of a newer one.
+	prEventsIdleTTL = time.Hour
+)
+
+var (
+	errEventSuperseded = errors.New("superseded by a more recent event for the same pull request")
+)
+
+// prEvents serializes the evaluations of each PR, so that an evaluation never runs concurrently with another one for
+// the same PR, and skips the evaluation of events superseded by a more recent event for the same PR while they waited.
+type prEvents struct {
+	mu sync.Mutex
+	// seq numbers the events received, across all PRs.
+	seq uint64
+	prs map[string]*prEventsState
+	// evicted is when idle PRs were last forgotten about.
+	evicted time.Time
+}
+
+type prEventsState struct {
+	// mu is held while the PR is being evaluated.
+	mu sync.Mutex
+	// latest is the number of the latest event received for the PR.
+	latest uint64
+	// users is the number of evaluations of the PR running or waiting to run.
+	users    int
+	lastSeen time.Time
+}
+
+func newPREvents() *prEvents {
+	return &prEvents{
+		prs: map[string]*prEventsState{},
+	}
+}
+
+// received records that an event was received for the PR, returning its number.
+func (p *prEvents) received(key string) uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.evictIdle()
+	p.seq++
+	state, ok := p.prs[key]
+	if !ok {
+		state = &prEventsState{}
+		p.prs[key] = state
+	}
+	state.latest = p.seq
+	state.lastSeen = time.Now()
+	return p.seq
+}
+
+// latest returns the number of the latest event received for the PR, or 0 if none was received for a while. Passing it
+// to serialize runs an evaluation which doesn't supersede the events waiting to be evaluated, while being superseded by
+// the ones received in the meantime.
+func (p *prEvents) latest(key string) uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if state, ok := p.prs[key]; ok {
+		return state.latest
+	}
+	return 0
+}
+
+// serialize runs fn once no other evaluation of the PR is running, unless an event more recent than the given one was
+// received for the PR in the meantime, in which case errEventSuperseded is returned.
+func (p *prEvents) serialize(key string, seq uint64, fn func() error) error {
+	p.mu.Lock()
+	state, ok := p.prs[key]
+	if !ok {
+		// The PR was forgotten about, so no event was received for it for a long time.
+		state = &prEventsState{latest: seq}
+		p.prs[key] = state
+	}
+	state.users++
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		state.users--
+		state.lastSeen = time.Now()
+		p.mu.Unlock()
+	}()
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	p.mu.Lock()
+	superseded := state.latest > seq
+	p.mu.Unlock()
+	if superseded {
+		return errEventSuperseded
+	}
+	return fn()
+}
+
+// evictIdle forgets about the PRs which haven't been evaluated for a while, at most once per TTL. p.mu must be held.
+func (p *prEvents) evictIdle() {
+	if time.Since(p.evicted) < prEventsIdleTTL {
+		return
+	}
+	p.evicted = time.Now()
+	for key, state := range p.prs {
+		if state.users == 0 && time.Since(state.lastSeen) > prEventsIdleTTL {
+			delete(p.prs, key)
+		}
+	}
+}
+
+// prEventsKey identifies the PR an event is about.
+func prEventsKey(event event) string {
+	return prKey(event.GetRepo().GetFullName(), event.GetPullRequest().GetNumber())
+}
+
+func prKey(repoFullName string, prNumber int) string {
+	return fmt.Sprintf("%s#%d", repoFullName, prNumber)
+}
+
+// isEvaluated returns whether the approval status of the PR is evaluated when handling the event.
+func isEvaluated(eventType string, event event) bool {
+	return !isPrMergeEvent(event) && isSupportedAction(eventType, event.GetAction())
+}
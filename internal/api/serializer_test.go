@@ -0,0 +1,73 @@
+package api
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPREvents_SkipsSupersededEvents(t *testing.T) {
+	p := newPREvents()
+
+	first := p.received("org/repo#1")
+	other := p.received("org/repo#2")
+	second := p.received("org/repo#1")
+
+	var evaluated []uint64
+	evaluate := func(seq uint64) func() error {
+		return func() error {
+			evaluated = append(evaluated, seq)
+			return nil
+		}
+	}
+	require.ErrorIs(t, p.serialize("org/repo#1", first, evaluate(first)), errEventSuperseded)
+	require.NoError(t, p.serialize("org/repo#1", second, evaluate(second)))
+	require.NoError(t, p.serialize("org/repo#2", other, evaluate(other)))
+	require.Equal(t, []uint64{second, other}, evaluated)
+}
+
+func TestPREvents_EvaluationsAtTheLatestEventDontSupersedeIt(t *testing.T) {
+	p := newPREvents()
+	require.Zero(t, p.latest("org/repo#1"))
+
+	seq := p.received("org/repo#1")
+	require.NoError(t, p.serialize("org/repo#1", p.latest("org/repo#1"), func() error { return nil }))
+	require.NoError(t, p.serialize("org/repo#1", seq, func() error { return nil }))
+
+	latest := p.latest("org/repo#1")
+	p.received("org/repo#1")
+	require.ErrorIs(t, p.serialize("org/repo#1", latest, func() error { return nil }), errEventSuperseded)
+}
+
+func TestPREvents_SerializesEvaluationsOfThePR(t *testing.T) {
+	p := newPREvents()
+	seq := p.received("org/repo#1")
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		running int
+		overlap bool
+	)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.NoError(t, p.serialize("org/repo#1", seq, func() error {
+				mu.Lock()
+				running++
+				overlap = overlap || running > 1
+				mu.Unlock()
+				time.Sleep(time.Millisecond)
+				mu.Lock()
+				running--
+				mu.Unlock()
+				return nil
+			}))
+		}()
+	}
+	wg.Wait()
+	require.False(t, overlap)
+}
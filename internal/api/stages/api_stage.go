@@ -1,18 +1,27 @@
 package stages
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"net/http"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
-	approverCfg "github.com/form3tech-oss/github-team-approver-commons/v2/pkg/configuration"
+	approverCommons "github.com/form3tech-oss/github-team-approver-commons/v2/pkg/configuration"
 	"github.com/form3tech-oss/github-team-approver/internal/api/approval"
+	approverCfg "github.com/form3tech-oss/github-team-approver/internal/api/configuration"
 	"github.com/form3tech-oss/github-team-approver/internal/api/stages/fakegithub"
 	"github.com/google/go-github/v42/github"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 )
 
@@ -21,10 +30,27 @@ const (
 	botName                = "github-team-approver"
 	httpHeaderXFinalStatus = "X-Final-Status"
 
-	ignoredReviewerMsg = "Following reviewers do not have approval capabilities for this review as they either contributed to or reopened the PR:"
-	invalidReviewerMsg = "Following reviewers are not member of a team with approval capabilities:"
+	// eventuallyTimeout and eventuallyTick bound how long to wait for events processed in the background.
+	eventuallyTimeout = 5 * time.Second
+	eventuallyTick    = 10 * time.Millisecond
+
+	summaryCommentMarker = "<!-- github-team-approver:summary -->"
+	ignoredReviewerMsg   = "Ignored as they contributed to or reopened the PR:"
+	invalidReviewerMsg   = "Not member of a team with approval capabilities:"
+	staleReviewerMsg     = "Approved a previous version of the PR:"
+
+	// The titles of the comments previous versions left on PRs for each category of reviews not counted.
+	legacyIgnoredReviewerMsg = "Following reviewers do not have approval capabilities for this review as they either contributed to or reopened the PR:"
+	legacyInvalidReviewerMsg = "Following reviewers are not member of a team with approval capabilities:"
 )
 
+// explanation is the body of the response of the explain endpoint.
+type explanation struct {
+	Status      string         `json:"status"`
+	Description string         `json:"description"`
+	Trace       approval.Trace `json:"trace"`
+}
+
 type ApiStage struct {
 	t *testing.T
 
@@ -36,6 +62,8 @@ type ApiStage struct {
 	labels []string
 
 	resp *http.Response
+	// lastDelivery resends the last event sent through deliver, with the same delivery ID.
+	lastDelivery func() *http.Response
 }
 
 func (s *ApiStage) GitHubWebHookTokenExists() *ApiStage {
@@ -47,10 +75,107 @@ func (s *ApiStage) GitHubWebHookTokenExists() *ApiStage {
 	return s
 }
 
+func (s *ApiStage) ExplainAPITokenExists() *ApiStage {
+	abs, err := filepath.Abs(tokenPath)
+	require.NoError(s.t, err, "filepath.Abs: %s", err)
+
+	s.setupEnv("EXPLAIN_API_TOKEN_PATH", abs)
+
+	return s
+}
+
+func (s *ApiStage) StatusReportedAsCheckRun() *ApiStage {
+	s.setupEnv("STATUS_REPORTING", "check_run")
+
+	return s
+}
+
+func (s *ApiStage) StatusReportedAsCommitStatusAndCheckRun() *ApiStage {
+	s.setupEnv("STATUS_REPORTING", "both")
+
+	return s
+}
+
+func (s *ApiStage) EventsProcessedAsynchronously() *ApiStage {
+	s.setupEnv("EVENT_QUEUE_WORKERS", "2")
+	s.setupEnv("EVENT_QUEUE_RETRY_BACKOFF", "10ms")
+	s.setupEnv("EVENT_DEBOUNCE_WINDOW", "10ms")
+
+	return s
+}
+
+// EventQueueWorkersNotConfigured leaves the number of workers processing events to its default.
+func (s *ApiStage) EventQueueWorkersNotConfigured() *ApiStage {
+	err := os.Unsetenv("EVENT_QUEUE_WORKERS")
+	require.NoError(s.t, err)
+	s.setupEnv("EVENT_DEBOUNCE_WINDOW", "10ms")
+
+	return s
+}
+
+func (s *ApiStage) TeamChangesReevaluatedWithoutDelay() *ApiStage {
+	s.setupEnv("TEAM_REEVALUATION_INTERVAL", "1ms")
+
+	return s
+}
+
+func (s *ApiStage) DraftsReportedAsPending() *ApiStage {
+	s.setupEnv("DRAFT_POLICY", "pending")
+
+	return s
+}
+
+func (s *ApiStage) DraftsSkipped() *ApiStage {
+	s.setupEnv("DRAFT_POLICY", "skip")
+
+	return s
+}
+
+func (s *ApiStage) DeliveriesRememberedInAFile() *ApiStage {
+	s.setupEnv("DELIVERY_STORE", "file")
+	s.setupEnv("DELIVERY_STORE_PATH", filepath.Join(s.t.TempDir(), "deliveries"))
+
+	return s
+}
+
+func (s *ApiStage) EventsDebouncedForLongerThanABurst() *ApiStage {
+	s.setupEnv("EVENT_DEBOUNCE_WINDOW", "500ms")
+
+	return s
+}
+
+func (s *ApiStage) StatusReportFailsOnce() *ApiStage {
+	s.fakeGitHub.SetStatusReportFailures(1)
+
+	return s
+}
+
 func (s *ApiStage) FakeGHRunning() *ApiStage {
 	s.fakeGitHub = fakegithub.NewFakeGithub(s.t)
 	s.setupEnv("GITHUB_BASE_URL", s.fakeGitHub.URL())
 	s.setupEnv("GITHUB_STATUS_NAME", botName)
+	s.appInstalled()
+
+	return s
+}
+
+// appInstalled installs the app, so that events are handled authenticating as its installation.
+func (s *ApiStage) appInstalled() {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(s.t, err)
+	path := filepath.Join(s.t.TempDir(), "private-key.pem")
+	err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0600)
+	require.NoError(s.t, err)
+
+	s.setupEnv("GITHUB_APP_ID", "1")
+	s.setupEnv("GITHUB_APP_INSTALLATION_ID", "1")
+	s.setupEnv("GITHUB_APP_PRIVATE_KEY_PATH", path)
+	s.fakeGitHub.SetAppInstalled(time.Hour)
+}
+
+// AppPrivateKeyMissing makes the app unable to authenticate as its installation.
+func (s *ApiStage) AppPrivateKeyMissing() *ApiStage {
+	s.setupEnv("GITHUB_APP_PRIVATE_KEY_PATH", filepath.Join(s.t.TempDir(), "missing.pem"))
 
 	return s
 }
@@ -84,9 +209,44 @@ func (s *ApiStage) OrganisationWithTeamFoo() *ApiStage {
 	return s
 }
 
+func (s *ApiStage) OrganisationWithTeamFooAndChildTeamBar() *ApiStage {
+	s.OrganisationWithTeamFoo()
+
+	org := s.fakeGitHub.Org()
+	bar := &github.Team{
+		ID:     github.Int64(2),
+		Name:   github.String("CAB - Bar"),
+		Slug:   github.String("cab-bar"),
+		Parent: org.Teams[0],
+	}
+	org.Teams = append(org.Teams, bar)
+	org.TeamMembers[bar.GetID()] = []*github.User{
+		{
+			Login: github.String("charlie"),
+		},
+	}
+
+	return s
+}
+
 func (s *ApiStage) RepoWithFooAsApprovingTeam() *ApiStage {
 	require.NotNil(s.t, s.fakeGitHub.Org())
-	approvingTeam := *s.fakeGitHub.Org().Teams[0].Slug
+	return s.repoWithApprovingTeam(s.fakeGitHub.Org().Teams[0].GetSlug())
+}
+
+// RepoWithFooAsApprovingTeamByName references the approving team by its name rather than its slug.
+func (s *ApiStage) RepoWithFooAsApprovingTeamByName() *ApiStage {
+	require.NotNil(s.t, s.fakeGitHub.Org())
+	return s.repoWithApprovingTeam(s.fakeGitHub.Org().Teams[0].GetName())
+}
+
+// RepoWithFooAsApprovingTeamByID references the approving team by its ID rather than its slug.
+func (s *ApiStage) RepoWithFooAsApprovingTeamByID() *ApiStage {
+	require.NotNil(s.t, s.fakeGitHub.Org())
+	return s.repoWithApprovingTeam(strconv.FormatInt(s.fakeGitHub.Org().Teams[0].GetID(), 10))
+}
+
+func (s *ApiStage) repoWithApprovingTeam(approvingTeam string) *ApiStage {
 
 	repo := &fakegithub.Repo{
 		Name: "some-service",
@@ -97,10 +257,12 @@ func (s *ApiStage) RepoWithFooAsApprovingTeam() *ApiStage {
 					TargetBranches: []string{"master"},
 					Rules: []approverCfg.Rule{
 						{
-							ApprovalMode:         approverCfg.ApprovalModeRequireAny,
-							Regex:                `- \[x\] Yes - this change impacts customers`,
-							ApprovingTeamHandles: []string{approvingTeam},
-							Labels:               []string{},
+							Rule: approverCommons.Rule{
+								ApprovalMode:         approverCfg.ApprovalModeRequireAny,
+								Regex:                `- \[x\] Yes - this change impacts customers`,
+								ApprovingTeamHandles: []string{approvingTeam},
+								Labels:               []string{},
+							},
 						},
 					},
 				},
@@ -125,11 +287,13 @@ func (s *ApiStage) RepoWithNoContributorReviewEnabledAndFooAsApprovingTeam() *Ap
 					TargetBranches: []string{"master"},
 					Rules: []approverCfg.Rule{
 						{
-							IgnoreContributorApproval: true,
-							ApprovalMode:              approverCfg.ApprovalModeRequireAny,
-							Regex:                     `- \[x\] Yes - this change impacts customers`,
-							ApprovingTeamHandles:      []string{approvingTeam},
-							Labels:                    []string{},
+							Rule: approverCommons.Rule{
+								IgnoreContributorApproval: true,
+								ApprovalMode:              approverCfg.ApprovalModeRequireAny,
+								Regex:                     `- \[x\] Yes - this change impacts customers`,
+								ApprovingTeamHandles:      []string{approvingTeam},
+								Labels:                    []string{},
+							},
 						},
 					},
 				},
@@ -141,6 +305,343 @@ func (s *ApiStage) RepoWithNoContributorReviewEnabledAndFooAsApprovingTeam() *Ap
 	return s
 }
 
+func (s *ApiStage) RepoWithChildTeamsIncludedAndFooAsApprovingTeam() *ApiStage {
+	require.NotNil(s.t, s.fakeGitHub.Org())
+	approvingTeam := *s.fakeGitHub.Org().Teams[0].Slug
+
+	repo := &fakegithub.Repo{
+		Name: "some-service",
+
+		ApproverCfg: &approverCfg.Configuration{
+			PullRequestApprovalRules: []approverCfg.PullRequestApprovalRule{
+				{
+					TargetBranches: []string{"master"},
+					Rules: []approverCfg.Rule{
+						{
+							Rule: approverCommons.Rule{
+								ApprovalMode:         approverCfg.ApprovalModeRequireAny,
+								Regex:                `- \[x\] Yes - this change impacts customers`,
+								ApprovingTeamHandles: []string{approvingTeam},
+								Labels:               []string{},
+							},
+							IncludeChildTeams: true,
+						},
+					},
+				},
+			},
+		},
+	}
+	s.fakeGitHub.SetRepo(repo)
+
+	return s
+}
+
+func (s *ApiStage) RepoWithStaleApprovalsDismissedAndFooAsApprovingTeam() *ApiStage {
+	require.NotNil(s.t, s.fakeGitHub.Org())
+	approvingTeam := *s.fakeGitHub.Org().Teams[0].Slug
+
+	repo := &fakegithub.Repo{
+		Name: "some-service",
+
+		ApproverCfg: &approverCfg.Configuration{
+			PullRequestApprovalRules: []approverCfg.PullRequestApprovalRule{
+				{
+					TargetBranches: []string{"master"},
+					Rules: []approverCfg.Rule{
+						{
+							Rule: approverCommons.Rule{
+								ApprovalMode:         approverCfg.ApprovalModeRequireAny,
+								Regex:                `- \[x\] Yes - this change impacts customers`,
+								ApprovingTeamHandles: []string{approvingTeam},
+								Labels:               []string{},
+							},
+							StaleApprovals: approverCfg.StaleApprovalsDismiss,
+						},
+					},
+				},
+			},
+		},
+	}
+	s.fakeGitHub.SetRepo(repo)
+
+	return s
+}
+
+func (s *ApiStage) RepoWithBlockOnChangesRequestedAndFooAsApprovingTeam() *ApiStage {
+	require.NotNil(s.t, s.fakeGitHub.Org())
+	approvingTeam := *s.fakeGitHub.Org().Teams[0].Slug
+
+	repo := &fakegithub.Repo{
+		Name: "some-service",
+
+		ApproverCfg: &approverCfg.Configuration{
+			PullRequestApprovalRules: []approverCfg.PullRequestApprovalRule{
+				{
+					TargetBranches: []string{"master"},
+					Rules: []approverCfg.Rule{
+						{
+							Rule: approverCommons.Rule{
+								ApprovalMode:         approverCfg.ApprovalModeRequireAny,
+								Regex:                `- \[x\] Yes - this change impacts customers`,
+								ApprovingTeamHandles: []string{approvingTeam},
+								Labels:               []string{},
+							},
+							BlockOnChangesRequested: true,
+						},
+					},
+				},
+			},
+		},
+	}
+	s.fakeGitHub.SetRepo(repo)
+
+	return s
+}
+
+func (s *ApiStage) RepoWithFooAndCharlieAsApprovers() *ApiStage {
+	require.NotNil(s.t, s.fakeGitHub.Org())
+	approvingTeam := *s.fakeGitHub.Org().Teams[0].Slug
+
+	repo := &fakegithub.Repo{
+		Name: "some-service",
+
+		ApproverCfg: &approverCfg.Configuration{
+			PullRequestApprovalRules: []approverCfg.PullRequestApprovalRule{
+				{
+					TargetBranches: []string{"master"},
+					Rules: []approverCfg.Rule{
+						{
+							Rule: approverCommons.Rule{
+								ApprovalMode:              approverCfg.ApprovalModeRequireAll,
+								Regex:                     `- \[x\] Yes - this change impacts customers`,
+								ApprovingTeamHandles:      []string{approvingTeam},
+								Labels:                    []string{},
+								IgnoreContributorApproval: true,
+							},
+							ApprovingUserHandles: []string{"charlie"},
+						},
+					},
+				},
+			},
+		},
+	}
+	s.fakeGitHub.SetRepo(repo)
+
+	return s
+}
+
+// RepoWithFooAsApprovingTeamAndCharlieAsApproverOnMaster has Charlie approve alongside Foo according to the
+// configuration on the master branch (i.e. the base branch of PRs), but not according to the one on the default branch.
+func (s *ApiStage) RepoWithFooAsApprovingTeamAndCharlieAsApproverOnMaster() *ApiStage {
+	s.RepoWithFooAndCharlieAsApprovers()
+	onMaster := s.fakeGitHub.Repo().ApproverCfg
+	s.RepoWithFooAsApprovingTeam()
+	s.fakeGitHub.Repo().ApproverCfgByRef = map[string]*approverCfg.Configuration{
+		"master": onMaster,
+	}
+
+	return s
+}
+
+func (s *ApiStage) CentralConfigurationRepositoryConfigured() *ApiStage {
+	s.setupEnv("CENTRAL_CONFIGURATION_REPOSITORY", ".github")
+	return s
+}
+
+func (s *ApiStage) CentralRepoWithFooAsApprovingTeam() *ApiStage {
+	require.NotNil(s.t, s.fakeGitHub.Org())
+	approvingTeam := *s.fakeGitHub.Org().Teams[0].Slug
+
+	repo := &fakegithub.Repo{
+		Name: ".github",
+
+		ApproverCfg: &approverCfg.Configuration{
+			PullRequestApprovalRules: []approverCfg.PullRequestApprovalRule{
+				{
+					TargetBranches: []string{"master"},
+					Rules: []approverCfg.Rule{
+						{
+							Rule: approverCommons.Rule{
+								ApprovalMode:         approverCfg.ApprovalModeRequireAny,
+								Regex:                `- \[x\] Yes - this change impacts customers`,
+								ApprovingTeamHandles: []string{approvingTeam},
+								Labels:               []string{},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	s.fakeGitHub.SetCentralRepo(repo)
+
+	return s
+}
+
+func (s *ApiStage) CentralRepoWithCharlieAsMandatoryApprover() *ApiStage {
+	require.NotNil(s.t, s.fakeGitHub.Org())
+
+	repo := &fakegithub.Repo{
+		Name: ".github",
+
+		ApproverCfg: &approverCfg.Configuration{
+			MandatoryPullRequestApprovalRules: []approverCfg.PullRequestApprovalRule{
+				{
+					Rules: []approverCfg.Rule{
+						{
+							Rule: approverCommons.Rule{
+								ApprovalMode: approverCfg.ApprovalModeRequireAny,
+								Regex:        `- \[x\] Yes - this change impacts customers`,
+								Labels:       []string{},
+							},
+							ApprovingUserHandles: []string{"charlie"},
+						},
+					},
+				},
+			},
+		},
+	}
+	s.fakeGitHub.SetCentralRepo(repo)
+
+	return s
+}
+
+func (s *ApiStage) ConfigurationAdminTeamBarConfigured() *ApiStage {
+	s.setupEnv("CONFIGURATION_ADMIN_TEAM", "cab-bar")
+	return s
+}
+
+func (s *ApiStage) ConfigurationReadFromBaseBranch() *ApiStage {
+	s.setupEnv("CONFIGURATION_SOURCE", "base_branch")
+	return s
+}
+
+func (s *ApiStage) AliceAndCharlieApprovePullRequest() *ApiStage {
+	s.AliceApprovesPullRequest()
+	return s.CharlieApprovesPullRequest()
+}
+
+func (s *ApiStage) RepoWithFooAsApprovingTeamForAllDirectoriesExceptConfig() *ApiStage {
+	return s.repoWithFooAsApprovingTeamForDirectories([]string{"**"}, []string{"config/**"})
+}
+
+func (s *ApiStage) RepoWithFooAsApprovingTeamForAllDirectoriesExceptCodeAndConfig() *ApiStage {
+	return s.repoWithFooAsApprovingTeamForDirectories([]string{"**"}, []string{"code/**", "config/*.yaml"})
+}
+
+func (s *ApiStage) repoWithFooAsApprovingTeamForDirectories(directories, excludeDirectories []string) *ApiStage {
+	require.NotNil(s.t, s.fakeGitHub.Org())
+	approvingTeam := *s.fakeGitHub.Org().Teams[0].Slug
+
+	repo := &fakegithub.Repo{
+		Name: "some-service",
+
+		ApproverCfg: &approverCfg.Configuration{
+			PullRequestApprovalRules: []approverCfg.PullRequestApprovalRule{
+				{
+					TargetBranches: []string{"master"},
+					Rules: []approverCfg.Rule{
+						{
+							Rule: approverCommons.Rule{
+								ApprovalMode:         approverCfg.ApprovalModeRequireAny,
+								Directories:          directories,
+								ApprovingTeamHandles: []string{approvingTeam},
+								Labels:               []string{},
+							},
+							ExcludeDirectories: excludeDirectories,
+						},
+					},
+				},
+			},
+		},
+	}
+	s.fakeGitHub.SetRepo(repo)
+
+	return s
+}
+
+func (s *ApiStage) RepoWithCodeownersRuleAndFooOwningCode() *ApiStage {
+	return s.repoWithCodeownersRule("/code/ @form3tech/cab-foo\n")
+}
+
+func (s *ApiStage) RepoWithCodeownersRuleAndFooOwningCodeAndCharlieOwningConfig() *ApiStage {
+	return s.repoWithCodeownersRule("/code/ @form3tech/cab-foo\n/config/ @charlie\n")
+}
+
+func (s *ApiStage) RepoWithCodeownersRuleAndNoCodeownersFile() *ApiStage {
+	return s.repoWithCodeownersRule("")
+}
+
+func (s *ApiStage) RepoWithCodeownersRuleAndInvalidCodeownersFile() *ApiStage {
+	return s.repoWithCodeownersRule("/code/ @form3tech/cab-foo\n/ @charlie\n")
+}
+
+func (s *ApiStage) RepoWithFooAsApprovingTeamAndCodeownersRuleWithoutOwnersOfChangedPaths() *ApiStage {
+	require.NotNil(s.t, s.fakeGitHub.Org())
+	approvingTeam := *s.fakeGitHub.Org().Teams[0].Slug
+
+	repo := &fakegithub.Repo{
+		Name: "some-service",
+
+		ApproverCfg: &approverCfg.Configuration{
+			PullRequestApprovalRules: []approverCfg.PullRequestApprovalRule{
+				{
+					TargetBranches: []string{"master"},
+					Rules: []approverCfg.Rule{
+						{
+							Rule: approverCommons.Rule{
+								Labels: []string{},
+							},
+							Codeowners:       true,
+							MinimumApprovals: 2,
+						},
+						{
+							Rule: approverCommons.Rule{
+								ApprovalMode:         approverCfg.ApprovalModeRequireAny,
+								Regex:                `- \[x\] Yes - this change impacts customers`,
+								ApprovingTeamHandles: []string{approvingTeam},
+								Labels:               []string{},
+							},
+						},
+					},
+				},
+			},
+		},
+		Codeowners: "/docs/ @charlie\n",
+	}
+	s.fakeGitHub.SetRepo(repo)
+
+	return s
+}
+
+func (s *ApiStage) repoWithCodeownersRule(codeowners string) *ApiStage {
+	require.NotNil(s.t, s.fakeGitHub.Org())
+
+	repo := &fakegithub.Repo{
+		Name: "some-service",
+
+		ApproverCfg: &approverCfg.Configuration{
+			PullRequestApprovalRules: []approverCfg.PullRequestApprovalRule{
+				{
+					TargetBranches: []string{"master"},
+					Rules: []approverCfg.Rule{
+						{
+							Rule: approverCommons.Rule{
+								Labels: []string{},
+							},
+							Codeowners: true,
+						},
+					},
+				},
+			},
+		},
+		Codeowners: codeowners,
+	}
+	s.fakeGitHub.SetRepo(repo)
+
+	return s
+}
+
 func (s *ApiStage) RepoWithFooAsApprovingTeamAndMultipleRules() *ApiStage {
 	require.NotNil(s.t, s.fakeGitHub.Org())
 	approvingTeam := *s.fakeGitHub.Org().Teams[0].Slug
@@ -154,19 +655,23 @@ func (s *ApiStage) RepoWithFooAsApprovingTeamAndMultipleRules() *ApiStage {
 					TargetBranches: []string{"master"},
 					Rules: []approverCfg.Rule{
 						{
-							IgnoreContributorApproval: true,
-							Directories:               []string{"code/"},
-							ApprovalMode:              approverCfg.ApprovalModeRequireAny,
-							Regex:                     `- \[x\] Yes - this change impacts customers`,
-							ApprovingTeamHandles:      []string{approvingTeam},
-							Labels:                    []string{},
+							Rule: approverCommons.Rule{
+								IgnoreContributorApproval: true,
+								Directories:               []string{"code/"},
+								ApprovalMode:              approverCfg.ApprovalModeRequireAny,
+								Regex:                     `- \[x\] Yes - this change impacts customers`,
+								ApprovingTeamHandles:      []string{approvingTeam},
+								Labels:                    []string{},
+							},
 						},
 						{
-							IgnoreContributorApproval: false,
-							ApprovalMode:              approverCfg.ApprovalModeRequireAny,
-							Regex:                     `- \[x\] Yes - this change impacts customers`,
-							ApprovingTeamHandles:      []string{approvingTeam},
-							Labels:                    []string{},
+							Rule: approverCommons.Rule{
+								IgnoreContributorApproval: false,
+								ApprovalMode:              approverCfg.ApprovalModeRequireAny,
+								Regex:                     `- \[x\] Yes - this change impacts customers`,
+								ApprovingTeamHandles:      []string{approvingTeam},
+								Labels:                    []string{},
+							},
 						},
 					},
 				},
@@ -191,17 +696,52 @@ func (s *ApiStage) RepoWithFooAsApprovingTeamWithEmergencyRule() *ApiStage {
 					TargetBranches: []string{"master"},
 					Rules: []approverCfg.Rule{
 						{
-							ApprovalMode:         approverCfg.ApprovalModeRequireAny,
-							Regex:                `- \[x\] Yes - this change impacts customers`,
-							ApprovingTeamHandles: []string{approvingTeam},
-							Labels:               []string{},
+							Rule: approverCommons.Rule{
+								ApprovalMode:         approverCfg.ApprovalModeRequireAny,
+								Regex:                `- \[x\] Yes - this change impacts customers`,
+								ApprovingTeamHandles: []string{approvingTeam},
+								Labels:               []string{},
+							},
 						},
 						{
-							ApprovalMode:         approverCfg.ApprovalModeRequireAny,
-							Regex:                `- \[x\] Yes - Emergency`,
-							ApprovingTeamHandles: []string{"cab-foo"},
-							Labels:               []string{},
-							ForceApproval:        true,
+							Rule: approverCommons.Rule{
+								ApprovalMode:         approverCfg.ApprovalModeRequireAny,
+								Regex:                `- \[x\] Yes - Emergency`,
+								ApprovingTeamHandles: []string{"cab-foo"},
+								Labels:               []string{},
+								ForceApproval:        true,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	s.fakeGitHub.SetRepo(repo)
+
+	return s
+}
+
+func (s *ApiStage) RepoWithFooForciblyApprovingEveryPR() *ApiStage {
+	require.NotNil(s.t, s.fakeGitHub.Org())
+	approvingTeam := *s.fakeGitHub.Org().Teams[0].Slug
+
+	repo := &fakegithub.Repo{
+		Name: "some-service",
+
+		ApproverCfg: &approverCfg.Configuration{
+			PullRequestApprovalRules: []approverCfg.PullRequestApprovalRule{
+				{
+					TargetBranches: []string{"master"},
+					Rules: []approverCfg.Rule{
+						{
+							Rule: approverCommons.Rule{
+								ApprovalMode:         approverCfg.ApprovalModeRequireAny,
+								Regex:                `.*`,
+								ApprovingTeamHandles: []string{approvingTeam},
+								Labels:               []string{},
+								ForceApproval:        true,
+							},
 						},
 					},
 				},
@@ -224,6 +764,36 @@ func (s *ApiStage) RepoWithoutConfigurationFile() *ApiStage {
 	return s
 }
 
+func (s *ApiStage) RepoWithConfigurationContainingInvalidRegex() *ApiStage {
+	s.RepoWithFooAsApprovingTeam()
+	s.fakeGitHub.Repo().ApproverCfg.PullRequestApprovalRules[0].Rules[0].Regex = `- [x Yes`
+
+	return s
+}
+
+// RepoWithConfigurationContainingUnknownField serves a configuration file as written for earlier versions, following
+// the example of the README which misspelled regex_label.
+func (s *ApiStage) RepoWithConfigurationContainingUnknownField() *ApiStage {
+	s.fakeGitHub.SetRepo(&fakegithub.Repo{
+		Name: "some-service",
+		ApproverCfgContent: `pull_request_approval_rules:
+- target_branches:
+  - master
+  rules:
+  - regex: "- \\[x\\] Yes - this change impacts customers"
+    regex_labels: "^cab$"
+    approving_team_handles:
+    - cab-foo
+    approval_mode: require_any
+    labels:
+    - needs-cab-approval
+    force_approval: false
+`,
+	})
+
+	return s
+}
+
 func (s *ApiStage) RepoWithConfigurationReferencingInvalidTeamHandles() *ApiStage {
 	require.NotNil(s.t, s.fakeGitHub.Org())
 	approvingTeam := *s.fakeGitHub.Org().Teams[0].Slug
@@ -237,17 +807,21 @@ func (s *ApiStage) RepoWithConfigurationReferencingInvalidTeamHandles() *ApiStag
 					TargetBranches: []string{"master"},
 					Rules: []approverCfg.Rule{
 						{
-							ApprovalMode:         approverCfg.ApprovalModeRequireAny,
-							Regex:                `- \[x\] Yes - this change impacts customers`,
-							ApprovingTeamHandles: []string{approvingTeam},
-							Labels:               []string{},
+							Rule: approverCommons.Rule{
+								ApprovalMode:         approverCfg.ApprovalModeRequireAny,
+								Regex:                `- \[x\] Yes - this change impacts customers`,
+								ApprovingTeamHandles: []string{approvingTeam},
+								Labels:               []string{},
+							},
 						},
 						{
-							ApprovalMode:         approverCfg.ApprovalModeRequireAny,
-							Regex:                `- \[x\] Yes - Emergency`,
-							ApprovingTeamHandles: []string{"crab-foo"},
-							Labels:               []string{},
-							ForceApproval:        true,
+							Rule: approverCommons.Rule{
+								ApprovalMode:         approverCfg.ApprovalModeRequireAny,
+								Regex:                `- \[x\] Yes - Emergency`,
+								ApprovingTeamHandles: []string{"crab-foo"},
+								Labels:               []string{},
+								ForceApproval:        true,
+							},
 						},
 					},
 				},
@@ -278,6 +852,31 @@ func (s *ApiStage) SendingAnUnsupportedEvent() *ApiStage {
 	return s
 }
 
+func (s *ApiStage) SendingMembershipAddedEventForTeamFoo() *ApiStage {
+	s.sendMembershipAddedEvent(s.fakeGitHub.Org().Teams[0].GetSlug())
+
+	return s
+}
+
+func (s *ApiStage) SendingMembershipAddedEventForAnotherTeam() *ApiStage {
+	s.sendMembershipAddedEvent("cab-bar")
+
+	return s
+}
+
+func (s *ApiStage) sendMembershipAddedEvent(teamSlug string) {
+	payload := &github.MembershipEvent{
+		Action: github.String("added"),
+		Scope:  github.String("team"),
+		Member: &github.User{Login: github.String("charlie")},
+		Team:   &github.Team{Slug: github.String(teamSlug)},
+		Org:    &github.Organization{Login: github.String(s.fakeGitHub.Org().OwnerName)},
+	}
+
+	c := newClient(s.t, s.app.URL(), s.WebHookSecret)
+	s.resp = c.sendEvent(payload, "membership")
+}
+
 func (s *ApiStage) SendingEventWithInvalidSignature() *ApiStage {
 	c := newClient(s.t, s.app.URL(), s.WebHookSecret)
 	s.resp = c.sendEventWithIncorrectSignature(&struct{}{})
@@ -305,11 +904,84 @@ func (s *ApiStage) ExpectErrorAnswerReturned() *ApiStage {
 	return s
 }
 
-func (s *ApiStage) ExpectSuccessAnswerReturned() *ApiStage {
-	require.NotNil(s.t, s.resp)
-	require.Equal(s.t, http.StatusOK, s.resp.StatusCode)
-	require.Equal(s.t, approval.StatusEventStatusSuccess, s.resp.Header.Get(httpHeaderXFinalStatus))
-	return s
+func (s *ApiStage) ExpectSuccessAnswerReturned() *ApiStage {
+	require.NotNil(s.t, s.resp)
+	require.Equal(s.t, http.StatusOK, s.resp.StatusCode)
+	require.Equal(s.t, approval.StatusEventStatusSuccess, s.resp.Header.Get(httpHeaderXFinalStatus))
+	return s
+}
+
+func (s *ApiStage) ExpectAcceptedAnswerReturned() *ApiStage {
+	require.NotNil(s.t, s.resp)
+	require.Equal(s.t, http.StatusAccepted, s.resp.StatusCode)
+	require.Empty(s.t, s.resp.Header.Get(httpHeaderXFinalStatus))
+	return s
+}
+
+func (s *ApiStage) ExpectRedeliveryAcknowledged() *ApiStage {
+	require.NotNil(s.t, s.resp)
+	require.Equal(s.t, http.StatusOK, s.resp.StatusCode)
+	require.Empty(s.t, s.resp.Header.Get(httpHeaderXFinalStatus))
+	return s
+}
+
+// ExpectEventFailedToBeHandled expects the event, or its redelivery, to have failed to be handled rather than be
+// ignored or acknowledged.
+func (s *ApiStage) ExpectEventFailedToBeHandled() *ApiStage {
+	require.NotNil(s.t, s.resp)
+	require.Equal(s.t, http.StatusInternalServerError, s.resp.StatusCode)
+	return s
+}
+
+func (s *ApiStage) ExpectMetricsReportingDuplicateDelivery() *ApiStage {
+	s.expectMetricsEventually("github_team_approver_deliveries_duplicated_total 1")
+	return s
+}
+
+func (s *ApiStage) ExpectStatusPendingEventuallyReported() *ApiStage {
+	require.Eventually(s.t, func() bool {
+		status := s.fakeGitHub.ReportedStatus()
+		return status != nil && status.GetState() == approval.StatusEventStatusPending
+	}, eventuallyTimeout, eventuallyTick)
+	return s
+}
+
+func (s *ApiStage) ExpectMetricsReportingEventProcessed() *ApiStage {
+	s.expectMetricsEventually("github_team_approver_events_processed_total 1", "github_team_approver_events_failed_total 0")
+	return s
+}
+
+func (s *ApiStage) ExpectMetricsReportingEventRetriedOnce() *ApiStage {
+	s.expectMetricsEventually("github_team_approver_events_retried_total 1", "github_team_approver_events_processed_total 1")
+	return s
+}
+
+func (s *ApiStage) ExpectMetricsReportingEventCoalesced() *ApiStage {
+	s.expectMetricsEventually("github_team_approver_events_coalesced_total 1", "github_team_approver_events_processed_total 1")
+	return s
+}
+
+func (s *ApiStage) ExpectMetricsReportingPullRequestReevaluated() *ApiStage {
+	s.expectMetricsEventually("github_team_approver_team_changes_total 1", "github_team_approver_pull_requests_reevaluated_total 1")
+	return s
+}
+
+func (s *ApiStage) ExpectMetricsReportingNoPullRequestReevaluated() *ApiStage {
+	s.expectMetricsEventually("github_team_approver_team_changes_total 1", "github_team_approver_pull_requests_reevaluated_total 0")
+	return s
+}
+
+func (s *ApiStage) expectMetricsEventually(samples ...string) {
+	c := newClient(s.t, s.app.URL(), s.WebHookSecret)
+	require.Eventually(s.t, func() bool {
+		metrics := c.metrics()
+		for _, sample := range samples {
+			if !strings.Contains(metrics, sample+"\n") {
+				return false
+			}
+		}
+		return true
+	}, eventuallyTimeout, eventuallyTick)
 }
 
 func (s *ApiStage) setupEnv(k, v string) {
@@ -453,14 +1125,16 @@ func (s *ApiStage) NoReviewsExist() *ApiStage {
 }
 
 func (s *ApiStage) IgnoredReviewCommentsExist() *ApiStage {
-	msg := fmt.Sprintf("%s\n- @%s\n", ignoredReviewerMsg, "some user")
+	msg := fmt.Sprintf("%s\n- @%s\n", legacyIgnoredReviewerMsg, "some user")
 	comments := []*github.IssueComment{
 		{
 			ID:   github.Int64(1),
+			User: fakegithub.BotUser(),
 			Body: github.String(msg),
 		},
 		{
 			ID:   github.Int64(2),
+			User: fakegithub.BotUser(),
 			Body: github.String(msg),
 		},
 	}
@@ -470,14 +1144,16 @@ func (s *ApiStage) IgnoredReviewCommentsExist() *ApiStage {
 }
 
 func (s *ApiStage) InvalidReviewCommentsExist() *ApiStage {
-	msg := fmt.Sprintf("%s\n- @%s\n", invalidReviewerMsg, "some other user")
+	msg := fmt.Sprintf("%s\n- @%s\n", legacyInvalidReviewerMsg, "some other user")
 	comments := []*github.IssueComment{
 		{
 			ID:   github.Int64(1),
+			User: fakegithub.BotUser(),
 			Body: github.String(msg),
 		},
 		{
 			ID:   github.Int64(2),
+			User: fakegithub.BotUser(),
 			Body: github.String(msg),
 		},
 	}
@@ -487,15 +1163,17 @@ func (s *ApiStage) InvalidReviewCommentsExist() *ApiStage {
 }
 
 func (s *ApiStage) InvalidAndIgnoredReviewCommentsExist() *ApiStage {
-	msgIgnored := fmt.Sprintf("%s\n- @%s\n", ignoredReviewerMsg, "alice")
-	msgInvalid := fmt.Sprintf("%s\n- @%s\n", invalidReviewerMsg, "some other user")
+	msgIgnored := fmt.Sprintf("%s\n- @%s\n", legacyIgnoredReviewerMsg, "alice")
+	msgInvalid := fmt.Sprintf("%s\n- @%s\n", legacyInvalidReviewerMsg, "some other user")
 	comments := []*github.IssueComment{
 		{
 			ID:   github.Int64(1),
+			User: fakegithub.BotUser(),
 			Body: github.String(msgIgnored),
 		},
 		{
 			ID:   github.Int64(2),
+			User: fakegithub.BotUser(),
 			Body: github.String(msgInvalid),
 		},
 	}
@@ -504,6 +1182,37 @@ func (s *ApiStage) InvalidAndIgnoredReviewCommentsExist() *ApiStage {
 	return s
 }
 
+func (s *ApiStage) SummaryCommentExists() *ApiStage {
+	s.fakeGitHub.SetIssueComments([]*github.IssueComment{
+		{
+			ID:   github.Int64(1),
+			User: fakegithub.BotUser(),
+			Body: github.String(fmt.Sprintf("%s\n### Approval status: pending\n", summaryCommentMarker)),
+		},
+	})
+
+	return s
+}
+
+func (s *ApiStage) SummaryCommentQuotedByUserExists() *ApiStage {
+	s.fakeGitHub.SetIssueComments([]*github.IssueComment{
+		{
+			ID:   github.Int64(1),
+			User: &github.User{Login: github.String("charlie"), Type: github.String("User")},
+			Body: github.String(fmt.Sprintf("%s\n### Approval status: pending\n\nWhy is this still pending?", summaryCommentMarker)),
+		},
+	})
+
+	return s
+}
+
+func (s *ApiStage) PullRequestImpactingCustomersIsOpen() *ApiStage {
+	s.fakeGitHub.PR().Body = "- [x] Yes - this change impacts customers"
+	s.fakeGitHub.SetInstallationRepositories()
+
+	return s
+}
+
 func (s *ApiStage) AliceApprovesPullRequest() *ApiStage {
 	reviews := []*github.PullRequestReview{
 		{
@@ -519,6 +1228,51 @@ func (s *ApiStage) AliceApprovesPullRequest() *ApiStage {
 	return s
 }
 
+func (s *ApiStage) AliceApprovesLatestCommit() *ApiStage {
+	return s.aliceApprovesCommit(s.fakeGitHub.PR().PRCommit)
+}
+
+func (s *ApiStage) AliceApprovesPreviousCommit() *ApiStage {
+	return s.aliceApprovesCommit("some-previous-hash")
+}
+
+func (s *ApiStage) aliceApprovesCommit(sha string) *ApiStage {
+	reviews := []*github.PullRequestReview{
+		{
+			State:    github.String("APPROVED"),
+			CommitID: github.String(sha),
+			User: &github.User{
+				Login: github.String("alice"),
+			},
+		},
+	}
+
+	s.fakeGitHub.SetReviews(reviews)
+
+	return s
+}
+
+func (s *ApiStage) AliceApprovesAndBobRequestsChanges() *ApiStage {
+	reviews := []*github.PullRequestReview{
+		{
+			State: github.String("APPROVED"),
+			User: &github.User{
+				Login: github.String("alice"),
+			},
+		},
+		{
+			State: github.String("CHANGES_REQUESTED"),
+			User: &github.User{
+				Login: github.String("bob"),
+			},
+		},
+	}
+
+	s.fakeGitHub.SetReviews(reviews)
+
+	return s
+}
+
 func (s *ApiStage) CharlieApprovesPullRequest() *ApiStage {
 	reviews := []*github.PullRequestReview{
 		{
@@ -562,7 +1316,101 @@ func (s *ApiStage) PullRequestExists() *ApiStage {
 	return s
 }
 
+func (s *ApiStage) PullRequestChangingConfigurationFileExists() *ApiStage {
+	s.PullRequestExists()
+	s.fakeGitHub.PR().Files = append(s.fakeGitHub.PR().Files, fakegithub.PRFile{
+		SHA:      "sha3",
+		Filename: approverCfg.ConfigurationFilePath,
+	})
+
+	return s
+}
+
+func (s *ApiStage) PullRequestRenamingConfigurationFileExists() *ApiStage {
+	s.PullRequestExists()
+	s.fakeGitHub.PR().Files = append(s.fakeGitHub.PR().Files, fakegithub.PRFile{
+		SHA:              "sha3",
+		Filename:         ".github/DISABLED.yaml",
+		PreviousFilename: approverCfg.ConfigurationFilePath,
+	})
+
+	return s
+}
+
+func (s *ApiStage) PullRequestImpactingCustomersExists() *ApiStage {
+	s.PullRequestExists()
+	s.fakeGitHub.PR().Body = "- [x] Yes - this change impacts customers"
+
+	return s
+}
+
+func (s *ApiStage) RequestingExplanation() *ApiStage {
+	c := newClient(s.t, s.app.URL(), s.WebHookSecret)
+	s.resp = c.explain(s.fakeGitHub.Org().OwnerName, s.fakeGitHub.Repo().Name, s.fakeGitHub.PR().PRNumber, string(s.WebHookSecret))
+
+	return s
+}
+
+func (s *ApiStage) RequestingExplanationWithoutToken() *ApiStage {
+	c := newClient(s.t, s.app.URL(), s.WebHookSecret)
+	s.resp = c.explain(s.fakeGitHub.Org().OwnerName, s.fakeGitHub.Repo().Name, s.fakeGitHub.PR().PRNumber, "")
+
+	return s
+}
+
 func (s *ApiStage) SendingPREvent() *ApiStage {
+	s.sendPREvent(s.prEvent("opened"))
+
+	return s
+}
+
+// SendingPREventForAnOlderCommit sends an event describing the PR as it was before its head commit was pushed, as
+// retried events or events waiting for the evaluation of a previous one do.
+func (s *ApiStage) SendingPREventForAnOlderCommit() *ApiStage {
+	e := s.prEvent("synchronize")
+	e.CommitSHA = "some-older-hash"
+	s.sendPREvent(e)
+
+	return s
+}
+
+func (s *ApiStage) SendingPRLabeledEvent() *ApiStage {
+	e := s.prEvent("labeled")
+	e.Label = "needs-cab-approval"
+	s.sendPREvent(e)
+
+	return s
+}
+
+// SendingPRLabeledEventForItsOwnLabel sends the event GitHub sends when github-team-approver labels the PR itself.
+func (s *ApiStage) SendingPRLabeledEventForItsOwnLabel() *ApiStage {
+	e := s.prEvent("labeled")
+	e.Label = "github-team-approver/needs-cab-approval"
+	s.sendPREvent(e)
+
+	return s
+}
+
+func (s *ApiStage) SendingDraftPREvent() *ApiStage {
+	e := s.prEvent("opened")
+	e.PRDraft = true
+	s.sendPREvent(e)
+
+	return s
+}
+
+func (s *ApiStage) SendingPRReadyForReviewEvent() *ApiStage {
+	s.sendPREvent(s.prEvent("ready_for_review"))
+
+	return s
+}
+
+func (s *ApiStage) sendPREvent(e fakegithub.Event) {
+	s.deliver(e.CreatePullRequestEvent(s.t), "pull_request")
+}
+
+// prEvent returns a "pull_request" event for the PR with the given action.
+func (s *ApiStage) prEvent(action string) fakegithub.Event {
 	require.NotNil(s.t, s.fakeGitHub.Org())
 	require.NotNil(s.t, s.fakeGitHub.Repo())
 
@@ -570,12 +1418,12 @@ func (s *ApiStage) SendingPREvent() *ApiStage {
 	targetBranch := "master"
 	s.labels = []string{"foo", "bar", "needs-cab-approval"}
 
-	r := fakegithub.Event{
+	return fakegithub.Event{
 		OwnerLogin: s.fakeGitHub.Org().OwnerName,
 		RepoName:   s.fakeGitHub.Repo().Name,
 		PRNumber:   s.fakeGitHub.PR().PRNumber,
 
-		Action:         "opened",
+		Action:         action,
 		CommitSHA:      s.fakeGitHub.PR().PRCommit,
 		LabelNames:     s.labels,
 		PRMerged:       false,
@@ -586,29 +1434,27 @@ func (s *ApiStage) SendingPREvent() *ApiStage {
 					TargetBranches: []string{targetBranch},
 					Rules: []approverCfg.Rule{
 						{
-							ApprovalMode:         approverCfg.ApprovalModeRequireAny,
-							Regex:                `- [x] Yes - this change impacts customers`,
-							ApprovingTeamHandles: []string{approvingTeam},
-							Labels:               []string{},
+							Rule: approverCommons.Rule{
+								ApprovalMode:         approverCfg.ApprovalModeRequireAny,
+								Regex:                `- [x] Yes - this change impacts customers`,
+								ApprovingTeamHandles: []string{approvingTeam},
+								Labels:               []string{},
+							},
 						},
 						{
-							ApprovalMode:         approverCfg.ApprovalModeRequireAny,
-							Regex:                `- [x] Yes - Emergency`,
-							ApprovingTeamHandles: []string{"CRAB - Foo"},
-							Labels:               []string{"needs-cab-approval"},
-							ForceApproval:        true,
+							Rule: approverCommons.Rule{
+								ApprovalMode:         approverCfg.ApprovalModeRequireAny,
+								Regex:                `- [x] Yes - Emergency`,
+								ApprovingTeamHandles: []string{"CRAB - Foo"},
+								Labels:               []string{"needs-cab-approval"},
+								ForceApproval:        true,
+							},
 						},
 					},
 				},
 			},
 		},
 	}
-	payload := r.CreatePullRequestEvent(s.t)
-
-	c := newClient(s.t, s.app.URL(), s.WebHookSecret)
-	s.resp = c.sendEvent(payload, "pull_request")
-
-	return s
 }
 
 func (s *ApiStage) SendingApprovedPRReviewSubmittedEvent() *ApiStage {
@@ -635,10 +1481,12 @@ func (s *ApiStage) SendingApprovedPRReviewSubmittedEvent() *ApiStage {
 					TargetBranches: []string{targetBranch},
 					Rules: []approverCfg.Rule{
 						{
-							ApprovalMode:         approverCfg.ApprovalModeRequireAny,
-							Regex:                `- [x] Yes - this change impacts customers`,
-							ApprovingTeamHandles: []string{"cab-foo"},
-							Labels:               []string{},
+							Rule: approverCommons.Rule{
+								ApprovalMode:         approverCfg.ApprovalModeRequireAny,
+								Regex:                `- [x] Yes - this change impacts customers`,
+								ApprovingTeamHandles: []string{"cab-foo"},
+								Labels:               []string{},
+							},
 						},
 					},
 				},
@@ -647,12 +1495,40 @@ func (s *ApiStage) SendingApprovedPRReviewSubmittedEvent() *ApiStage {
 	}
 	payload := r.CreatePullRequestReviewEvent(s.t)
 
-	c := newClient(s.t, s.app.URL(), s.WebHookSecret)
-	s.resp = c.sendEvent(payload, "pull_request_review")
+	s.deliver(payload, "pull_request_review")
+
+	return s
+}
+
+func (s *ApiStage) RedeliveringTheLastEvent() *ApiStage {
+	require.NotNil(s.t, s.lastDelivery)
+	s.resp = s.lastDelivery()
 
 	return s
 }
 
+// deliver sends the event, remembering it so that it can be redelivered. The PR is then described by the fake as the
+// event describes it, but for its head commit.
+func (s *ApiStage) deliver(payload interface{}, eventType string) {
+	if e, ok := payload.(interface{ GetPullRequest() *github.PullRequest }); ok && s.fakeGitHub.PR() != nil {
+		pr := e.GetPullRequest()
+		var labels []string
+		for _, l := range pr.Labels {
+			labels = append(labels, l.GetName())
+		}
+		s.fakeGitHub.UpdatePR(pr.GetBody(), labels, pr.GetDraft())
+	}
+
+	u, err := uuid.NewRandom()
+	require.NoError(s.t, err, "uuid.NewRandom")
+
+	c := newClient(s.t, s.app.URL(), s.WebHookSecret)
+	s.lastDelivery = func() *http.Response {
+		return c.sendDelivery(payload, eventType, u.String())
+	}
+	s.resp = s.lastDelivery()
+}
+
 func (s *ApiStage) SendingPRReviewSubmittedEventWithForceApproval() *ApiStage {
 	require.NotNil(s.t, s.fakeGitHub.Org())
 	require.NotNil(s.t, s.fakeGitHub.Repo())
@@ -677,11 +1553,13 @@ func (s *ApiStage) SendingPRReviewSubmittedEventWithForceApproval() *ApiStage {
 					TargetBranches: []string{targetBranch},
 					Rules: []approverCfg.Rule{
 						{
-							ApprovalMode:         approverCfg.ApprovalModeRequireAny,
-							Regex:                `- [x] Yes - Emergency`,
-							ApprovingTeamHandles: []string{"cab-foo"},
-							Labels:               []string{"needs-cab-approval"},
-							ForceApproval:        true,
+							Rule: approverCommons.Rule{
+								ApprovalMode:         approverCfg.ApprovalModeRequireAny,
+								Regex:                `- [x] Yes - Emergency`,
+								ApprovingTeamHandles: []string{"cab-foo"},
+								Labels:               []string{"needs-cab-approval"},
+								ForceApproval:        true,
+							},
 						},
 					},
 				},
@@ -690,8 +1568,7 @@ func (s *ApiStage) SendingPRReviewSubmittedEventWithForceApproval() *ApiStage {
 	}
 	payload := r.CreatePullRequestReviewEvent(s.t)
 
-	c := newClient(s.t, s.app.URL(), s.WebHookSecret)
-	s.resp = c.sendEvent(payload, "pull_request_review")
+	s.deliver(payload, "pull_request_review")
 
 	return s
 }
@@ -713,6 +1590,13 @@ func (s *ApiStage) ExpectStatusPendingReported() *ApiStage {
 	return s
 }
 
+func (s *ApiStage) ExpectStatusPendingReportedForDraft() *ApiStage {
+	status := s.fakeGitHub.ReportedStatus()
+	require.Equal(s.t, approval.StatusEventStatusPending, status.GetState())
+	require.Equal(s.t, "Draft PR", status.GetDescription())
+	return s
+}
+
 func (s *ApiStage) ExpectStatusErrorReported() *ApiStage {
 	status := s.fakeGitHub.ReportedStatus()
 	require.Equal(s.t, approval.StatusEventStatusError, *(status.State))
@@ -733,6 +1617,30 @@ func (s *ApiStage) ExpectInvalidTeamHandleInStatusDescription() *ApiStage {
 	return s
 }
 
+func (s *ApiStage) ExpectMissingCodeownersFileInStatusDescription() *ApiStage {
+	status := s.fakeGitHub.ReportedStatus()
+	require.Equal(s.t, "Invalid config: no .github/CODEOWNERS file exists in the target branch.\nConfig: default branch.", status.GetDescription())
+	return s
+}
+
+func (s *ApiStage) ExpectInvalidCodeownersFileInStatusDescription() *ApiStage {
+	status := s.fakeGitHub.ReportedStatus()
+	require.Equal(s.t, "Invalid config:\nCODEOWNERS line 2: invalid pattern \"/\"\nConfig: default branch.", status.GetDescription())
+	return s
+}
+
+func (s *ApiStage) ExpectInvalidRegexInStatusDescription() *ApiStage {
+	status := s.fakeGitHub.ReportedStatus()
+	require.Regexp(s.t, "^Invalid config:\\npull_request_approval_rules\\[0\\]\\.rules\\[0\\]\\.regex: invalid regular expression", *(status.Description))
+	return s
+}
+
+func (s *ApiStage) ExpectUnknownFieldInStatusDescription() *ApiStage {
+	status := s.fakeGitHub.ReportedStatus()
+	require.Equal(s.t, "Invalid config:\nline 6: field regex_labels not found in type configuration.Rule", status.GetDescription())
+	return s
+}
+
 func (s *ApiStage) ExpectedReviewRequestsMadeForFoo() *ApiStage {
 	reviews := s.fakeGitHub.RequestedTeamReviews()
 	require.Len(s.t, reviews, 1)
@@ -740,67 +1648,223 @@ func (s *ApiStage) ExpectedReviewRequestsMadeForFoo() *ApiStage {
 	return s
 }
 
+func (s *ApiStage) ExpectedReviewRequestsMadeForBar() *ApiStage {
+	reviews := s.fakeGitHub.RequestedTeamReviews()
+	require.Equal(s.t, []string{"cab-bar"}, reviews)
+	return s
+}
+
+func (s *ApiStage) ExpectPendingApprovalFromBarForConfigurationChangeInStatusDescription() *ApiStage {
+	status := s.fakeGitHub.ReportedStatus()
+	require.Equal(s.t, "Changes to .github/GITHUB_TEAM_APPROVER.yaml need approval from:\ncab-bar\nConfig: default branch.", *(status.Description))
+	return s
+}
+
 func (s *ApiStage) ExpectCommentAliceIgnoredAsReviewer() *ApiStage {
+	s.expectSummaryCommentContaining(fmt.Sprintf("%s @alice", ignoredReviewerMsg))
+	return s
+}
 
-	var comments []*github.IssueComment
-	for _, c := range s.fakeGitHub.ReportedComments() {
-		if strings.Contains(*c.Body, ignoredReviewerMsg) {
-			comments = append(comments, c)
-		}
-	}
+func (s *ApiStage) ExpectInvalidCommentCharlieIgnoredAsReviewer() *ApiStage {
+	s.expectSummaryCommentContaining(fmt.Sprintf("%s @charlie", invalidReviewerMsg))
+	return s
+}
+
+func (s *ApiStage) ExpectCommentAliceStaleReviewer() *ApiStage {
+	s.expectSummaryCommentContaining(fmt.Sprintf("%s @alice", staleReviewerMsg))
+	return s
+}
 
+// expectSummaryCommentContaining checks a single summary comment was created, and that it contains the given text.
+func (s *ApiStage) expectSummaryCommentContaining(text string) {
+	comments := s.fakeGitHub.ReportedComments()
 	require.Len(s.t, comments, 1)
-	require.NotNil(s.t, comments[0])
-	require.NotEmpty(s.t, comments[0].Body)
-	require.Contains(s.t, *comments[0].Body, ignoredReviewerMsg)
-	require.Contains(s.t, *comments[0].Body, "alice")
+	require.NotNil(s.t, comments[0].Body)
+	require.True(s.t, strings.HasPrefix(*comments[0].Body, summaryCommentMarker))
+	require.Contains(s.t, *comments[0].Body, "| 1 | `cab-foo` | 0/1 | 1 |")
+	require.Contains(s.t, *comments[0].Body, text)
+}
+
+func (s *ApiStage) ExpectSummaryCommentEditedWithApprovalFromAlice() *ApiStage {
+	require.Empty(s.t, s.fakeGitHub.ReportedComments())
+
+	edited := s.fakeGitHub.EditedComments()
+	require.Len(s.t, edited, 1)
+	require.Equal(s.t, int64(1), edited[0].GetID())
+	require.Contains(s.t, edited[0].GetBody(), "### Approval status: success")
+	require.Contains(s.t, edited[0].GetBody(), "| 1 | `cab-foo` | 1/1 | 0 |")
 
 	return s
 }
 
-func (s *ApiStage) ExpectInvalidCommentCharlieIgnoredAsReviewer() *ApiStage {
+func (s *ApiStage) ExpectSummaryCommentNotEdited() *ApiStage {
+	require.Empty(s.t, s.fakeGitHub.EditedComments())
+	return s
+}
 
-	var comments []*github.IssueComment
-	for _, c := range s.fakeGitHub.ReportedComments() {
-		if strings.Contains(*c.Body, invalidReviewerMsg) {
-			comments = append(comments, c)
-		}
-	}
+func (s *ApiStage) ExpectSummaryCommentDeleted() *ApiStage {
+	require.Empty(s.t, s.fakeGitHub.Comments())
+	return s
+}
 
+func (s *ApiStage) ExpectCommentQuotedByUserLeftAlone() *ApiStage {
+	require.Empty(s.t, s.fakeGitHub.EditedComments())
+	comments := s.fakeGitHub.Comments()
 	require.Len(s.t, comments, 1)
-	require.NotNil(s.t, comments[0])
-	require.NotEmpty(s.t, comments[0].Body)
-	require.Contains(s.t, *comments[0].Body, invalidReviewerMsg)
-	require.Contains(s.t, *comments[0].Body, "charlie")
+	require.Equal(s.t, "charlie", comments[0].GetUser().GetLogin())
+	return s
+}
 
+func (s *ApiStage) ExpectLegacyReviewCommentsDeleted() *ApiStage {
+	for _, comment := range s.fakeGitHub.Comments() {
+		require.True(s.t, strings.HasPrefix(comment.GetBody(), summaryCommentMarker), "unexpected comment: %s", comment.GetBody())
+	}
 	return s
 }
 
-func (s *ApiStage) ExpectNoCommentsMade() *ApiStage {
-	require.Empty(s.t, s.fakeGitHub.ReportedComments())
+func (s *ApiStage) ExpectStaleReviewerAliceInStatusDescription() *ApiStage {
+	status := s.fakeGitHub.ReportedStatus()
+	require.Regexp(s.t, "Stale approvals from:\\nalice", *(status.Description))
+	return s
+}
 
+func (s *ApiStage) ExpectBlockingReviewerBobInStatusDescription() *ApiStage {
+	status := s.fakeGitHub.ReportedStatus()
+	require.Regexp(s.t, "^Changes requested by:\\nbob", *(status.Description))
 	return s
 }
 
-func (s *ApiStage) ExpectNoReviewRequestsMade() *ApiStage {
-	require.Empty(s.t, s.fakeGitHub.ReportedComments())
+func (s *ApiStage) ExpectNoRulesMatchedInStatusDescription() *ApiStage {
+	status := s.fakeGitHub.ReportedStatus()
+	require.Equal(s.t, "The PR's body doesn't meet the requirements.\nConfig: default branch.", *(status.Description))
+	return s
+}
 
+func (s *ApiStage) ExpectPendingApprovalFromCharlieInStatusDescription() *ApiStage {
+	status := s.fakeGitHub.ReportedStatus()
+	require.Equal(s.t, "Needs approval from:\n@charlie\nConfig: default branch.", *(status.Description))
 	return s
 }
 
-func (s *ApiStage) ExpectPreviousIgnoredReviewCommentsDeleted() *ApiStage {
-	require.Empty(s.t, s.fakeGitHub.Comments())
+func (s *ApiStage) ExpectPendingApprovalFromCharlieAndBaseBranchInStatusDescription() *ApiStage {
+	status := s.fakeGitHub.ReportedStatus()
+	require.Equal(s.t, "Needs approval from:\n@charlie\nConfiguration read from base branch \"master\".", *(status.Description))
+	return s
+}
+
+func (s *ApiStage) ExpectUnauthorizedReturned() *ApiStage {
+	require.NotNil(s.t, s.resp)
+	require.Equal(s.t, http.StatusUnauthorized, s.resp.StatusCode)
+
+	return s
+}
+
+func (s *ApiStage) ExpectNoStatusReported() *ApiStage {
+	require.Nil(s.t, s.fakeGitHub.ReportedStatus())
+
+	return s
+}
+
+func (s *ApiStage) ExpectCompletedCheckRunSummarisingApprovalFromAlice() *ApiStage {
+	checkRuns := s.fakeGitHub.ReportedCheckRuns()
+	require.Len(s.t, checkRuns, 1)
+
+	checkRun := checkRuns[0]
+	require.Equal(s.t, botName, checkRun.Name)
+	require.Equal(s.t, s.fakeGitHub.PR().PRCommit, checkRun.HeadSHA)
+	require.Equal(s.t, "completed", checkRun.GetStatus())
+	require.Equal(s.t, "success", checkRun.GetConclusion())
+	require.Equal(s.t, "Approved by: cab-foo Config: default branch.", checkRun.GetOutput().GetTitle())
+	require.Contains(s.t, checkRun.GetOutput().GetSummary(), "| Rule | Applies | Reason | Approving teams | Approvers | Fulfilled |")
+	require.Contains(s.t, checkRun.GetOutput().GetSummary(), "| `cab-foo` (1/1) | @alice | yes |")
+
+	return s
+}
+
+func (s *ApiStage) ExpectInProgressCheckRunSummarisingIgnoredReviewers() *ApiStage {
+	checkRuns := s.fakeGitHub.ReportedCheckRuns()
+	require.Len(s.t, checkRuns, 1)
+
+	checkRun := checkRuns[0]
+	require.Equal(s.t, "in_progress", checkRun.GetStatus())
+	require.Nil(s.t, checkRun.Conclusion)
+	require.Contains(s.t, checkRun.GetOutput().GetSummary(), "- Ignored as they contributed to or reopened the PR: @alice")
+
+	return s
+}
+
+func (s *ApiStage) ExpectCheckRunUpdated() *ApiStage {
+	require.Equal(s.t, 1, s.fakeGitHub.CheckRunUpdates())
+
+	return s
+}
 
+func (s *ApiStage) ExpectNoCheckRunReported() *ApiStage {
+	require.Empty(s.t, s.fakeGitHub.ReportedCheckRuns())
+
+	return s
+}
+
+func (s *ApiStage) explanation() explanation {
+	require.NotNil(s.t, s.resp)
+	require.Equal(s.t, http.StatusOK, s.resp.StatusCode)
+
+	var e explanation
+	err := json.NewDecoder(s.resp.Body).Decode(&e)
+	require.NoError(s.t, err)
+
+	return e
+}
+
+func (s *ApiStage) ExpectExplanationOfRuleNotMatchingBody() *ApiStage {
+	e := s.explanation()
+	require.Equal(s.t, approval.StatusEventStatusPending, e.Status)
+	require.Equal(s.t, "The PR's body doesn't meet the requirements.\nConfig: default branch.", e.Description)
+	require.Equal(s.t, "master", e.Trace.TargetBranch)
+	require.Len(s.t, e.Trace.Rules, 1)
+
+	rule := e.Trace.Rules[0]
+	require.False(s.t, rule.Matched)
+	require.Equal(s.t, []approval.ConditionMatch{
+		{
+			Condition: "regex",
+			Matched:   false,
+			Reason:    `body doesn't match "- \\[x\\] Yes - this change impacts customers"`,
+		},
+	}, rule.Conditions)
+	require.Empty(s.t, rule.Teams)
+
+	return s
+}
+
+func (s *ApiStage) ExpectExplanationOfApprovalFromAlice() *ApiStage {
+	e := s.explanation()
+	require.Equal(s.t, approval.StatusEventStatusSuccess, e.Status)
+	require.Len(s.t, e.Trace.Rules, 1)
+
+	rule := e.Trace.Rules[0]
+	require.True(s.t, rule.Matched)
+	require.True(s.t, rule.Fulfilled)
+	require.Equal(s.t, []approval.TeamApproval{{Handle: "cab-foo", Approvals: 1, Required: 1}}, rule.Teams)
+	require.Equal(s.t, []string{"alice"}, rule.Approvers)
+
+	return s
+}
+
+func (s *ApiStage) ExpectNoCommentsMade() *ApiStage {
+	require.Empty(s.t, s.fakeGitHub.ReportedComments())
+
+	return s
+}
+
+func (s *ApiStage) ExpectedReviewRequestsMadeForCharlie() *ApiStage {
+	require.Equal(s.t, []string{"charlie"}, s.fakeGitHub.RequestedUserReviews())
 	return s
 }
 
-func (s *ApiStage) ExpectPreviousAliceIgnoredReviewCommentsRetained() *ApiStage {
-	require.Len(s.t, s.fakeGitHub.Comments(), 1)
-	comment := s.fakeGitHub.Comments()[0]
-	require.NotNil(s.t, comment)
-	require.NotEmpty(s.t, comment.Body)
-	require.Contains(s.t, *comment.Body, ignoredReviewerMsg)
-	require.Contains(s.t, *comment.Body, "alice")
+func (s *ApiStage) ExpectNoReviewRequestsMade() *ApiStage {
+	require.Empty(s.t, s.fakeGitHub.ReportedComments())
+
 	return s
 }
 
@@ -823,6 +1887,9 @@ func ApiTest(t *testing.T) (*ApiStage, *ApiStage, *ApiStage) {
 		app:           NewAppServer(t),
 		WebHookSecret: webhookSecret,
 	}
+	// Events are processed within the webhook request unless processed asynchronously, so that the response carries
+	// their final status.
+	s.setupEnv("EVENT_QUEUE_WORKERS", "0")
 
 	return s, s, s
 }
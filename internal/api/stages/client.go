@@ -7,6 +7,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"testing"
 	"time"
@@ -53,6 +54,13 @@ func (c *client) sendEventWithIncorrectSignature(e interface{}) *http.Response {
 }
 
 func (c *client) sendEvent(e interface{}, eventType string) *http.Response {
+	u, err := uuid.NewRandom()
+	require.NoError(c.t, err, "uuid.NewRandom")
+
+	return c.sendDelivery(e, eventType, u.String())
+}
+
+func (c *client) sendDelivery(e interface{}, eventType, deliveryID string) *http.Response {
 	payload, err := json.Marshal(e)
 	require.NoError(c.t, err)
 
@@ -61,10 +69,22 @@ func (c *client) sendEvent(e interface{}, eventType string) *http.Response {
 
 	req.Header.Add("X-Hub-Signature-256", c.generateSignature(payload))
 	req.Header.Add("X-GitHub-Event", eventType)
+	req.Header.Add("X-GitHub-Delivery", deliveryID)
 
-	u, err := uuid.NewRandom()
-	require.NoError(c.t, err, "uuid.NewRandom")
-	req.Header.Add("X-GitHub-Delivery", u.String())
+	resp, err := c.http.Do(req)
+	require.NoError(c.t, err)
+
+	return resp
+}
+
+func (c *client) explain(ownerLogin, repoName string, prNumber int, token string) *http.Response {
+	url := fmt.Sprintf("%s/api/v1/repos/%s/%s/pulls/%d/explain", c.testAddress, ownerLogin, repoName, prNumber)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(c.t, err)
+
+	if token != "" {
+		req.Header.Add("Authorization", "Bearer "+token)
+	}
 
 	resp, err := c.http.Do(req)
 	require.NoError(c.t, err)
@@ -72,6 +92,17 @@ func (c *client) sendEvent(e interface{}, eventType string) *http.Response {
 	return resp
 }
 
+func (c *client) metrics() string {
+	resp, err := c.http.Get(fmt.Sprintf("%s/metrics", c.testAddress))
+	require.NoError(c.t, err)
+	defer resp.Body.Close()
+	require.Equal(c.t, http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(c.t, err)
+	return string(body)
+}
+
 func (c *client) generateSignature(payload []byte) string {
 	h := hmac.New(sha256.New, []byte(c.secretToken))
 	_, err := h.Write(payload)
@@ -0,0 +1,62 @@
+package fakegithub
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+// SetAppInstalled serves the installation tokens of the app, the token of each installation being
+// "installation-<id>". Tokens expire after the given duration. Repositories are found to be accessible to installation 1.
+func (f *FakeGitHub) SetAppInstalled(tokenLifetime time.Duration) {
+	f.tokenLifetime = tokenLifetime
+	f.installationID = 1
+	f.uninstalled = map[string]bool{}
+	f.mux.HandleFunc("/app/installations/{id:[0-9]+}/access_tokens", f.accessTokensHandler)
+	f.mux.HandleFunc("/repos/{owner}/{repo}/installation", f.repoInstallationHandler)
+}
+
+// ReinstallApp uninstalls the app, so that no more tokens are issued for its current installation, and installs it
+// again under the given installation ID.
+func (f *FakeGitHub) ReinstallApp(installationID int64) {
+	f.uninstalled[strconv.FormatInt(f.installationID, 10)] = true
+	f.installationID = installationID
+}
+
+func (f *FakeGitHub) repoInstallationHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	payload, err := json.Marshal(map[string]interface{}{
+		"id": f.installationID,
+	})
+	require.NoError(f.t, err)
+	_, err = w.Write(payload)
+	require.NoError(f.t, err)
+}
+
+func (f *FakeGitHub) accessTokensHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	if f.uninstalled[id] {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	f.issuedTokens = append(f.issuedTokens, id)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	payload, err := json.Marshal(map[string]interface{}{
+		"token":      "installation-" + id,
+		"expires_at": time.Now().Add(f.tokenLifetime),
+	})
+	require.NoError(f.t, err)
+	_, err = w.Write(payload)
+	require.NoError(f.t, err)
+}
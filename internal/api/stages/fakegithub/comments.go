@@ -44,28 +44,63 @@ func (f *FakeGitHub) postCommentHandler(w http.ResponseWriter, r *http.Request)
 	err = json.Unmarshal(payload, &comment)
 	require.NoError(f.t, err)
 
+	comment.ID = github.Int64(f.nextCommentID())
+	comment.User = BotUser()
 	f.reportedComments = append(f.reportedComments, comment)
+	f.issueComments = append(f.issueComments, comment)
 
 	w.Header().Set("Content-Type", "application/json")
 
-	// ack by writing payload back to client
+	// ack by writing the comment back to client
+	payload, err = json.Marshal(comment)
+	require.NoError(f.t, err)
 	_, err = w.Write(payload)
 	require.NoError(f.t, err)
 }
 
-func (f *FakeGitHub) deleteCommentHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodDelete {
+// BotUser returns the user the app acts as.
+func BotUser() *github.User {
+	return &github.User{Login: github.String("github-team-approver[bot]"), Type: github.String("Bot")}
+}
+
+func (f *FakeGitHub) issueCommentHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodDelete:
+		f.deleteCommentHandler(w, r)
+	case http.MethodPatch:
+		f.editCommentHandler(w, r)
+	default:
 		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func (f *FakeGitHub) editCommentHandler(w http.ResponseWriter, r *http.Request) {
+	var edit *github.IssueComment
+	payload, err := ioutil.ReadAll(r.Body)
+	require.NoError(f.t, err)
+
+	err = json.Unmarshal(payload, &edit)
+	require.NoError(f.t, err)
+
+	comment, err := f.comment(f.commentID(r))
+	if errors.Is(err, errNotFound) {
+		f.notFoundResp(w)
 		return
 	}
+	require.NoError(f.t, err)
 
-	vars := mux.Vars(r)
-	val, ok := vars["id"]
-	require.True(f.t, ok, "go-github sent incorrect comment ID. URL: %s", r.URL.String())
-	id, err := strconv.Atoi(val)
+	comment.Body = edit.Body
+	f.editedComments = append(f.editedComments, comment)
+
+	w.Header().Set("Content-Type", "application/json")
+	payload, err = json.Marshal(comment)
+	require.NoError(f.t, err)
+	_, err = w.Write(payload)
 	require.NoError(f.t, err)
+}
 
-	err = f.deleteComment(int64(id))
+func (f *FakeGitHub) deleteCommentHandler(w http.ResponseWriter, r *http.Request) {
+	err := f.deleteComment(f.commentID(r))
 	if errors.Is(err, errNotFound) {
 		f.notFoundResp(w)
 		return
@@ -88,6 +123,34 @@ func (f *FakeGitHub) notFoundResp(w http.ResponseWriter) {
 	require.NoError(f.t, err)
 }
 
+func (f *FakeGitHub) commentID(r *http.Request) int64 {
+	vars := mux.Vars(r)
+	val, ok := vars["id"]
+	require.True(f.t, ok, "go-github sent incorrect comment ID. URL: %s", r.URL.String())
+	id, err := strconv.Atoi(val)
+	require.NoError(f.t, err)
+	return int64(id)
+}
+
+func (f *FakeGitHub) nextCommentID() int64 {
+	var id int64
+	for _, c := range f.issueComments {
+		if c.GetID() > id {
+			id = c.GetID()
+		}
+	}
+	return id + 1
+}
+
+func (f *FakeGitHub) comment(id int64) (*github.IssueComment, error) {
+	for _, c := range f.issueComments {
+		if c.GetID() == id {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("%d %w", id, errNotFound)
+}
+
 func (f *FakeGitHub) deleteComment(id int64) error {
 	for i, c := range f.issueComments {
 		if *c.ID == id {
@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"testing"
 
-	"github.com/form3tech-oss/github-team-approver-commons/v2/pkg/configuration"
+	"github.com/form3tech-oss/github-team-approver/internal/api/configuration"
 	"github.com/google/go-github/v42/github"
 	"github.com/stretchr/testify/require"
 )
@@ -19,7 +19,10 @@ type Event struct {
 	CommitSHA      string
 	LabelNames     []string
 	PRMerged       bool
+	PRDraft        bool
 	PRTargetBranch string
+	// Label is the label added or removed by "labeled" and "unlabeled" events.
+	Label string
 
 	// GitHubTeam Approver template filled by author
 	PRCfg *configuration.Configuration
@@ -52,6 +55,9 @@ func (r *Event) CreatePullRequestReviewEvent(t *testing.T) *github.PullRequestRe
 			Base: &github.PullRequestBranch{
 				Ref: github.String(r.PRTargetBranch),
 			},
+			Head: &github.PullRequestBranch{
+				SHA: github.String(r.CommitSHA),
+			},
 			Merged: github.Bool(r.PRMerged),
 		},
 	}
@@ -65,12 +71,18 @@ func (e *Event) CreatePullRequestEvent(t *testing.T) *github.PullRequestEvent {
 	owner := &github.User{Login: github.String(e.OwnerLogin)}
 	fullName := fmt.Sprintf("%s/%s", e.OwnerLogin, e.RepoName)
 
+	var label *github.Label
+	if e.Label != "" {
+		label = &github.Label{Name: github.String(e.Label)}
+	}
+
 	return &github.PullRequestEvent{
 		Repo: &github.Repository{
 			Owner:    owner,
 			Name:     github.String(e.RepoName),
 			FullName: github.String(fullName),
 		},
+		Label: label,
 
 		Action: github.String(e.Action),
 		PullRequest: &github.PullRequest{
@@ -83,7 +95,11 @@ func (e *Event) CreatePullRequestEvent(t *testing.T) *github.PullRequestEvent {
 			Base: &github.PullRequestBranch{
 				Ref: github.String(e.PRTargetBranch),
 			},
+			Head: &github.PullRequestBranch{
+				SHA: github.String(e.CommitSHA),
+			},
 			Merged: github.Bool(e.PRMerged),
+			Draft:  github.Bool(e.PRDraft),
 		},
 	}
 }
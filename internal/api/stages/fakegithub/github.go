@@ -3,9 +3,11 @@ package fakegithub
 import (
 	"fmt"
 	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
 
-	approverCfg "github.com/form3tech-oss/github-team-approver-commons/v2/pkg/configuration"
+	approverCfg "github.com/form3tech-oss/github-team-approver/internal/api/configuration"
 	"github.com/google/go-github/v42/github"
 
 	"github.com/gorilla/mux"
@@ -24,18 +26,29 @@ type Org struct {
 type Repo struct {
 	Name        string
 	ApproverCfg *approverCfg.Configuration
+	// ApproverCfgByRef holds the configuration served when it is requested at a given ref.
+	ApproverCfgByRef map[string]*approverCfg.Configuration
+	// ApproverCfgContent is served as is instead of ApproverCfg if set, e.g. to serve fields the configuration doesn't
+	// know about.
+	ApproverCfgContent string
+	Codeowners         string
 }
 
 type PR struct {
 	PRNumber int
 	PRCommit string
+	Body     string
+	Labels   []string
+	Draft    bool
 	Files    []PRFile
 }
 
 type PRFile struct {
-	SHA         string
-	Filename    string
-	ContentsURL string
+	SHA      string
+	Filename string
+	// PreviousFilename is the name of the file before the PR renamed it, if it did.
+	PreviousFilename string
+	ContentsURL      string
 }
 
 type FakeGitHub struct {
@@ -43,9 +56,10 @@ type FakeGitHub struct {
 	mux *mux.Router
 	t   *testing.T
 
-	org  *Org
-	repo *Repo
-	pr   *PR
+	org         *Org
+	repo        *Repo
+	centralRepo *Repo
+	pr          *PR
 
 	commits       []*github.RepositoryCommit
 	reviews       []*github.PullRequestReview
@@ -53,9 +67,28 @@ type FakeGitHub struct {
 	events        []*github.IssueEvent
 
 	reportedStatus         *github.RepoStatus
+	reportedCheckRuns      []*github.CreateCheckRunOptions
+	checkRunUpdates        int
 	reportedComments       []*github.IssueComment
+	editedComments         []*github.IssueComment
 	reportedLabels         []string
 	requestedTeamReviewers []string
+	requestedUserReviewers []string
+
+	// mu guards the status and check runs reported and the PR, which are read while events are processed in the background.
+	mu sync.Mutex
+	// statusReportFailures is the number of status reports to fail before accepting them.
+	statusReportFailures int
+
+	tokenLifetime time.Duration
+	// installationID is the ID of the installation repositories are found to be accessible to.
+	installationID int64
+	// uninstalled holds the IDs of the installations no more tokens are issued for.
+	uninstalled map[string]bool
+	// issuedTokens holds the ID of the installation each token was issued for, in order.
+	issuedTokens []string
+	// prAuthorizations holds the Authorization header of each request for the PR, in order.
+	prAuthorizations []string
 }
 
 func NewFakeGithub(t *testing.T) *FakeGitHub {
@@ -87,6 +120,13 @@ func (f *FakeGitHub) SetOrg(o *Org) {
 func (f *FakeGitHub) SetRepo(r *Repo) {
 	f.repo = r
 	f.mux.HandleFunc(f.contentsURL(approverCfg.ConfigurationFilePath), f.contentsHandler)
+	f.mux.HandleFunc(f.contentsURL(approverCfg.CodeownersFilePath), f.codeownersHandler)
+}
+
+// SetCentralRepo sets the repository of the organisation holding the central configuration.
+func (f *FakeGitHub) SetCentralRepo(r *Repo) {
+	f.centralRepo = r
+	f.mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/contents/%s", f.org.OwnerName, r.Name, approverCfg.ConfigurationFilePath), f.centralContentsHandler)
 }
 
 func (f *FakeGitHub) SetPR(pr *PR) {
@@ -95,9 +135,15 @@ func (f *FakeGitHub) SetPR(pr *PR) {
 	// only expose handlers when expected data is there
 	// the following handlers handles reporting (POST/PUT) from Approver Bot
 	f.mux.HandleFunc(f.statusURL(), f.statusHandler)
+	f.mux.HandleFunc(f.checkRunsURL(), f.checkRunsHandler)
+	f.mux.HandleFunc(f.commitCheckRunsURL(), f.commitCheckRunsHandler)
+	f.mux.HandleFunc(f.checkRunURL(), f.checkRunHandler)
 	f.mux.HandleFunc(f.labelsURL(), f.labelsHandler)
 	f.mux.HandleFunc(f.requestedReviewersURL(), f.requestedReviewersHandler)
 	f.mux.HandleFunc(f.prFilesURL(), f.prFilesHandler)
+	f.mux.HandleFunc(f.prURL(), f.prHandler)
+	f.mux.HandleFunc(f.commentsURL(), f.commentsHandler)
+	f.mux.HandleFunc(f.issueCommentsURL(), f.issueCommentHandler)
 }
 
 func (f *FakeGitHub) SetCommits(r []*github.RepositoryCommit) {
@@ -121,21 +167,50 @@ func (f *FakeGitHub) SetReviews(r []*github.PullRequestReview) {
 
 func (f *FakeGitHub) SetIssueComments(c []*github.IssueComment) {
 	f.issueComments = c
+}
 
-	// only expose handlers when expected data is there
-	f.mux.HandleFunc(f.commentsURL(), f.commentsHandler)
-	f.mux.HandleFunc(f.issueCommentsURL(), f.deleteCommentHandler)
+// SetStatusReportFailures makes the given number of status reports fail with an internal server error.
+func (f *FakeGitHub) SetStatusReportFailures(n int) {
+	f.statusReportFailures = n
 }
 
 func (f *FakeGitHub) Org() *Org   { return f.org }
 func (f *FakeGitHub) Repo() *Repo { return f.repo }
 func (f *FakeGitHub) PR() *PR     { return f.pr }
 
-func (f *FakeGitHub) ReportedLabels() []string                 { return f.reportedLabels }
-func (f *FakeGitHub) ReportedStatus() *github.RepoStatus       { return f.reportedStatus }
+// UpdatePR updates the body, the labels and the draft flag of the PR, which are read while events are processed in the
+// background.
+func (f *FakeGitHub) UpdatePR(body string, labels []string, draft bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pr.Body = body
+	f.pr.Labels = labels
+	f.pr.Draft = draft
+}
+
+func (f *FakeGitHub) ReportedLabels() []string { return f.reportedLabels }
+func (f *FakeGitHub) ReportedStatus() *github.RepoStatus {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.reportedStatus
+}
+func (f *FakeGitHub) ReportedCheckRuns() []*github.CreateCheckRunOptions {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.reportedCheckRuns
+}
+func (f *FakeGitHub) CheckRunUpdates() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.checkRunUpdates
+}
 func (f *FakeGitHub) ReportedComments() []*github.IssueComment { return f.reportedComments }
+func (f *FakeGitHub) EditedComments() []*github.IssueComment   { return f.editedComments }
 func (f *FakeGitHub) RequestedTeamReviews() []string           { return f.requestedTeamReviewers }
+func (f *FakeGitHub) RequestedUserReviews() []string           { return f.requestedUserReviewers }
 func (f *FakeGitHub) Comments() []*github.IssueComment         { return f.issueComments }
+func (f *FakeGitHub) IssuedTokens() []string                   { return f.issuedTokens }
+func (f *FakeGitHub) PRAuthorizations() []string               { return f.prAuthorizations }
 
 func (f *FakeGitHub) URL() string {
 	return fmt.Sprintf("%s", f.ts.URL)
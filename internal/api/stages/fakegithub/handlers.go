@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/google/go-github/v42/github"
 	"github.com/gorilla/mux"
@@ -14,22 +15,60 @@ import (
 )
 
 func (f *FakeGitHub) contentsHandler(w http.ResponseWriter, r *http.Request) {
+	f.serveConfiguration(w, r, f.repo)
+}
+
+func (f *FakeGitHub) centralContentsHandler(w http.ResponseWriter, r *http.Request) {
+	f.serveConfiguration(w, r, f.centralRepo)
+}
+
+func (f *FakeGitHub) serveConfiguration(w http.ResponseWriter, r *http.Request, repo *Repo) {
 	if r.Method != http.MethodGet {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	if f.repo.ApproverCfg == nil {
-		w.WriteHeader(http.StatusNotFound)
-		return
+	var buf bytes.Buffer
+	if repo.ApproverCfgContent != "" {
+		buf.WriteString(repo.ApproverCfgContent)
+	} else {
+		cfg := repo.ApproverCfg
+		if ref := r.URL.Query().Get("ref"); ref != "" {
+			cfg = repo.ApproverCfgByRef[ref]
+		}
+		if cfg == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		err := cfg.Write(&buf)
+		require.NoError(f.t, err)
 	}
 
-	var buf bytes.Buffer
-	err := f.repo.ApproverCfg.Write(&buf)
+	content := &github.RepositoryContent{
+		Content: github.String(buf.String()),
+	}
+
+	payload, err := json.Marshal(content)
 	require.NoError(f.t, err)
 
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(payload)
+	require.NoError(f.t, err)
+}
+
+func (f *FakeGitHub) codeownersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if f.repo.Codeowners == "" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
 	content := &github.RepositoryContent{
-		Content: github.String(buf.String()),
+		Content: github.String(f.repo.Codeowners),
 	}
 
 	payload, err := json.Marshal(content)
@@ -128,10 +167,111 @@ func (f *FakeGitHub) statusHandler(w http.ResponseWriter, r *http.Request) {
 	err = json.Unmarshal(payload, status)
 	require.NoError(f.t, err)
 
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.statusReportFailures > 0 {
+		f.statusReportFailures--
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
 	f.reportedStatus = status
 	w.WriteHeader(http.StatusCreated)
 }
 
+func (f *FakeGitHub) checkRunsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	checkRun := &github.CreateCheckRunOptions{}
+	payload, err := ioutil.ReadAll(r.Body)
+	require.NoError(f.t, err)
+
+	err = json.Unmarshal(payload, checkRun)
+	require.NoError(f.t, err)
+
+	f.mu.Lock()
+	f.reportedCheckRuns = append(f.reportedCheckRuns, checkRun)
+	id := len(f.reportedCheckRuns)
+	f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_, err = w.Write([]byte(fmt.Sprintf(`{"id": %d}`, id)))
+	require.NoError(f.t, err)
+}
+
+// commitCheckRunsHandler lists the check runs reported on the commit of the PR, their ID being their position in the
+// order they were created in.
+func (f *FakeGitHub) commitCheckRunsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	runs := []*github.CheckRun{}
+	f.mu.Lock()
+	for i, checkRun := range f.reportedCheckRuns {
+		if name := r.URL.Query().Get("check_name"); name != "" && name != checkRun.Name {
+			continue
+		}
+		runs = append(runs, &github.CheckRun{
+			ID:      github.Int64(int64(i + 1)),
+			Name:    github.String(checkRun.Name),
+			HeadSHA: github.String(checkRun.HeadSHA),
+		})
+	}
+	f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	payload, err := json.Marshal(&github.ListCheckRunsResults{
+		Total:     github.Int(len(runs)),
+		CheckRuns: runs,
+	})
+	require.NoError(f.t, err)
+	_, err = w.Write(payload)
+	require.NoError(f.t, err)
+}
+
+func (f *FakeGitHub) checkRunHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	update := &github.UpdateCheckRunOptions{}
+	payload, err := ioutil.ReadAll(r.Body)
+	require.NoError(f.t, err)
+
+	err = json.Unmarshal(payload, update)
+	require.NoError(f.t, err)
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	require.NoError(f.t, err)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if id < 1 || id > len(f.reportedCheckRuns) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	f.reportedCheckRuns[id-1] = &github.CreateCheckRunOptions{
+		Name:        update.Name,
+		HeadSHA:     f.reportedCheckRuns[id-1].HeadSHA,
+		Status:      update.Status,
+		Conclusion:  update.Conclusion,
+		CompletedAt: update.CompletedAt,
+		Output:      update.Output,
+	}
+	f.checkRunUpdates++
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write([]byte(fmt.Sprintf(`{"id": %d}`, id)))
+	require.NoError(f.t, err)
+}
+
 func (f *FakeGitHub) labelsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPut {
 		w.WriteHeader(http.StatusBadRequest)
@@ -170,6 +310,7 @@ func (f *FakeGitHub) requestedReviewersHandler(w http.ResponseWriter, r *http.Re
 	require.NoError(f.t, err)
 
 	f.requestedTeamReviewers = reviewReq.TeamReviewers
+	f.requestedUserReviewers = reviewReq.Reviewers
 
 	w.Header().Set("Content-Type", "application/json")
 
@@ -183,6 +324,48 @@ func (f *FakeGitHub) requestedReviewersHandler(w http.ResponseWriter, r *http.Re
 	require.NoError(f.t, err)
 }
 
+func (f *FakeGitHub) prHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	require.NotNil(f.t, f.pr)
+	f.prAuthorizations = append(f.prAuthorizations, r.Header.Get("Authorization"))
+	if f.uninstalled[strings.TrimPrefix(r.Header.Get("Authorization"), "token installation-")] {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	payload, err := json.Marshal(f.pullRequest())
+	require.NoError(f.t, err)
+	_, err = w.Write(payload)
+	require.NoError(f.t, err)
+}
+
+func (f *FakeGitHub) pullRequest() *github.PullRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var labels []*github.Label
+	for _, n := range f.pr.Labels {
+		labels = append(labels, &github.Label{Name: github.String(n)})
+	}
+	return &github.PullRequest{
+		Number: github.Int(f.pr.PRNumber),
+		Body:   github.String(f.pr.Body),
+		Labels: labels,
+		Draft:  github.Bool(f.pr.Draft),
+		Base: &github.PullRequestBranch{
+			Ref: github.String("master"),
+		},
+		Head: &github.PullRequestBranch{
+			SHA: github.String(f.pr.PRCommit),
+		},
+	}
+}
+
 func (f *FakeGitHub) prFilesHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		w.WriteHeader(http.StatusBadRequest)
@@ -194,11 +377,16 @@ func (f *FakeGitHub) prFilesHandler(w http.ResponseWriter, r *http.Request) {
 	files := []*github.CommitFile{}
 	for _, file := range f.pr.Files {
 		repoPath := fmt.Sprintf("https://api.github.com/repos/%s/contents/%s", f.repoFullName(), file.Filename)
-		files = append(files, &github.CommitFile{
+		commitFile := &github.CommitFile{
 			SHA:         github.String(file.SHA),
 			Filename:    github.String(file.Filename),
 			ContentsURL: github.String(repoPath),
-		})
+		}
+		if file.PreviousFilename != "" {
+			commitFile.Status = github.String("renamed")
+			commitFile.PreviousFilename = github.String(file.PreviousFilename)
+		}
+		files = append(files, commitFile)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
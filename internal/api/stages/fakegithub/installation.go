@@ -0,0 +1,56 @@
+package fakegithub
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-github/v42/github"
+	"github.com/stretchr/testify/require"
+)
+
+// SetInstallationRepositories lists the repository as the only one the installation has access to, and the PR as its
+// only open PR.
+func (f *FakeGitHub) SetInstallationRepositories() {
+	require.NotNil(f.t, f.repo)
+	require.NotNil(f.t, f.pr)
+
+	f.mux.HandleFunc("/installation/repositories", f.installationRepositoriesHandler)
+	f.mux.HandleFunc(fmt.Sprintf("/repos/%s/pulls", f.repoFullName()), f.pullRequestsHandler)
+}
+
+func (f *FakeGitHub) installationRepositoriesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	repos := &github.ListRepositories{
+		TotalCount: github.Int(1),
+		Repositories: []*github.Repository{
+			{
+				Name:     github.String(f.repo.Name),
+				FullName: github.String(f.repoFullName()),
+				Owner:    &github.User{Login: github.String(f.org.OwnerName)},
+			},
+		},
+	}
+	f.writeJSON(w, repos)
+}
+
+func (f *FakeGitHub) pullRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet || r.URL.Query().Get("state") != "open" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	f.writeJSON(w, []*github.PullRequest{f.pullRequest()})
+}
+
+func (f *FakeGitHub) writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	payload, err := json.Marshal(v)
+	require.NoError(f.t, err)
+	_, err = w.Write(payload)
+	require.NoError(f.t, err)
+}
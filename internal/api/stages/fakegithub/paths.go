@@ -30,6 +30,18 @@ func (f *FakeGitHub) statusURL() string {
 	return fmt.Sprintf("/repos/%s/statuses/%s", f.repoFullName(), f.pr.PRCommit)
 }
 
+func (f *FakeGitHub) checkRunsURL() string {
+	return fmt.Sprintf("/repos/%s/check-runs", f.repoFullName())
+}
+
+func (f *FakeGitHub) commitCheckRunsURL() string {
+	return fmt.Sprintf("/repos/%s/commits/%s/check-runs", f.repoFullName(), f.pr.PRCommit)
+}
+
+func (f *FakeGitHub) checkRunURL() string {
+	return fmt.Sprintf("/repos/%s/check-runs/{id:[0-9]+}", f.repoFullName())
+}
+
 func (f *FakeGitHub) labelsURL() string {
 	return fmt.Sprintf("/repos/%s/issues/%d/labels", f.repoFullName(), f.pr.PRNumber)
 }
@@ -50,6 +62,10 @@ func (f *FakeGitHub) repoFullName() string {
 	return fmt.Sprintf("%s/%s", f.org.OwnerName, f.repo.Name)
 }
 
+func (f *FakeGitHub) prURL() string {
+	return fmt.Sprintf("/repos/%s/pulls/%d", f.repoFullName(), f.pr.PRNumber)
+}
+
 func (f *FakeGitHub) prFilesURL() string {
 	return fmt.Sprintf("/repos/%s/pulls/%d/files", f.repoFullName(), f.pr.PRNumber)
 }
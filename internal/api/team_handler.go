@@ -0,0 +1,268 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v42/github"
+	"github.com/sirupsen/logrus"
+
+	"github.com/form3tech-oss/github-team-approver/internal/api/approval"
+	ghclient "github.com/form3tech-oss/github-team-approver/internal/api/github"
+)
+
+const (
+	eventTypeMembership = "membership"
+	eventTypeTeam       = "team"
+
+	membershipActionAdded   = "added"
+	membershipActionRemoved = "removed"
+	membershipScopeTeam     = "team"
+
+	teamActionCreated = "created"
+	teamActionDeleted = "deleted"
+	teamActionEdited  = "edited"
+
+	logFieldTeam = "team"
+)
+
+func isTeamEvent(eventType string) bool {
+	return eventType == eventTypeMembership || eventType == eventTypeTeam
+}
+
+// handleTeamEvent handles a "membership" or "team" event, by re-evaluating in the background the open PRs whose rules
+// reference the team changed.
+func (api *API) handleTeamEvent(w http.ResponseWriter, log *logrus.Entry, eventType, deliveryID string, body []byte) {
+	change, err := teamChangeFromEvent(eventType, body)
+	if err != nil {
+		log.WithError(err).Error("unmarshal request body")
+		sendHttpBadRequestResponse(w, fmt.Errorf("unmarshal request body: %w", err))
+		return
+	}
+	if change == nil {
+		log.Warn("ignoring event: unsupported action")
+		sendHttpNoContentResponse(w)
+		return
+	}
+
+	change.deliveryID = deliveryID
+	change.log = log.WithFields(logrus.Fields{
+		logFieldTeam:           change.key(),
+		logFieldInstallationID: change.installationID,
+	})
+	if err := api.teamReevaluator.enqueue(change); err != nil {
+		change.log.WithError(err).Error("failed to enqueue team re-evaluation")
+		api.forgetDelivery(log, deliveryID)
+		sendHttpResponse(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	sendHttpResponse(w, http.StatusAccepted, "")
+}
+
+// teamChangeFromEvent returns the change described by a "membership" or "team" event, or nil if the action of the
+// event doesn't call for PRs to be re-evaluated.
+func teamChangeFromEvent(eventType string, body []byte) (*teamChange, error) {
+	var (
+		team         *github.Team
+		org          *github.Organization
+		installation *github.Installation
+	)
+	switch eventType {
+	case eventTypeMembership:
+		event := &github.MembershipEvent{}
+		if err := unmarshalEvent(body, event); err != nil {
+			return nil, err
+		}
+		action := event.GetAction()
+		if event.GetScope() != membershipScopeTeam || (action != membershipActionAdded && action != membershipActionRemoved) {
+			return nil, nil
+		}
+		team, org, installation = event.GetTeam(), event.GetOrg(), event.GetInstallation()
+	case eventTypeTeam:
+		event := &github.TeamEvent{}
+		if err := unmarshalEvent(body, event); err != nil {
+			return nil, err
+		}
+		action := event.GetAction()
+		if action != teamActionCreated && action != teamActionDeleted && action != teamActionEdited {
+			return nil, nil
+		}
+		team, org, installation = event.GetTeam(), event.GetOrg(), event.GetInstallation()
+	default:
+		return nil, fmt.Errorf("%s: %w", eventType, errIgnoredEvent)
+	}
+	if team.GetSlug() == "" {
+		return nil, nil
+	}
+
+	handles := []string{team.GetSlug()}
+	for parent := team.GetParent(); parent.GetSlug() != ""; parent = parent.GetParent() {
+		handles = append(handles, parent.GetSlug())
+	}
+	return &teamChange{
+		installationID: installation.GetID(),
+		ownerLogin:     org.GetLogin(),
+		handles:        handles,
+	}, nil
+}
+
+// reevaluateTeam re-evaluates the open PRs of the repositories of the organisation the installation has access to,
+// reporting the status of the ones whose matched rules reference the team changed. PRs failing to be re-evaluated don't
+// prevent the others from being re-evaluated, but an error is returned once they all were.
+func (api *API) reevaluateTeam(ctx context.Context, c *teamChange, throttle <-chan time.Time) error {
+	c.log.Info("re-evaluating pull requests affected by team change")
+	defer api.metrics.teamChanges.Add(1)
+
+	client, err := api.clients.ForInstallation(c.installationID)
+	if err != nil {
+		return err
+	}
+	// Events only describe the direct parent of the team, so the rest of its ancestors are looked up.
+	if teams, err := client.GetTeams(ctx, c.ownerLogin); err != nil {
+		c.log.WithError(err).Warn("failed to list teams, only re-evaluating pull requests referencing the team or its parent")
+	} else {
+		c.teams = teams
+		c.handles = teamAncestorHandles(teams, c.handles)
+	}
+	repos, err := client.ListInstallationRepositories(ctx)
+	if err != nil {
+		return fmt.Errorf("list repositories: %w", err)
+	}
+	failed := 0
+	for _, repo := range repos {
+		if !strings.EqualFold(repo.GetOwner().GetLogin(), c.ownerLogin) || repo.GetArchived() || isMember(api.ignoredRepositories, repo.GetFullName()) {
+			continue
+		}
+		log := c.log.WithField(logFieldRepo, repo.GetFullName())
+		prs, err := client.ListOpenPullRequests(ctx, repo.GetOwner().GetLogin(), repo.GetName())
+		if err != nil {
+			log.WithError(err).Warn("failed to list open pull requests")
+			failed++
+			continue
+		}
+		for _, pr := range prs {
+			select {
+			case <-throttle:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			if !api.reevaluatePR(ctx, log.WithField(logFieldPR, pr.GetNumber()), client, c, repo, pr) {
+				failed++
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d repositories or pull requests failed to be re-evaluated", failed)
+	}
+	return nil
+}
+
+// teamAncestorHandles returns the given handles, followed by the slugs of the ancestors of the team the first one is
+// the slug of, as given by the parent of each team. Each ancestor is returned once, even if the hierarchy contains cycles.
+func teamAncestorHandles(teams []*github.Team, handles []string) []string {
+	byID := map[int64]*github.Team{}
+	var team *github.Team
+	for _, t := range teams {
+		byID[t.GetID()] = t
+		if t.GetSlug() == handles[0] {
+			team = t
+		}
+	}
+	listed := map[string]bool{}
+	for _, h := range handles {
+		listed[h] = true
+	}
+	visited := map[int64]bool{}
+	for team != nil && !visited[team.GetID()] {
+		visited[team.GetID()] = true
+		if !listed[team.GetSlug()] {
+			listed[team.GetSlug()] = true
+			handles = append(handles, team.GetSlug())
+		}
+		team = byID[team.GetParent().GetID()]
+	}
+	return handles
+}
+
+// reevaluatePR re-evaluates an open PR, reporting its status if one of its matched rules references the team changed.
+// It returns false if the PR failed to be re-evaluated.
+func (api *API) reevaluatePR(ctx context.Context, log *logrus.Entry, client *ghclient.Client, c *teamChange, repo *github.Repository, pr *github.PullRequest) bool {
+	if pr.GetDraft() && api.draftPolicy == draftPolicySkip {
+		return true
+	}
+
+	// Serialize the re-evaluation with the evaluations of the PR triggered by its own events. As the status is only
+	// reported if the PR is affected by the change, the re-evaluation must not supersede the events of the PR waiting to
+	// be evaluated, while the ones received in the meantime supersede it as they evaluate the PR anyway.
+	key := prKey(repo.GetFullName(), pr.GetNumber())
+	err := api.prEvents.serialize(key, api.prEvents.latest(key), func() error {
+		p := newPR(repo.GetOwner().GetLogin(), repo.GetName(), pr)
+		app := approval.NewApproval(log, client, api.approvalSettings())
+		result, err := app.ComputeApprovalStatus(ctx, p)
+		if err != nil {
+			return err
+		}
+		if !referencesTeam(result, c.ownerLogin, c.teams, c.handles) {
+			log.Debug("pull request not affected by team change")
+			return nil
+		}
+		if _, err := NewPullRequestEventHandler(api, log, client).report(ctx, p, result); err != nil {
+			return err
+		}
+		api.metrics.prsReevaluated.Add(1)
+		return nil
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, ghclient.ErrNoConfigurationFile), errors.Is(err, errEventSuperseded):
+		log.WithError(err).Debug("not re-evaluating pull request")
+	default:
+		log.WithError(err).Warn("failed to re-evaluate pull request")
+		return false
+	}
+	return true
+}
+
+// referencesTeam returns whether one of the rules matched by the PR, or the guard of the configuration file, lists one
+// of the teams with the given slugs among its approving teams. Rules may reference teams by ID, slug or name, which are
+// resolved against the teams of the organisation. Handles which can't be resolved, e.g. as the team was deleted or the
+// teams couldn't be listed, are compared with the slugs.
+func referencesTeam(result *approval.Result, ownerLogin string, teams []*github.Team, handles []string) bool {
+	names := map[string]bool{}
+	for _, team := range teams {
+		for _, h := range handles {
+			if strings.EqualFold(team.GetSlug(), h) {
+				names[team.GetName()] = true
+			}
+		}
+	}
+	matches := append([]approval.RuleMatch{}, result.RuleMatches()...)
+	if guard := result.Trace().ConfigurationGuard; guard != nil {
+		matches = append(matches, *guard)
+	}
+	for _, rm := range matches {
+		if !rm.Matched {
+			continue
+		}
+		for _, team := range rm.Teams {
+			if name, err := approval.GetTeamNameFromTeamHandle(teams, ownerLogin, team.Handle); err == nil {
+				if names[name] {
+					return true
+				}
+				continue
+			}
+			// Teams may be referenced by their slug, or as "<org>/<slug>" in CODEOWNERS.
+			handle := strings.TrimPrefix(team.Handle, ownerLogin+"/")
+			for _, h := range handles {
+				if strings.EqualFold(handle, h) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
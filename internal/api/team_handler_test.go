@@ -0,0 +1,46 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v42/github"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTeamChangeFromEvent_ListsTheAncestorsOfTheTeam(t *testing.T) {
+	body := []byte(`{
+		"action": "added",
+		"scope": "team",
+		"team": {"slug": "appsec", "parent": {"slug": "security", "parent": {"slug": "platform"}}},
+		"organization": {"login": "form3tech"},
+		"installation": {"id": 1}
+	}`)
+
+	change, err := teamChangeFromEvent(eventTypeMembership, body)
+	require.NoError(t, err)
+	require.Equal(t, "form3tech/appsec", change.key())
+	require.Equal(t, []string{"appsec", "security", "platform"}, change.handles)
+}
+
+func TestTeamAncestorHandles(t *testing.T) {
+	platform := &github.Team{ID: github.Int64(1), Slug: github.String("platform")}
+	security := &github.Team{ID: github.Int64(2), Slug: github.String("security"), Parent: &github.Team{ID: github.Int64(1)}}
+	appsec := &github.Team{ID: github.Int64(3), Slug: github.String("appsec"), Parent: &github.Team{ID: github.Int64(2)}}
+	docs := &github.Team{ID: github.Int64(4), Slug: github.String("docs")}
+	teams := []*github.Team{appsec, docs, platform, security}
+
+	t.Run("team with ancestors", func(t *testing.T) {
+		require.Equal(t, []string{"appsec", "security", "platform"}, teamAncestorHandles(teams, []string{"appsec", "security"}))
+	})
+	t.Run("team without ancestors", func(t *testing.T) {
+		require.Equal(t, []string{"docs"}, teamAncestorHandles(teams, []string{"docs"}))
+	})
+	t.Run("unknown team", func(t *testing.T) {
+		require.Equal(t, []string{"network", "platform"}, teamAncestorHandles(teams, []string{"network", "platform"}))
+	})
+	t.Run("hierarchy with cycles", func(t *testing.T) {
+		a := &github.Team{ID: github.Int64(1), Slug: github.String("a"), Parent: &github.Team{ID: github.Int64(2)}}
+		b := &github.Team{ID: github.Int64(2), Slug: github.String("b"), Parent: &github.Team{ID: github.Int64(1)}}
+		require.Equal(t, []string{"a", "b"}, teamAncestorHandles([]*github.Team{a, b}, []string{"a"}))
+	})
+}